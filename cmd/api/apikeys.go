@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"calendar-extension/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateAPIKeyRequest is the body of a new API key.
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" example:"WatsonX extension"`
+	Scopes []string `json:"scopes" enums:"read-availability,book-meetings,admin" example:"read-availability,book-meetings"`
+	// ExpiresAt is when the key stops working; without it the key does not expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" format:"date-time" example:"2025-01-01T00:00:00Z"`
+}
+
+// CreateAPIKey issues an API key
+// @Summary Issue an API key
+// @Description Issues a key for a service calling the API, such as the WatsonX extension, sent in the X-API-Key header. read-availability allows reading users, groups and availability, book-meetings allows booking and changing meetings, and admin allows everything.
+// @Description The key is only returned in this response; only its hash is stored.
+// @Tags Auth
+// @Accept  json
+// @Produce  json
+// @Param key body CreateAPIKeyRequest true "Name, scopes and expiry of the key"
+// @Success 201 {object} jsonResponse{data=data.APIKey} "API key issued"
+// @Failure 400 {object} jsonResponse "Invalid key"
+// @Failure 401 {object} jsonResponse "Missing or invalid credentials"
+// @Failure 403 {object} jsonResponse "Credentials lack the admin scope"
+// @Failure 500 {object} jsonResponse "Error issuing key"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /api-keys [post]
+func (app *Config) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req CreateAPIKeyRequest
+	err := app.readJSON(w, r, &req)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	key, err := app.Models.CreateAPIKey(req.Name, req.Scopes, req.ExpiresAt)
+	if errors.Is(err, data.ErrInvalidAPIKey) {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("failed to issue api key: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "API key issued",
+		Data:    key,
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// ListAPIKeys lists the API keys
+// @Summary List API keys
+// @Description Returns every issued key with its scopes and expiry, without the secrets.
+// @Tags Auth
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} jsonResponse{data=[]data.APIKey} "API keys"
+// @Failure 401 {object} jsonResponse "Missing or invalid credentials"
+// @Failure 403 {object} jsonResponse "Credentials lack the admin scope"
+// @Failure 500 {object} jsonResponse "Error listing keys"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /api-keys [get]
+func (app *Config) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := app.Models.ListAPIKeys()
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("failed to list api keys: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "API keys",
+		Data:    keys,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// DeleteAPIKey revokes an API key
+// @Summary Revoke an API key
+// @Description Revokes the key, which stops working immediately.
+// @Tags Auth
+// @Accept  json
+// @Produce  json
+// @Param id path string true "API key ID"
+// @Success 200 {object} jsonResponse "API key revoked"
+// @Failure 401 {object} jsonResponse "Missing or invalid credentials"
+// @Failure 403 {object} jsonResponse "Credentials lack the admin scope"
+// @Failure 404 {object} jsonResponse "API key not found"
+// @Failure 500 {object} jsonResponse "Error revoking key"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /api-keys/{id} [delete]
+func (app *Config) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	err := app.Models.DeleteAPIKey(id)
+	if errors.Is(err, data.ErrAPIKeyNotFound) {
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("failed to revoke api key: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: fmt.Sprintf("API key %s revoked", id),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"calendar-extension/data"
+)
+
+const (
+	// apiKeyHeader carries the API keys of services such as the WatsonX extension.
+	apiKeyHeader = "X-API-Key"
+	// sessionTokenTTL is how long a session token issued after signing in is valid.
+	sessionTokenTTL = 12 * time.Hour
+)
+
+var (
+	errInvalidToken = errors.New("invalid session token")
+	errTokenExpired = errors.New("session token expired")
+)
+
+// scopesKey is the request context key of the scopes of the caller's credentials.
+type scopesKey struct{}
+
+// credentialScopes returns the scopes of the credentials requireScope accepted for r.
+func credentialScopes(r *http.Request) []string {
+	scopes, _ := r.Context().Value(scopesKey{}).([]string)
+	return scopes
+}
+
+// sessionClaims are the contents of a session token.
+type sessionClaims struct {
+	Email     string   `json:"email"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// SignIn is what a user receives after authorizing the app: the token to send as
+// "Authorization: Bearer <token>" on later calls.
+type SignIn struct {
+	Email     string    `json:"email" example:"anna@example.com"`
+	Token     string    `json:"token" example:"eyJlbWFpbCI6ImFubmFAZXhhbXBsZS5jb20ifQ.c2lnbmF0dXJl"`
+	ExpiresAt time.Time `json:"expires_at" format:"date-time" example:"2024-05-06T22:00:00Z"`
+}
+
+// userScopes are the scopes of a session token for email: everything a user needs to
+// schedule their own meetings, and admin for the users in app.Admins.
+func (app *Config) userScopes(email string) []string {
+	if app.Admins[email] {
+		return []string{data.ScopeAdmin}
+	}
+	return []string{data.ScopeReadAvailability, data.ScopeBookMeetings}
+}
+
+// sign returns the signature of payload under the app's token secret.
+func (app *Config) sign(payload string) string {
+	mac := hmac.New(sha256.New, app.TokenSecret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// issueSessionToken returns a token for email with scopes, valid until expiresAt. The
+// token is the encoded claims and their HMAC-SHA256 signature, separated by a dot.
+func (app *Config) issueSessionToken(email string, scopes []string, expiresAt time.Time) (string, error) {
+	claims, err := json.Marshal(sessionClaims{Email: email, Scopes: scopes, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session token: %w", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+	return payload + "." + app.sign(payload), nil
+}
+
+// verifySessionToken returns the claims of token if it was issued by this app and has
+// not expired.
+func (app *Config) verifySessionToken(token string, now time.Time) (*sessionClaims, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(app.sign(payload))) {
+		return nil, errInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	var claims sessionClaims
+	err = json.Unmarshal(raw, &claims)
+	if err != nil || claims.Email == "" {
+		return nil, errInvalidToken
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, errTokenExpired
+	}
+
+	return &claims, nil
+}
+
+// requireScope authenticates callers by an API key in the X-API-Key header or a session
+// token in the Authorization header, and lets through those granted scope. Others get
+// a 401 for missing or invalid credentials and a 403 for credentials without the scope.
+//
+// API keys belong to services acting for many users, which name the acting user in the
+// X-User-Email header. The header is not verified: a key is trusted to act for any user
+// within its scopes, so keys are only issued to services that sign their users in
+// themselves. Session tokens belong to one user, who is set as the acting user; a
+// different X-User-Email is refused.
+func (app *Config) requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var scopes []string
+
+			if secret := r.Header.Get(apiKeyHeader); secret != "" {
+				key, err := app.Models.AuthenticateAPIKey(secret)
+				switch {
+				case errors.Is(err, data.ErrAPIKeyNotFound), errors.Is(err, data.ErrAPIKeyExpired):
+					app.invalidCredentials(w)
+					return
+				case err != nil:
+					app.errorJSON(w, fmt.Errorf("failed to authenticate: %w", err), http.StatusInternalServerError)
+					return
+				}
+				scopes = key.Scopes
+			} else if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+				claims, err := app.verifySessionToken(strings.TrimSpace(token), time.Now())
+				if err != nil {
+					app.invalidCredentials(w)
+					return
+				}
+				actor := r.Header.Get(actorHeader)
+				if actor != "" && !strings.EqualFold(actor, claims.Email) {
+					app.errorJSON(w, fmt.Errorf("%s does not match the signed in user %s", actorHeader, claims.Email), http.StatusForbidden)
+					return
+				}
+				r.Header.Set(actorHeader, claims.Email)
+				scopes = claims.Scopes
+			} else {
+				app.invalidCredentials(w)
+				return
+			}
+
+			if !data.HasScope(scopes, scope) {
+				app.errorJSON(w, fmt.Errorf("credentials lack the %s scope", scope), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), scopesKey{}, scopes)))
+		})
+	}
+}
+
+// actor returns the acting user named in the X-User-Email header, sending a 401 and
+// returning false when there is none.
+func (app *Config) actor(w http.ResponseWriter, r *http.Request) (string, bool) {
+	actor := r.Header.Get(actorHeader)
+	if actor == "" {
+		app.errorJSON(w, fmt.Errorf("%s header is required", actorHeader), http.StatusUnauthorized)
+		return "", false
+	}
+	return actor, true
+}
+
+// requireActor checks that the acting user is user, sending the error response and
+// returning false otherwise. Calls with the admin scope may act for anyone.
+func (app *Config) requireActor(w http.ResponseWriter, r *http.Request, user string) bool {
+	if data.HasScope(credentialScopes(r), data.ScopeAdmin) {
+		return true
+	}
+	actor, ok := app.actor(w, r)
+	if !ok {
+		return false
+	}
+	if !strings.EqualFold(actor, user) {
+		app.errorJSON(w, fmt.Errorf("%s cannot act for %s", actor, user), http.StatusForbidden)
+		return false
+	}
+	return true
+}
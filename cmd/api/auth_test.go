@@ -0,0 +1,316 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"calendar-extension/data"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func testApp() *Config {
+	return &Config{
+		Admins:      map[string]bool{"root@example.com": true},
+		TokenSecret: []byte("test secret"),
+	}
+}
+
+func TestSessionToken(t *testing.T) {
+	app := testApp()
+	now := time.Now()
+
+	token, err := app.issueSessionToken("anna@example.com", app.userScopes("anna@example.com"), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	claims, err := app.verifySessionToken(token, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.Email != "anna@example.com" || data.HasScope(claims.Scopes, data.ScopeAdmin) {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	if _, err := app.verifySessionToken(token, now.Add(2*time.Hour)); err != errTokenExpired {
+		t.Errorf("expected errTokenExpired, got %v", err)
+	}
+
+	payload, signature, _ := strings.Cut(token, ".")
+	forged, _ := app.issueSessionToken("root@example.com", []string{data.ScopeAdmin}, now.Add(time.Hour))
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+	if _, err := app.verifySessionToken(forgedPayload+"."+signature, now); err != errInvalidToken {
+		t.Errorf("expected a payload with another signature to be rejected, got %v", err)
+	}
+	if _, err := app.verifySessionToken(payload, now); err != errInvalidToken {
+		t.Errorf("expected an unsigned token to be rejected, got %v", err)
+	}
+
+	other := testApp()
+	other.TokenSecret = []byte("another secret")
+	if _, err := other.verifySessionToken(token, now); err != errInvalidToken {
+		t.Errorf("expected a token signed with another secret to be rejected, got %v", err)
+	}
+}
+
+// actorEcho responds with the acting user the handler sees.
+var actorEcho = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(r.Header.Get(actorHeader)))
+})
+
+func TestRequireScopeSessionToken(t *testing.T) {
+	app := testApp()
+	handler := app.requireScope(data.ScopeBookMeetings)(actorEcho)
+	token, _ := app.issueSessionToken("anna@example.com", app.userScopes("anna@example.com"), time.Now().Add(time.Hour))
+	expired, _ := app.issueSessionToken("anna@example.com", app.userScopes("anna@example.com"), time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name   string
+		token  string
+		actor  string
+		status int
+		body   string
+	}{
+		{"no credentials", "", "", http.StatusUnauthorized, ""},
+		{"valid token", token, "", http.StatusOK, "anna@example.com"},
+		{"matching actor", token, "Anna@example.com", http.StatusOK, "anna@example.com"},
+		{"other actor", token, "bob@example.com", http.StatusForbidden, ""},
+		{"expired token", expired, "", http.StatusUnauthorized, ""},
+		{"garbage", "not-a-token", "", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/meetings", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.actor != "" {
+				req.Header.Set(actorHeader, tt.actor)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rr.Code, rr.Body)
+			}
+			if tt.status == http.StatusOK && rr.Body.String() != tt.body {
+				t.Errorf("expected acting user %q, got %q", tt.body, rr.Body)
+			}
+			if tt.status == http.StatusUnauthorized && !strings.Contains(rr.Body.String(), "invalid authentication credentials") {
+				t.Errorf("expected the invalid credentials response, got %s", rr.Body)
+			}
+		})
+	}
+}
+
+func TestRequireScopeAPIKey(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	app := testApp()
+	app.Models = data.NewModels(db)
+	handler := app.requireScope(data.ScopeBookMeetings)(actorEcho)
+
+	keyRows := func(scopes string, expiresAt any) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "scopes", "expires_at", "created_at"}).
+			AddRow("key-1", "WatsonX extension", scopes, expiresAt, time.Now())
+	}
+	query := `SELECT id, name, scopes, expires_at, created_at FROM api_keys WHERE key_hash`
+
+	tests := []struct {
+		name   string
+		rows   *sqlmock.Rows
+		status int
+	}{
+		{"scoped key", keyRows("read-availability,book-meetings", nil), http.StatusOK},
+		{"admin key", keyRows("admin", time.Now().Add(time.Hour)), http.StatusOK},
+		{"read only key", keyRows("read-availability", nil), http.StatusForbidden},
+		{"expired key", keyRows("book-meetings", time.Now().Add(-time.Hour)), http.StatusUnauthorized},
+		{"unknown key", sqlmock.NewRows([]string{"id", "name", "scopes", "expires_at", "created_at"}), http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectQuery(query).WillReturnRows(tt.rows)
+
+			req := httptest.NewRequest(http.MethodPost, "/meetings", nil)
+			req.Header.Set(apiKeyHeader, "cal_9f86d081884c7d659a2feaa0c55ad015")
+			req.Header.Set(actorHeader, "bob@example.com")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rr.Code, rr.Body)
+			}
+			if tt.status == http.StatusOK && rr.Body.String() != "bob@example.com" {
+				t.Errorf("expected the API key to act for the user in %s, got %q", actorHeader, rr.Body)
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	app := testApp()
+	app.Models = data.NewModels(db)
+	handler := app.requireScope(data.ScopeBookMeetings)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.requireAdmin(w, r) {
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+
+	tests := []struct {
+		name   string
+		scopes string
+		actor  string
+		status int
+	}{
+		{"admin key", "admin", "root@example.com", http.StatusOK},
+		{"admin key for another user", "admin", "bob@example.com", http.StatusForbidden},
+		{"booking key naming an admin", "book-meetings", "root@example.com", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectQuery(`SELECT id, name, scopes, expires_at, created_at FROM api_keys WHERE key_hash`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "scopes", "expires_at", "created_at"}).
+					AddRow("key-1", "WatsonX extension", tt.scopes, nil, time.Now()))
+
+			req := httptest.NewRequest(http.MethodPost, "/resources", nil)
+			req.Header.Set(apiKeyHeader, "cal_9f86d081884c7d659a2feaa0c55ad015")
+			req.Header.Set(actorHeader, tt.actor)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rr.Code, rr.Body)
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRoutesActOnlyForTheActingUser(t *testing.T) {
+	app := testApp()
+	mux := app.routes()
+	token, _ := app.issueSessionToken("anna@example.com", app.userScopes("anna@example.com"), time.Now().Add(time.Hour))
+
+	meeting := `{"organizer": "bob@example.com", "title": "Design sync", "start": "2024-05-06T10:00:00Z", "end": "2024-05-06T10:30:00Z"}`
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/meetings", meeting},
+		{http.MethodPost, "/holds", meeting},
+		{http.MethodPost, "/polls", `{"organizer": "bob@example.com", "title": "Design sync", "participants": ["eva@partner.example"],
+			"slots": [{"start": "2024-05-06T10:00:00Z", "end": "2024-05-06T10:30:00Z"}]}`},
+		{http.MethodPut, "/users/bob@example.com/preferences", `{"timezone": "Europe/Warsaw"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "anna@example.com cannot act for bob@example.com") {
+				t.Errorf("expected a non-admin acting for another user to be refused, got %d: %s", rr.Code, rr.Body)
+			}
+		})
+	}
+}
+
+// TestRoutesRequireDocumentedScope checks that every endpoint documented with a scope
+// refuses callers without credentials and callers with every other scope but admin.
+func TestRoutesRequireDocumentedScope(t *testing.T) {
+	app := testApp()
+	mux := app.routes()
+	pathParams := regexp.MustCompile(`\{[^}]+\}`)
+
+	others := map[string][]string{
+		data.ScopeReadAvailability: {data.ScopeBookMeetings},
+		data.ScopeBookMeetings:     {data.ScopeReadAvailability},
+		data.ScopeAdmin:            {data.ScopeReadAvailability, data.ScopeBookMeetings},
+	}
+
+	for _, e := range endpoints {
+		if e.scope == "" {
+			continue
+		}
+		path := pathParams.ReplaceAllString(e.path, "x")
+
+		req := httptest.NewRequest(e.method, path, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without credentials: expected 401, got %d", e.method, e.path, rr.Code)
+		}
+
+		token, _ := app.issueSessionToken("anna@example.com", others[e.scope], time.Now().Add(time.Hour))
+		req = httptest.NewRequest(e.method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("%s %s without the %s scope: expected 403, got %d", e.method, e.path, e.scope, rr.Code)
+		}
+	}
+}
+
+func TestOAuthCallbackChecksState(t *testing.T) {
+	app := testApp()
+
+	rr := httptest.NewRecorder()
+	app.AddUser(rr, httptest.NewRequest(http.MethodPost, "/add-user", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oauthStateCookie || !cookies[0].HttpOnly || cookies[0].Value == "" {
+		t.Fatalf("expected an HttpOnly state cookie, got %v", cookies)
+	}
+	if !strings.Contains(rr.Body.String(), "state="+cookies[0].Value) {
+		t.Errorf("expected the link to carry the cookie's state, got %s", rr.Body.String())
+	}
+
+	tests := []struct {
+		name   string
+		state  string
+		cookie *http.Cookie
+	}{
+		{name: "no cookie", state: cookies[0].Value},
+		{name: "no state", cookie: cookies[0]},
+		{name: "other state", state: "forged", cookie: cookies[0]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/oauth2callback?code=attacker-code&state="+tt.state, nil)
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			rr := httptest.NewRecorder()
+			app.OAuthCallback(rr, req)
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("expected 400 before the code is exchanged, got %d", rr.Code)
+			}
+		})
+	}
+}
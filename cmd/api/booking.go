@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"calendar-extension/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// Requests per client IP allowed on the public booking endpoints.
+	bookingViewLimit  = 60
+	bookingViewWindow = time.Minute
+	bookingLimit      = 10
+	bookingWindow     = time.Hour
+)
+
+// BookingPageView is the public view of a booking page with the times it offers.
+type BookingPageView struct {
+	Slug            string                 `json:"slug" example:"anna-intro"`
+	Title           string                 `json:"title" example:"Intro call"`
+	Description     string                 `json:"description,omitempty" example:"A short call to get to know each other"`
+	DurationMinutes int                    `json:"duration_minutes" example:"30"`
+	Questions       []data.BookingQuestion `json:"questions"`
+	Times           []data.TimeSlot        `json:"times"`
+}
+
+// BookingView is what a guest sees of a meeting booked through a booking page.
+type BookingView struct {
+	// Reference identifies the booking, as the ID of its event.
+	Reference string    `json:"reference" example:"5q8s0m7h2kq1m3b0f9g6v4c2pl"`
+	Title     string    `json:"title" example:"Intro call with Pat Partner"`
+	Start     time.Time `json:"start" format:"date-time"`
+	End       time.Time `json:"end" format:"date-time"`
+	MeetURL   string    `json:"meet_url,omitempty" example:"https://meet.google.com/abc-defg-hij"`
+}
+
+// BookRequest is the body of a booking through a booking page.
+type BookRequest struct {
+	GuestEmail string            `json:"guest_email" example:"partner@example.org"`
+	GuestName  string            `json:"guest_name" example:"Pat Partner"`
+	Start      time.Time         `json:"start" format:"date-time" example:"2024-05-06T10:00:00+02:00"`
+	Answers    map[string]string `json:"answers"`
+}
+
+// CreateBookingPage creates a public booking page
+// @Summary Create a booking page
+// @Description Creates a public page at /book/{slug} through which people outside the service book meetings of duration_minutes with the user in the X-User-Email header, or with a group that user administers. Guests answer the page's questions when booking.
+// @Description The rules limit how many days ahead times are offered (window_days, default 14), the minimum notice, buffers around meetings, the meetings per day and the bookings per guest email (max_bookings_per_guest, default 1).
+// @Tags Booking
+// @Accept  json
+// @Produce  json
+// @Param X-User-Email header string true "Email of the page owner"
+// @Param page body data.BookingPage true "Booking page; owner is taken from the header"
+// @Success 201 {object} jsonResponse{data=data.BookingPage} "Booking page created"
+// @Failure 400 {object} jsonResponse "Invalid booking page"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse "Not an admin of the group"
+// @Failure 409 {object} jsonResponse "Slug already in use"
+// @Failure 500 {object} jsonResponse "Error creating booking page"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /booking-pages [post]
+func (app *Config) CreateBookingPage(w http.ResponseWriter, r *http.Request) {
+	actor, ok := app.actor(w, r)
+	if !ok {
+		return
+	}
+
+	var page data.BookingPage
+	err := app.readJSON(w, r, &page)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+	page.Owner = actor
+
+	err = page.Validate()
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = app.Models.CreateBookingPage(page)
+	switch {
+	case errors.Is(err, data.ErrNotAllowed):
+		app.errorJSON(w, fmt.Errorf("%s is not an admin of group %s", actor, page.Group), http.StatusForbidden)
+		return
+	case errors.Is(err, data.ErrBookingPageExists):
+		app.errorJSON(w, err, http.StatusConflict)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to create booking page: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Booking page created",
+		Data:    page,
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// DeleteBookingPage removes a booking page
+// @Summary Remove a booking page
+// @Description Removes a booking page. Meetings booked through it are kept. Only the owner, identified by the X-User-Email header, may remove a page.
+// @Tags Booking
+// @Accept  json
+// @Produce  json
+// @Param slug path string true "Booking page slug"
+// @Param X-User-Email header string true "Email of the page owner"
+// @Success 200 {object} jsonResponse "Booking page removed"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse "Not the owner of the page"
+// @Failure 404 {object} jsonResponse "Booking page not found"
+// @Failure 500 {object} jsonResponse "Error removing booking page"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /booking-pages/{slug} [delete]
+func (app *Config) DeleteBookingPage(w http.ResponseWriter, r *http.Request) {
+	actor, ok := app.actor(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.Models.DeleteBookingPage(chi.URLParam(r, "slug"), actor)
+	switch {
+	case errors.Is(err, data.ErrBookingPageNotFound):
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	case errors.Is(err, data.ErrNotAllowed):
+		app.errorJSON(w, errors.New("only the owner may remove a booking page"), http.StatusForbidden)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to delete booking page: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Booking page deleted",
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// GetBookingPage shows a booking page
+// @Summary Show a booking page
+// @Description Public endpoint returning a booking page's title, questions and the times guests can book, computed from the owner's or group's availability, working hours and booking constraints. Requests are rate limited per client IP.
+// @Tags Booking
+// @Accept  json
+// @Produce  json
+// @Param slug path string true "Booking page slug"
+// @Param tz query string false "IANA time zone of the times (default UTC)"
+// @Success 200 {object} jsonResponse{data=BookingPageView} "Booking page"
+// @Failure 400 {object} jsonResponse "Invalid time zone"
+// @Failure 404 {object} jsonResponse "Booking page not found"
+// @Failure 429 {object} jsonResponse "Too many requests"
+// @Failure 500 {object} jsonResponse "Error reading availability"
+// @Router /book/{slug} [get]
+func (app *Config) GetBookingPage(w http.ResponseWriter, r *http.Request) {
+	loc := time.UTC
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		var err error
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			app.errorJSON(w, fmt.Errorf("invalid tz %q: must be an IANA time zone name", tz), http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := app.Models.GetBookingPage(chi.URLParam(r, "slug"))
+	switch {
+	case errors.Is(err, data.ErrBookingPageNotFound):
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to get booking page: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	times, err := app.Models.BookingTimes(r.Context(), page, loc)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("failed to get booking times: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Booking page",
+		Data: BookingPageView{
+			Slug:            page.Slug,
+			Title:           page.Title,
+			Description:     page.Description,
+			DurationMinutes: page.DurationMinutes,
+			Questions:       page.Questions,
+			Times:           times,
+		},
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// Book books a time through a booking page
+// @Summary Book through a booking page
+// @Description Public endpoint booking one of the times a booking page offers. The meeting is created on the owner's calendar, with the group's members for group pages, and the guest is invited by email. The response shows the guest only the title, times, Meet link and a booking reference. Each guest email can book at most the page's max_bookings_per_guest meetings, and requests are rate limited per client IP.
+// @Tags Booking
+// @Accept  json
+// @Produce  json
+// @Param slug path string true "Booking page slug"
+// @Param booking body BookRequest true "Guest details, chosen start time and answers keyed by question id"
+// @Success 201 {object} jsonResponse{data=BookingView} "Meeting booked"
+// @Failure 400 {object} jsonResponse "Missing guest email or required answer"
+// @Failure 404 {object} jsonResponse "Booking page not found"
+// @Failure 409 {object} jsonResponse "Time not available or booking limit reached"
+// @Failure 429 {object} jsonResponse "Too many requests"
+// @Failure 500 {object} jsonResponse "Error booking"
+// @Router /book/{slug} [post]
+func (app *Config) Book(w http.ResponseWriter, r *http.Request) {
+	var req BookRequest
+	err := app.readJSON(w, r, &req)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+	if req.Start.IsZero() {
+		app.errorJSON(w, errors.New("start is required"), http.StatusBadRequest)
+		return
+	}
+
+	meeting, err := app.Models.Book(r.Context(), chi.URLParam(r, "slug"), data.Booking{
+		GuestEmail: req.GuestEmail,
+		GuestName:  req.GuestName,
+		Start:      req.Start,
+		Answers:    req.Answers,
+	})
+	switch {
+	case errors.Is(err, data.ErrBookingPageNotFound):
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	case errors.Is(err, data.ErrInvalidBooking):
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	case errors.Is(err, data.ErrTimeUnavailable), errors.Is(err, data.ErrGuestLimitReached), errors.Is(err, data.ErrNoHostAvailable):
+		app.errorJSON(w, err, http.StatusConflict)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to book: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Meeting booked",
+		Data: BookingView{
+			Reference: meeting.EventID,
+			Title:     meeting.Title,
+			Start:     meeting.Start,
+			End:       meeting.End,
+			MeetURL:   meeting.MeetURL,
+		},
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
@@ -2,41 +2,139 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"calendar-extension/data"
+	"calendar-extension/timeparse"
+
+	"github.com/go-chi/chi/v5"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/idtoken"
 )
 
 var oauthConfig = &oauth2.Config{
 	ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
 	ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
 	RedirectURL:  "http://localhost:80/oauth2callback",
-	Scopes:       []string{calendar.CalendarReadonlyScope},
+	Scopes:       []string{calendar.CalendarReadonlyScope, "openid", "email"},
 	Endpoint:     google.Endpoint,
 }
 
+// verifiedEmail validates the ID token returned alongside token and returns the
+// email address Google has verified for the authorizing user.
+func verifiedEmail(ctx context.Context, token *oauth2.Token) (string, error) {
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return "", fmt.Errorf("no id_token in token response")
+	}
+
+	payload, err := idtoken.Validate(ctx, rawIDToken, oauthConfig.ClientID)
+	if err != nil {
+		return "", fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	email, _ := payload.Claims["email"].(string)
+	if email == "" {
+		return "", fmt.Errorf("id_token has no email claim")
+	}
+
+	verified, _ := payload.Claims["email_verified"].(bool)
+	if !verified {
+		return "", fmt.Errorf("email %s is not verified", email)
+	}
+
+	return email, nil
+}
+
+const (
+	// oauthStateCookie holds the state of the last consent link given to a browser,
+	// which the callback has to be redirected with.
+	oauthStateCookie = "oauth_state"
+	// oauthStateTTL is how long a consent link can be used.
+	oauthStateTTL = 10 * time.Minute
+)
+
+// authURL returns the Google consent page link. With write set it additionally asks for
+// permission to manage events, keeping the scopes the user granted before (incremental
+// authorization). A non-empty email preselects the user's Google account. The link
+// carries a random state, which is also set in a short-lived cookie on w so that
+// OAuthCallback only completes sign-ins started by the same browser.
+func authURL(w http.ResponseWriter, email string, write bool) (string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate oauth state: %w", err)
+	}
+	state := base64.RawURLEncoding.EncodeToString(buf)
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/oauth2callback",
+		MaxAge:   int(oauthStateTTL.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	config := *oauthConfig
+	opts := []oauth2.AuthCodeOption{oauth2.AccessTypeOffline}
+	if write {
+		config.Scopes = append(append([]string{}, oauthConfig.Scopes...), calendar.CalendarEventsScope)
+		opts = append(opts, oauth2.SetAuthURLParam("include_granted_scopes", "true"))
+	}
+	if email != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("login_hint", email))
+	}
+
+	return config.AuthCodeURL(state, opts...), nil
+}
+
 // AddUser handles user authorization process
 // @Summary Initiates user authorization
 // @Description Redirects the user to Google OAuth2 authorization page to allow app access.
+// @Description With access=write the link also asks for permission to create and change events, which booking meetings requires.
+// @Description The link has to be opened in the browser that requested it: the response sets a cookie, valid for 10 minutes, with the link's state, which the callback checks.
 // @Tags User
 // @Accept  json
 // @Produce  json
-// @Success 200 {string} string "User authorization link"
-// @Failure 500 {string} string "Error initiating authorization"
+// @Param access query string false "Requested calendar access: read (default) or write" Enums(read, write)
+// @Param email query string false "Email of the Google account to preselect"
+// @Success 200 {object} jsonResponse{data=string} "User authorization link"
+// @Failure 400 {object} jsonResponse "Invalid access level"
+// @Failure 500 {object} jsonResponse "Error initiating authorization"
 // @Router /add-user [post]
 func (app *Config) AddUser(w http.ResponseWriter, r *http.Request) {
-	url := oauthConfig.AuthCodeURL("state", oauth2.AccessTypeOffline)
+	access := r.URL.Query().Get("access")
+	if access != "" && access != "read" && access != "write" {
+		app.errorJSON(w, fmt.Errorf("invalid access %q: must be read or write", access), http.StatusBadRequest)
+		return
+	}
+
+	url, err := authURL(w, r.URL.Query().Get("email"), access == "write")
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
 	response := jsonResponse{
 		Error:   false,
 		Message: "Click the link to authorize the app",
 		Data:    url,
 	}
 
-	err := app.writeJSON(w, http.StatusOK, response)
+	err = app.writeJSON(w, http.StatusOK, response)
 	if err != nil {
 		app.errorJSON(w, err, http.StatusInternalServerError)
 	}
@@ -44,12 +142,15 @@ func (app *Config) AddUser(w http.ResponseWriter, r *http.Request) {
 
 // OAuthCallback handles the callback from Google after user authorization
 // @Summary Handles OAuth2 callback
-// @Description Handles the Google OAuth2 callback and stores the access token.
+// @Description Handles the Google OAuth2 callback, verifies the user's ID token and stores the access token under the verified email.
+// @Description The response carries a session token, valid for 12 hours, to send as "Authorization: Bearer <token>" on calls made as this user.
 // @Tags User
 // @Accept  json
 // @Produce  json
-// @Success 200 {string} string "Authorization successful"
-// @Failure 500 {string} string "Error during OAuth2 callback"
+// @Success 200 {object} jsonResponse{data=SignIn} "Authorization successful"
+// @Failure 400 {object} jsonResponse "No code in request, or state not matching the consent link's cookie"
+// @Failure 401 {object} jsonResponse "ID token could not be verified"
+// @Failure 500 {object} jsonResponse "Error during OAuth2 callback"
 // @Router /oauth2callback [get]
 func (app *Config) OAuthCallback(w http.ResponseWriter, r *http.Request) {
 	code := r.URL.Query().Get("code")
@@ -58,21 +159,51 @@ func (app *Config) OAuthCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The state has to be the one set in this browser with the consent link, or anyone
+	// could sign the browser in to their own account with a code of theirs
+	state := r.URL.Query().Get("state")
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		app.errorJSON(w, fmt.Errorf("invalid oauth state: open the consent link in the browser that requested it"), http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/oauth2callback", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+
 	token, err := oauthConfig.Exchange(context.Background(), code)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("failed to exchange token: %w", err), http.StatusInternalServerError)
 		return
 	}
 
-	err = app.Models.SaveUserToken(token)
+	email, err := verifiedEmail(r.Context(), token)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("failed to verify user identity: %w", err), http.StatusUnauthorized)
+		return
+	}
+
+	err = app.Models.SaveUserToken(email, token)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("failed to save token: %w", err), http.StatusInternalServerError)
 		return
 	}
 
+	// Working hours default to the time zone of the user's calendar on first login
+	err = app.Models.InitPreferences(r.Context(), email, token)
+	if err != nil {
+		log.Printf("Error setting default preferences for %s: %v", email, err)
+	}
+
+	expiresAt := time.Now().Add(sessionTokenTTL).UTC()
+	sessionToken, err := app.issueSessionToken(email, app.userScopes(email), expiresAt)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
 	response := jsonResponse{
 		Error:   false,
 		Message: "Authorization successful",
+		Data:    SignIn{Email: email, Token: sessionToken, ExpiresAt: expiresAt},
 	}
 	err = app.writeJSON(w, http.StatusOK, response)
 	if err != nil {
@@ -80,23 +211,224 @@ func (app *Config) OAuthCallback(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+const (
+	defaultAvailabilityWindow = 7 * 24 * time.Hour
+	maxAvailabilityWindow     = 31 * 24 * time.Hour
+	defaultMinDuration        = 30 * time.Minute
+)
+
+// parseSlotOptions reads the from, to, when, min_duration, tz and all_day query parameters
+// shared by the availability endpoints, applying defaults for the ones that are missing.
+func parseSlotOptions(r *http.Request) (data.SlotOptions, error) {
+	query := r.URL.Query()
+	opts := data.SlotOptions{
+		MinDuration: defaultMinDuration,
+		Location:    time.UTC,
+	}
+
+	if tz := query.Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return opts, fmt.Errorf("invalid tz %q: must be an IANA time zone name", tz)
+		}
+		opts.Location = loc
+	}
+
+	var window *timeparse.Window
+	if when := query.Get("when"); when != "" {
+		if query.Get("from") != "" || query.Get("to") != "" {
+			return opts, errors.New("pass either when or from and to, not both")
+		}
+		var err error
+		window, err = timeparse.Parse(when, time.Now(), opts.Location)
+		if err != nil {
+			return opts, fmt.Errorf("invalid when %q: %w", when, err)
+		}
+		opts.From, opts.To = window.Start, window.End
+		for _, r := range window.Ranges {
+			opts.Periods = append(opts.Periods, data.Period{Start: r.Start, End: r.End})
+		}
+	}
+
+	if window == nil {
+		opts.From = time.Now().In(opts.Location)
+	}
+	if from := query.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return opts, fmt.Errorf("invalid from %q: must be an RFC 3339 timestamp", from)
+		}
+		opts.From = t.In(opts.Location)
+	}
+
+	if window == nil {
+		opts.To = opts.From.Add(defaultAvailabilityWindow)
+	}
+	if to := query.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return opts, fmt.Errorf("invalid to %q: must be an RFC 3339 timestamp", to)
+		}
+		opts.To = t.In(opts.Location)
+	}
+
+	err := checkWindow(opts)
+	if err != nil {
+		return opts, err
+	}
+
+	if minDuration := query.Get("min_duration"); minDuration != "" {
+		d, err := time.ParseDuration(minDuration)
+		if err != nil || d <= 0 {
+			return opts, fmt.Errorf("invalid min_duration %q: must be a positive duration such as 30m or 1h", minDuration)
+		}
+		opts.MinDuration = d
+	} else if window != nil && window.Duration() > 0 {
+		opts.MinDuration = window.Duration()
+	}
+
+	switch allDay := query.Get("all_day"); allDay {
+	case "", "busy":
+	case "ignore":
+		opts.IgnoreAllDay = true
+	default:
+		return opts, fmt.Errorf("invalid all_day %q: must be busy or ignore", allDay)
+	}
+
+	params := ConstraintParams{
+		BufferBefore:  query.Get("buffer_before"),
+		BufferAfter:   query.Get("buffer_after"),
+		MinNotice:     query.Get("min_notice"),
+		MaxPerDay:     json.Number(query.Get("max_per_day")),
+		MaxPerWeek:    json.Number(query.Get("max_per_week")),
+		MaxContinuous: query.Get("max_continuous"),
+		Break:         query.Get("break"),
+	}
+	opts.Overrides, err = params.overrides()
+	if err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+// ConstraintParams override the booking constraints from user preferences for a single
+// search. Durations are Go durations such as 10m; empty values keep the users' own.
+type ConstraintParams struct {
+	BufferBefore  string      `json:"buffer_before" example:"10m"`
+	BufferAfter   string      `json:"buffer_after" example:"10m"`
+	MinNotice     string      `json:"min_notice" example:"2h"`
+	MaxPerDay     json.Number `json:"max_per_day" swaggertype:"integer" example:"4"`
+	MaxPerWeek    json.Number `json:"max_per_week" swaggertype:"integer" example:"15"`
+	MaxContinuous string      `json:"max_continuous" example:"2h"`
+	Break         string      `json:"break" example:"15m"`
+}
+
+// overrides parses the non-empty parameters.
+func (p ConstraintParams) overrides() (data.ConstraintOverrides, error) {
+	var overrides data.ConstraintOverrides
+
+	durations := []struct {
+		name   string
+		value  string
+		target **time.Duration
+	}{
+		{"buffer_before", p.BufferBefore, &overrides.BufferBefore},
+		{"buffer_after", p.BufferAfter, &overrides.BufferAfter},
+		{"min_notice", p.MinNotice, &overrides.MinNotice},
+		{"max_continuous", p.MaxContinuous, &overrides.MaxContinuous},
+		{"break", p.Break, &overrides.Break},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		value, err := time.ParseDuration(d.value)
+		if err != nil || value < 0 {
+			return overrides, fmt.Errorf("invalid %s %q: must be a duration such as 10m or 1h", d.name, d.value)
+		}
+		*d.target = &value
+	}
+
+	counts := []struct {
+		name   string
+		value  json.Number
+		target **int
+	}{
+		{"max_per_day", p.MaxPerDay, &overrides.MaxPerDay},
+		{"max_per_week", p.MaxPerWeek, &overrides.MaxPerWeek},
+	}
+	for _, c := range counts {
+		if c.value == "" {
+			continue
+		}
+		value, err := strconv.Atoi(c.value.String())
+		if err != nil || value < 0 {
+			return overrides, fmt.Errorf("invalid %s %q: must be a non-negative number, 0 for no cap", c.name, c.value)
+		}
+		*c.target = &value
+	}
+
+	return overrides, nil
+}
+
+// checkWindow checks that the search window of opts is not empty and not too long.
+func checkWindow(opts data.SlotOptions) error {
+	if !opts.To.After(opts.From) {
+		return fmt.Errorf("to must be after from")
+	}
+	if opts.To.Sub(opts.From) > maxAvailabilityWindow {
+		return fmt.Errorf("window between from and to must not exceed %s", maxAvailabilityWindow)
+	}
+	return nil
+}
+
 // CheckAvailability checks user's calendar availability
 // @Summary Check user calendar availability
 // @Description Retrieves free slots from the user's Google Calendar within a given time range.
 // @Tags Calendar
 // @Accept  json
 // @Produce  json
-// @Success 200 {array} string "List of free slots"
-// @Failure 500 {string} string "Error retrieving availability"
-// @Router /check-availability [get]
+// @Param email path string true "User email"
+// @Param from query string false "Start of the window (RFC 3339), defaults to now"
+// @Param to query string false "End of the window (RFC 3339), defaults to seven days after from"
+// @Param when query string false "The window in words instead of from and to, e.g. next Tuesday afternoon; read in tz. A length it mentions is the default min_duration"
+// @Param min_duration query string false "Minimum slot length as a Go duration, e.g. 30m or 1h (default 30m)"
+// @Param tz query string false "IANA time zone of the response (default UTC); slots follow the working hours in each user's preferences"
+// @Param all_day query string false "Whether all-day events block their days: busy (default) or ignore" Enums(busy, ignore)
+// @Param buffer_before query string false "Time kept free before every meeting, overriding the users' preferences, e.g. 10m"
+// @Param buffer_after query string false "Time kept free after every meeting, overriding the users' preferences, e.g. 10m"
+// @Param min_notice query string false "How far ahead of now slots have to start, overriding the users' preferences, e.g. 2h"
+// @Param max_per_day query int false "Days with this many meetings are closed, overriding the users' preferences; 0 for no cap"
+// @Param max_per_week query int false "Weeks with this many meetings are closed, overriding the users' preferences; 0 for no cap"
+// @Param max_continuous query string false "Longest run of meetings without a break, overriding the users' preferences, e.g. 2h"
+// @Param break query string false "Gap that ends a run of meetings (default 15m when max_continuous is set)"
+// @Success 200 {object} jsonResponse{data=[]data.TimeSlot} "List of free slots"
+// @Failure 400 {object} jsonResponse "Invalid query parameters"
+// @Failure 403 {object} jsonResponse "User's authorization expired or was revoked"
+// @Failure 404 {object} jsonResponse "User has not authorized the app"
+// @Failure 500 {object} jsonResponse "Error retrieving availability"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /users/{email}/availability [get]
 func (app *Config) CheckAvailability(w http.ResponseWriter, r *http.Request) {
-	token, err := app.Models.GetUserToken("user_email@example.com")
+	email := chi.URLParam(r, "email")
+
+	opts, err := parseSlotOptions(r)
 	if err != nil {
-		app.errorJSON(w, fmt.Errorf("failed to get user token: %w", err), http.StatusInternalServerError)
+		app.errorJSON(w, err, http.StatusBadRequest)
 		return
 	}
 
-	freeSlots, err := app.Models.GetFreeSlots(r.Context(), token)
+	freeSlots, err := app.Models.GetFreeSlots(r.Context(), email, opts)
+	if errors.Is(err, data.ErrTokenNotFound) {
+		app.errorJSON(w, fmt.Errorf("user %s has not authorized the app", email), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, data.ErrInvalidToken) {
+		app.errorJSON(w, fmt.Errorf("authorization of user %s expired or was revoked", email), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("failed to get free slots: %w", err), http.StatusInternalServerError)
 		return
@@ -114,24 +446,161 @@ func (app *Config) CheckAvailability(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// AddUserToGroup adds a user to a group
-// @Summary Add a user to a group
-// @Description Adds a specified user to a specified group.
+// parseQuorum reads the required, optional and min_attendees query parameters of the
+// group availability endpoint. Email lists are comma separated.
+func parseQuorum(r *http.Request) (data.Quorum, error) {
+	query := r.URL.Query()
+	quorum := data.Quorum{
+		Required: splitList(query.Get("required")),
+		Optional: splitList(query.Get("optional")),
+	}
+
+	if minAttendees := query.Get("min_attendees"); minAttendees != "" {
+		n, err := strconv.Atoi(minAttendees)
+		if err != nil || n < 1 {
+			return quorum, fmt.Errorf("invalid min_attendees %q: must be a positive integer", minAttendees)
+		}
+		quorum.MinAttendees = n
+	}
+
+	return quorum, nil
+}
+
+// parseRoomRequirement reads a room requirement from the query parameters capacity,
+// building and features, each with prefix.
+func parseRoomRequirement(r *http.Request, prefix string) (data.RoomRequirement, error) {
+	query := r.URL.Query()
+	room := data.RoomRequirement{
+		Building: query.Get(prefix + "building"),
+		Features: splitList(query.Get(prefix + "features")),
+	}
+
+	if capacity := query.Get(prefix + "capacity"); capacity != "" {
+		n, err := strconv.Atoi(capacity)
+		if err != nil || n < 1 {
+			return room, fmt.Errorf("invalid %scapacity %q: must be a positive integer", prefix, capacity)
+		}
+		room.Capacity = n
+	}
+
+	return room, nil
+}
+
+// splitList splits a comma separated query value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// GroupAvailability checks when the members of a group are available
+// @Summary Check group availability
+// @Description Retrieves the slots in which all members of a group with a valid token are available, using Google's free/busy data only. Members without a valid token are listed in unavailable_members.
+// @Description With required, optional or min_attendees the search only needs the required members and at least min_attendees people to be free, and returns ranked_slots ordered by how many optional members can make it, each listing who is missing.
+// @Description With room_capacity, room_building or room_features slots are limited to times in which a matching room is free.
+// @Description For a group with a distribution mode only one member hosts each meeting, so slots are the times in which any member is free, and distribution names the mode.
 // @Tags Group
 // @Accept  json
 // @Produce  json
-// @Param user_data body AddUserToGroupRequest true "User and Group Data"
-// @Success 200 {string} string "User added to group"
-// @Failure 500 {string} string "Error adding userhttps://github.com/nesistor/whatsonx-meeting-scheduler to group"
-// @Router /add-user-to-group [post]
+// @Param name path string true "Group name"
+// @Param from query string false "Start of the window (RFC 3339), defaults to now"
+// @Param to query string false "End of the window (RFC 3339), defaults to seven days after from"
+// @Param when query string false "The window in words instead of from and to, e.g. next Tuesday afternoon; read in tz. A length it mentions is the default min_duration"
+// @Param min_duration query string false "Minimum slot length as a Go duration, e.g. 30m or 1h (default 30m)"
+// @Param tz query string false "IANA time zone of the response (default UTC); slots follow the working hours in each user's preferences"
+// @Param buffer_before query string false "Time kept free before every meeting, overriding the users' preferences, e.g. 10m"
+// @Param buffer_after query string false "Time kept free after every meeting, overriding the users' preferences, e.g. 10m"
+// @Param min_notice query string false "How far ahead of now slots have to start, overriding the users' preferences, e.g. 2h"
+// @Param max_per_day query int false "Days with this many meetings are closed, overriding the users' preferences; 0 for no cap"
+// @Param max_per_week query int false "Weeks with this many meetings are closed, overriding the users' preferences; 0 for no cap"
+// @Param max_continuous query string false "Longest run of meetings without a break, overriding the users' preferences, e.g. 2h"
+// @Param break query string false "Gap that ends a run of meetings (default 15m when max_continuous is set)"
+// @Param required query string false "Comma separated emails of members who must attend"
+// @Param optional query string false "Comma separated emails of members who are nice to have"
+// @Param min_attendees query int false "Minimum number of attendees who must be free"
+// @Param room_capacity query int false "Require a free room with at least this many seats"
+// @Param room_building query string false "Require the room to be in this building"
+// @Param room_features query string false "Comma separated features the room must have, e.g. video"
+// @Success 200 {object} jsonResponse{data=data.GroupAvailability} "Group availability"
+// @Failure 400 {object} jsonResponse "Invalid query parameters or a quorum email outside the group"
+// @Failure 404 {object} jsonResponse "Group not found"
+// @Failure 500 {object} jsonResponse "Error retrieving availability"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /groups/{name}/availability [get]
+func (app *Config) GroupAvailability(w http.ResponseWriter, r *http.Request) {
+	groupName := chi.URLParam(r, "name")
+
+	opts, err := parseSlotOptions(r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
 
+	quorum, err := parseQuorum(r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	opts.Room, err = parseRoomRequirement(r, "room_")
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	availability, err := app.Models.GetGroupFreeSlots(r.Context(), groupName, opts, quorum)
+	if errors.Is(err, data.ErrGroupNotFound) {
+		app.errorJSON(w, fmt.Errorf("group %s not found", groupName), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, data.ErrNotGroupMember) {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("failed to get group availability: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Group availability data",
+		Data:    availability,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// AddUserToGroupRequest names the user to add, the group and their role in it.
 type AddUserToGroupRequest struct {
-	UserEmail string `json:"user_email"`
-	GroupName string `json:"group_name"`
+	UserEmail string `json:"user_email" example:"anna@example.com"`
+	GroupName string `json:"group_name" example:"design"`
+	Role      string `json:"role" enums:"member,admin" example:"member"`
 }
 
+// AddUserToGroup adds a user to a group
+// @Summary Add a user to a group
+// @Description Adds a specified user to a specified group as a member (default) or an admin. Admins may reschedule and cancel meetings booked for the group.
+// @Tags Group
+// @Accept  json
+// @Produce  json
+// @Param user_data body AddUserToGroupRequest true "User and Group Data"
+// @Success 200 {object} jsonResponse "User added to group"
+// @Failure 400 {object} jsonResponse "Invalid body or role"
+// @Failure 500 {object} jsonResponse "Error adding user to group"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /add-user-to-group [post]
 func (app *Config) AddUserToGroup(w http.ResponseWriter, r *http.Request) {
-
 	var req AddUserToGroupRequest
 	err := app.readJSON(w, r, &req)
 	if err != nil {
@@ -139,7 +608,15 @@ func (app *Config) AddUserToGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.Models.AddUserToGroup(req.UserEmail, req.GroupName)
+	if req.Role == "" {
+		req.Role = data.RoleMember
+	}
+	if req.Role != data.RoleMember && req.Role != data.RoleAdmin {
+		app.errorJSON(w, fmt.Errorf("invalid role %q: must be member or admin", req.Role), http.StatusBadRequest)
+		return
+	}
+
+	err = app.Models.AddUserToGroup(req.UserEmail, req.GroupName, req.Role)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("failed to add user to group: %w", err), http.StatusInternalServerError)
 		return
@@ -156,14 +633,84 @@ func (app *Config) AddUserToGroup(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// SetGroupDistribution sets how a group's meetings are handed to hosts
+// @Summary Set a group's distribution mode
+// @Description Makes meetings booked with the group go to one host instead of every member. round_robin takes turns in alphabetical order, least_busy picks the member with the least meeting time in the week and weighted keeps each member's share of meetings in proportion to their weight (1 by default). An empty mode makes every member attend again.
+// @Description Hosts are only picked among the members free at the meeting time, and past assignments are stored so turns carry over restarts. Only an admin of the group, identified by the X-User-Email header, may change the mode.
+// @Tags Group
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Group name"
+// @Param X-User-Email header string true "Email of a group admin"
+// @Param distribution body data.Distribution true "Distribution mode and member weights"
+// @Success 200 {object} jsonResponse{data=data.Distribution} "Distribution updated"
+// @Failure 400 {object} jsonResponse "Invalid mode or a weight for someone outside the group"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse "Not an admin of the group"
+// @Failure 404 {object} jsonResponse "Group not found"
+// @Failure 500 {object} jsonResponse "Error updating distribution"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /groups/{name}/distribution [put]
+func (app *Config) SetGroupDistribution(w http.ResponseWriter, r *http.Request) {
+	groupName := chi.URLParam(r, "name")
+
+	actor, ok := app.actor(w, r)
+	if !ok {
+		return
+	}
+
+	var distribution data.Distribution
+	err := app.readJSON(w, r, &distribution)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	err = distribution.Validate()
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = app.Models.SetGroupDistribution(groupName, actor, distribution)
+	switch {
+	case errors.Is(err, data.ErrGroupNotFound):
+		app.errorJSON(w, fmt.Errorf("group %s not found", groupName), http.StatusNotFound)
+		return
+	case errors.Is(err, data.ErrNotAllowed):
+		app.errorJSON(w, fmt.Errorf("%s is not an admin of group %s", actor, groupName), http.StatusForbidden)
+		return
+	case errors.Is(err, data.ErrNotGroupMember):
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to set group distribution: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: fmt.Sprintf("Distribution of group %s updated", groupName),
+		Data:    distribution,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
 // ListUsers lists all users
 // @Summary List all users
 // @Description Retrieves the list of all users from the database.
 // @Tags User
 // @Accept  json
 // @Produce  json
-// @Success 200 {array} string "List of users"
-// @Failure 500 {string} string "Error listing users"
+// @Success 200 {object} jsonResponse{data=[]string} "List of users"
+// @Failure 500 {object} jsonResponse "Error listing users"
+// @Security ApiKey
+// @Security BearerAuth
 // @Router /list-users [get]
 func (app *Config) ListUsers(w http.ResponseWriter, r *http.Request) {
 	users, err := app.Models.ListUsers()
@@ -190,8 +737,10 @@ func (app *Config) ListUsers(w http.ResponseWriter, r *http.Request) {
 // @Tags Group
 // @Accept  json
 // @Produce  json
-// @Success 200 {array} string "List of groups"
-// @Failure 500 {string} string "Error listing groups"
+// @Success 200 {object} jsonResponse{data=[]string} "List of groups"
+// @Failure 500 {object} jsonResponse "Error listing groups"
+// @Security ApiKey
+// @Security BearerAuth
 // @Router /list-groups [get]
 func (app *Config) ListGroups(w http.ResponseWriter, r *http.Request) {
 	groups, err := app.Models.ListGroups()
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"calendar-extension/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultHoldTTL = 15 * time.Minute
+	maxHoldTTL     = 24 * time.Hour
+	// holdReapInterval is how often expired holds are released.
+	holdReapInterval = time.Minute
+)
+
+// CreateHoldRequest is the body of a hold: the meeting it reserves a slot for and how
+// long the reservation lasts.
+type CreateHoldRequest struct {
+	CreateMeetingRequest
+	TTL string `json:"ttl" example:"15m"`
+}
+
+// CreateHold reserves a proposed slot
+// @Summary Hold a slot
+// @Description Reserves a proposed meeting slot for the organizer and the attendees, including every member of the listed groups, or the one member picked to host for a group with a distribution mode, until the hold expires or is confirmed. While a hold is active its slot is busy in every availability search, so the same slot is not offered to anyone else.
+// @Description The organizer has to be the user in the X-User-Email header, unless the call has the admin scope. ttl is a duration such as 15m (default 15m, at most 24h). Holds are kept in the service only; nothing is written to the calendars until the hold is confirmed.
+// @Tags Meeting
+// @Accept  json
+// @Produce  json
+// @Param X-User-Email header string true "Email of the organizer"
+// @Param hold body CreateHoldRequest true "Meeting to hold a slot for"
+// @Success 201 {object} jsonResponse{data=data.Hold} "Slot held"
+// @Failure 400 {object} jsonResponse "Invalid hold"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse{data=string} "Organizer is not the acting user, or has to grant write access"
+// @Failure 404 {object} jsonResponse "Organizer or group not found"
+// @Failure 409 {object} jsonResponse "Slot overlaps an active hold or no host is available"
+// @Failure 500 {object} jsonResponse "Error holding slot"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /holds [post]
+func (app *Config) CreateHold(w http.ResponseWriter, r *http.Request) {
+	var req CreateHoldRequest
+	err := app.readJSON(w, r, &req)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	err = req.validate()
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if !app.requireActor(w, r, req.Organizer) {
+		return
+	}
+	if req.Recurrence != "" {
+		app.errorJSON(w, errors.New("recurring meetings cannot be held, book them directly"), http.StatusBadRequest)
+		return
+	}
+	if req.Room != nil {
+		app.errorJSON(w, errors.New("rooms cannot be held, book the meeting directly"), http.StatusBadRequest)
+		return
+	}
+
+	ttl := defaultHoldTTL
+	if req.TTL != "" {
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 || ttl > maxHoldTTL {
+			app.errorJSON(w, fmt.Errorf("invalid ttl %q: expected a positive duration of at most %s", req.TTL, maxHoldTTL), http.StatusBadRequest)
+			return
+		}
+	}
+
+	hold, err := app.Models.CreateHold(r.Context(), data.MeetingRequest{
+		Organizer:   req.Organizer,
+		Attendees:   req.Attendees,
+		Groups:      req.Groups,
+		Title:       req.Title,
+		Description: req.Description,
+		Start:       req.Start,
+		End:         req.End,
+	}, ttl)
+	switch {
+	case errors.Is(err, data.ErrWriteAccessRequired):
+		app.writeAccessRequired(w, req.Organizer)
+		return
+	case errors.Is(err, data.ErrTokenNotFound):
+		app.errorJSON(w, fmt.Errorf("organizer %s has not authorized the app", req.Organizer), http.StatusNotFound)
+		return
+	case errors.Is(err, data.ErrGroupNotFound):
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	case errors.Is(err, data.ErrSlotHeld), errors.Is(err, data.ErrNoHostAvailable):
+		app.errorJSON(w, err, http.StatusConflict)
+		return
+	case errors.Is(err, data.ErrInvalidDistribution):
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to hold slot: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Slot held",
+		Data:    hold,
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// ConfirmHold books the meeting of a hold
+// @Summary Confirm a hold
+// @Description Books the meeting a hold was created for, with a Google Meet link and invitations, and returns it. Only the organizer of the hold, identified by the X-User-Email header, may confirm it, and only before it expires.
+// @Tags Meeting
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Hold ID"
+// @Param X-User-Email header string true "Email of the organizer"
+// @Success 201 {object} jsonResponse{data=data.Meeting} "Meeting created"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse "Not allowed to confirm the hold"
+// @Failure 404 {object} jsonResponse "Hold, organizer or group not found"
+// @Failure 409 {object} jsonResponse "Hold expired or already confirmed"
+// @Failure 500 {object} jsonResponse "Error confirming hold"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /holds/{id}/confirm [post]
+func (app *Config) ConfirmHold(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	actor, ok := app.actor(w, r)
+	if !ok {
+		return
+	}
+
+	meeting, err := app.Models.ConfirmHold(r.Context(), id, actor)
+	switch {
+	case errors.Is(err, data.ErrHoldNotFound):
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	case errors.Is(err, data.ErrHoldInactive):
+		app.errorJSON(w, err, http.StatusConflict)
+		return
+	case errors.Is(err, data.ErrTokenNotFound):
+		app.errorJSON(w, fmt.Errorf("organizer %s has not authorized the app", actor), http.StatusNotFound)
+		return
+	case errors.Is(err, data.ErrGroupNotFound):
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	case err != nil:
+		app.meetingError(w, actor, err)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Meeting created",
+		Data:    meeting,
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// releaseExpiredHolds releases holds past their expiry every interval. Expired holds
+// stop blocking availability as soon as they expire; this keeps their status accurate.
+func (app *Config) releaseExpiredHolds(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		released, err := app.Models.ReleaseExpiredHolds()
+		if err != nil {
+			log.Printf("Error releasing expired holds: %v", err)
+			continue
+		}
+		if released > 0 {
+			log.Printf("Released %d expired holds", released)
+		}
+	}
+}
@@ -13,15 +13,27 @@
 // @host localhost:80
 // @BasePath /
 // @schemes http
+
+// @securityDefinitions.apikey ApiKey
+// @in header
+// @name X-API-Key
+// @description API key issued with POST /api-keys
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Session token returned after signing in, as "Bearer <token>"
 package main
 
 import (
+	"crypto/rand"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
+	_ "time/tzdata"
 
 	"calendar-extension/data"
 
@@ -37,6 +49,10 @@ var counts int64
 type Config struct {
 	DB     *sql.DB
 	Models data.Models
+	// Admins are the emails allowed to manage rooms and other shared resources.
+	Admins map[string]bool
+	// TokenSecret signs the session tokens issued to users after they sign in.
+	TokenSecret []byte
 }
 
 func main() {
@@ -50,8 +66,39 @@ func main() {
 	app := Config{
 		DB:     conn,
 		Models: data.NewModels(conn),
+		Admins: map[string]bool{},
+	}
+
+	// ADMIN_EMAILS is a comma separated list of the users who may manage rooms
+	for _, email := range splitList(os.Getenv("ADMIN_EMAILS")) {
+		app.Admins[email] = true
 	}
 
+	// SESSION_SECRET signs session tokens; without it they only last until a restart
+	app.TokenSecret = []byte(os.Getenv("SESSION_SECRET"))
+	if len(app.TokenSecret) == 0 {
+		log.Println("SESSION_SECRET is not set, session tokens will stop working on restart")
+		app.TokenSecret = make([]byte, 32)
+		_, err := rand.Read(app.TokenSecret)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	// Local development can run without Google by keeping calendars in memory
+	if os.Getenv("CALENDAR_PROVIDER") == "fake" {
+		log.Println("Using in-memory fake calendar provider")
+		app.Models.Calendar = data.NewFakeCalendar()
+	}
+
+	err := app.Models.InitializeDatabase()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	go app.releaseExpiredHolds(holdReapInterval)
+	go app.removeExpiredSessions(sessionReapInterval)
+
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%s", webPort),
 		Handler: app.routes(),
@@ -74,7 +121,7 @@ func connectToDB() *sql.DB {
 	for {
 		connection, err := openDB(dsn)
 		if err != nil {
-			fmt.Errorf("Error opening database: %w", err)
+			log.Printf("Error opening database: %v", err)
 			counts++
 		} else {
 			log.Printf("Connected to Postgres!")
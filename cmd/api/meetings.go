@@ -0,0 +1,333 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"calendar-extension/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateMeetingRequest is the body of a meeting booking.
+type CreateMeetingRequest struct {
+	Organizer   string    `json:"organizer" example:"anna@example.com"`
+	Attendees   []string  `json:"attendees" example:"bob@example.com"`
+	Groups      []string  `json:"groups" example:"design"`
+	Title       string    `json:"title" example:"Design sync"`
+	Description string    `json:"description" example:"Weekly review of open design questions"`
+	Start       time.Time `json:"start" format:"date-time" example:"2024-05-06T10:00:00+02:00"`
+	End         time.Time `json:"end" format:"date-time" example:"2024-05-06T10:30:00+02:00"`
+	// Recurrence is an RRULE with FREQ=DAILY, WEEKLY or MONTHLY, INTERVAL, BYDAY and
+	// COUNT or UNTIL. Start has to be the first occurrence.
+	Recurrence string `json:"recurrence,omitempty" example:"RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=10"`
+	// Force books a series even if attendees are busy at some occurrences.
+	Force bool `json:"force,omitempty" example:"false"`
+	// Room asks for a free room to be booked with the meeting.
+	Room *data.RoomRequirement `json:"room,omitempty"`
+}
+
+// validate checks that the request describes a meeting that can be booked.
+func (req CreateMeetingRequest) validate() error {
+	switch {
+	case req.Organizer == "":
+		return errors.New("organizer is required")
+	case req.Title == "":
+		return errors.New("title is required")
+	case req.Start.IsZero() || req.End.IsZero():
+		return errors.New("start and end are required")
+	case !req.End.After(req.Start):
+		return errors.New("end must be after start")
+	}
+	return nil
+}
+
+// writeAccessRequired tells the caller that email has to grant write access to their
+// calendar, returning the consent link that grants it.
+func (app *Config) writeAccessRequired(w http.ResponseWriter, email string) {
+	url, err := authURL(w, email, true)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   true,
+		Message: fmt.Sprintf("user %s has to allow the app to manage calendar events", email),
+		Data:    url,
+	}
+
+	err = app.writeJSON(w, http.StatusForbidden, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// CreateMeeting books a meeting with a Google Meet link
+// @Summary Book a meeting
+// @Description Creates an event with a Google Meet conference on the organizer's calendar and emails invitations to the attendees and to every member of the listed groups.
+// @Description The organizer has to be the user in the X-User-Email header, unless the call has the admin scope. If the organizer has only granted read access, the 403 response carries in data the link that grants write access.
+// @Description With a recurrence the meeting is created as one recurring event in the organizer's time zone. Every occurrence is checked first: occurrences at which attendees are busy or outside their working hours are returned with a 409, each with up to three free times on the same day, unless force is set.
+// @Description With room the smallest registered room meeting the requirement that is free for the meeting, or for every occurrence, is booked as a resource attendee; a 409 is returned when there is none.
+// @Description A group with a distribution mode sends one host instead of all its members: the host is picked among the members free at the time and returned in host. Only one such group can be listed; a 409 is returned when none of its members is free.
+// @Tags Meeting
+// @Accept  json
+// @Produce  json
+// @Param X-User-Email header string true "Email of the organizer"
+// @Param meeting body CreateMeetingRequest true "Meeting details"
+// @Success 201 {object} jsonResponse{data=data.Meeting} "Meeting created"
+// @Failure 400 {object} jsonResponse "Invalid meeting"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse{data=string} "Organizer is not the acting user, or has to grant write access"
+// @Failure 404 {object} jsonResponse "Organizer or group not found"
+// @Failure 409 {object} jsonResponse{data=[]data.OccurrenceConflict} "Attendees are busy at some occurrences, or no room or host is free"
+// @Failure 500 {object} jsonResponse "Error creating meeting"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /meetings [post]
+func (app *Config) CreateMeeting(w http.ResponseWriter, r *http.Request) {
+	var req CreateMeetingRequest
+	err := app.readJSON(w, r, &req)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	err = req.validate()
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if !app.requireActor(w, r, req.Organizer) {
+		return
+	}
+
+	var recurrence *data.Recurrence
+	if req.Recurrence != "" {
+		recurrence, err = data.ParseRecurrence(req.Recurrence)
+		if err != nil {
+			app.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var room data.RoomRequirement
+	if req.Room != nil {
+		room = *req.Room
+		if room.Capacity < 1 {
+			app.errorJSON(w, errors.New("room capacity must be at least 1"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	meeting, err := app.Models.CreateMeeting(r.Context(), data.MeetingRequest{
+		Organizer:   req.Organizer,
+		Attendees:   req.Attendees,
+		Groups:      req.Groups,
+		Title:       req.Title,
+		Description: req.Description,
+		Start:       req.Start,
+		End:         req.End,
+		Recurrence:  recurrence,
+		Force:       req.Force,
+		Room:        room,
+	})
+	var conflict *data.SeriesConflictError
+	switch {
+	case errors.As(err, &conflict):
+		response := jsonResponse{
+			Error:   true,
+			Message: err.Error(),
+			Data:    conflict.Conflicts,
+		}
+		app.writeJSON(w, http.StatusConflict, response)
+		return
+	case errors.Is(err, data.ErrInvalidRecurrence):
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	case errors.Is(err, data.ErrNoRoomAvailable), errors.Is(err, data.ErrNoHostAvailable):
+		app.errorJSON(w, err, http.StatusConflict)
+		return
+	case errors.Is(err, data.ErrInvalidDistribution):
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	case errors.Is(err, data.ErrWriteAccessRequired), errors.Is(err, data.ErrInvalidToken):
+		app.writeAccessRequired(w, req.Organizer)
+		return
+	case errors.Is(err, data.ErrTokenNotFound):
+		app.errorJSON(w, fmt.Errorf("organizer %s has not authorized the app", req.Organizer), http.StatusNotFound)
+		return
+	case errors.Is(err, data.ErrGroupNotFound):
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to create meeting: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Meeting created",
+		Data:    meeting,
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// actorHeader carries the email of the person on whose behalf the caller acts.
+const actorHeader = "X-User-Email"
+
+// UpdateMeetingRequest is the body of a meeting change. Omitted fields are left as they are.
+type UpdateMeetingRequest struct {
+	Title       string    `json:"title" example:"Design sync (moved)"`
+	Description string    `json:"description" example:"Moved because of the release"`
+	Start       time.Time `json:"start" format:"date-time" example:"2024-05-07T10:00:00+02:00"`
+	End         time.Time `json:"end" format:"date-time" example:"2024-05-07T10:30:00+02:00"`
+	Force       bool      `json:"force" example:"false"`
+}
+
+// meetingError sends the response matching an error from changing a meeting.
+func (app *Config) meetingError(w http.ResponseWriter, organizer string, err error) {
+	var conflict *data.ConflictError
+	switch {
+	case errors.As(err, &conflict):
+		response := jsonResponse{
+			Error:   true,
+			Message: err.Error(),
+			Data:    conflict.Emails,
+		}
+		app.writeJSON(w, http.StatusConflict, response)
+	case errors.Is(err, data.ErrWriteAccessRequired), errors.Is(err, data.ErrInvalidToken):
+		app.writeAccessRequired(w, organizer)
+	case errors.Is(err, data.ErrMeetingNotFound), errors.Is(err, data.ErrEventNotFound):
+		app.errorJSON(w, data.ErrMeetingNotFound, http.StatusNotFound)
+	case errors.Is(err, data.ErrNotAllowed):
+		app.errorJSON(w, err, http.StatusForbidden)
+	case errors.Is(err, data.ErrMeetingCancelled), errors.Is(err, data.ErrSeriesMove):
+		app.errorJSON(w, err, http.StatusConflict)
+	default:
+		app.errorJSON(w, fmt.Errorf("failed to change meeting: %w", err), http.StatusInternalServerError)
+	}
+}
+
+// UpdateMeeting reschedules or edits a meeting
+// @Summary Reschedule a meeting
+// @Description Changes the title, description or time of a meeting booked through the API and notifies the attendees. Before moving a meeting the availability of the attendees and of its room at the new time is checked; busy attendees and rooms are returned with a 409 unless force is set. A recurring meeting cannot be moved, which is refused with a 409.
+// @Description Only the organizer or an admin of one of the meeting's groups, identified by the X-User-Email header, may change a meeting.
+// @Tags Meeting
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Google Calendar event ID"
+// @Param X-User-Email header string true "Email of the user making the change"
+// @Param change body UpdateMeetingRequest true "Fields to change"
+// @Success 200 {object} jsonResponse{data=data.Meeting} "Meeting updated"
+// @Failure 400 {object} jsonResponse "Invalid change"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse "Not allowed to change the meeting"
+// @Failure 404 {object} jsonResponse "Meeting not found"
+// @Failure 409 {object} jsonResponse{data=[]string} "Attendees or the room are busy at the new time, the meeting is recurring or it was cancelled"
+// @Failure 500 {object} jsonResponse "Error updating meeting"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /meetings/{id} [patch]
+func (app *Config) UpdateMeeting(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+
+	actor, ok := app.actor(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateMeetingRequest
+	err := app.readJSON(w, r, &req)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+	if !req.Start.IsZero() && !req.End.IsZero() && !req.End.After(req.Start) {
+		app.errorJSON(w, errors.New("end must be after start"), http.StatusBadRequest)
+		return
+	}
+
+	meeting, err := app.Models.RescheduleMeeting(r.Context(), eventID, actor, data.MeetingChange{
+		Title:       req.Title,
+		Description: req.Description,
+		Start:       req.Start,
+		End:         req.End,
+		Force:       req.Force,
+	})
+	if err != nil {
+		app.meetingError(w, app.meetingOrganizer(eventID), err)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Meeting updated",
+		Data:    meeting,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// CancelMeeting cancels a meeting
+// @Summary Cancel a meeting
+// @Description Deletes a meeting booked through the API from the organizer's calendar, notifies the attendees and records it as cancelled.
+// @Description Only the organizer or an admin of one of the meeting's groups, identified by the X-User-Email header, may cancel a meeting.
+// @Tags Meeting
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Google Calendar event ID"
+// @Param X-User-Email header string true "Email of the user cancelling the meeting"
+// @Success 200 {object} jsonResponse{data=data.Meeting} "Meeting cancelled"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse "Not allowed to cancel the meeting"
+// @Failure 404 {object} jsonResponse "Meeting not found"
+// @Failure 409 {object} jsonResponse "Meeting already cancelled"
+// @Failure 500 {object} jsonResponse "Error cancelling meeting"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /meetings/{id} [delete]
+func (app *Config) CancelMeeting(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+
+	actor, ok := app.actor(w, r)
+	if !ok {
+		return
+	}
+
+	meeting, err := app.Models.CancelMeeting(r.Context(), eventID, actor)
+	if err != nil {
+		app.meetingError(w, app.meetingOrganizer(eventID), err)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Meeting cancelled",
+		Data:    meeting,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// meetingOrganizer returns the organizer of the meeting with eventID, or an empty
+// string if it cannot be looked up.
+func (app *Config) meetingOrganizer(eventID string) string {
+	meeting, err := app.Models.GetMeeting(eventID)
+	if err != nil {
+		return ""
+	}
+	return meeting.Organizer
+}
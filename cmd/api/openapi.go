@@ -0,0 +1,669 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"calendar-extension/data"
+	"calendar-extension/openapi"
+	"calendar-extension/timeparse"
+)
+
+// Security schemes of the document.
+const (
+	// apiKeyScheme is an API key in apiKeyHeader.
+	apiKeyScheme = "ApiKey"
+	// bearerScheme is a session token issued after signing in.
+	bearerScheme = "BearerAuth"
+	// userEmailScheme names the user an API key acts for in actorHeader.
+	userEmailScheme = "UserEmail"
+)
+
+// response is a possible response of an endpoint. data is a value of the type in the
+// data field of the JSON body, or nil when the body carries only a message.
+type response struct {
+	status      int
+	description string
+	data        any
+}
+
+// endpoint describes a route for the OpenAPI document.
+type endpoint struct {
+	method      string
+	path        string
+	id          string
+	tag         string
+	summary     string
+	description string
+	// scope is the scope callers need, or empty for public endpoints.
+	scope string
+	// actor is set for endpoints that act on behalf of the user in actorHeader.
+	actor     bool
+	params    []openapi.Parameter
+	body      any
+	bodyNote  string
+	optional  bool
+	responses []response
+}
+
+func pathParam(name, description string, example any) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "path", Description: description, Required: true, Schema: &openapi.Schema{Type: "string"}, Example: example}
+}
+
+func queryParam(name, typ, description string, example any) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: typ}, Example: example}
+}
+
+// slotParams are the query parameters shared by the availability endpoints.
+var slotParams = []openapi.Parameter{
+	queryParam("from", "string", "Start of the window (RFC 3339), defaults to now", "2024-05-06T00:00:00+02:00"),
+	queryParam("to", "string", "End of the window (RFC 3339), defaults to seven days after from", "2024-05-10T00:00:00+02:00"),
+	queryParam("when", "string", "The window in words instead of from and to, read in tz. A length it mentions is the default min_duration", "next Tuesday afternoon"),
+	queryParam("min_duration", "string", "Minimum slot length as a Go duration (default 30m)", "30m"),
+	queryParam("tz", "string", "IANA time zone of the response (default UTC)", "Europe/Warsaw"),
+	queryParam("buffer_before", "string", "Time kept free before every meeting, overriding the users' preferences", "10m"),
+	queryParam("buffer_after", "string", "Time kept free after every meeting, overriding the users' preferences", "10m"),
+	queryParam("min_notice", "string", "How far ahead of now slots have to start, overriding the users' preferences", "2h"),
+	queryParam("max_per_day", "integer", "Days with this many meetings are closed, overriding the users' preferences; 0 for no cap", 4),
+	queryParam("max_per_week", "integer", "Weeks with this many meetings are closed, overriding the users' preferences; 0 for no cap", 15),
+	queryParam("max_continuous", "string", "Longest run of meetings without a break, overriding the users' preferences", "2h"),
+	queryParam("break", "string", "Gap that ends a run of meetings (default 15m when max_continuous is set)", "15m"),
+}
+
+// params joins lists of parameters into a new slice.
+func params(lists ...[]openapi.Parameter) []openapi.Parameter {
+	var all []openapi.Parameter
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	return all
+}
+
+// endpoints lists every route served by routes() except the documentation itself.
+var endpoints = []endpoint{
+	{
+		method: "POST", path: "/add-user", id: "addUser", tag: "User",
+		summary:     "Get an authorization link",
+		description: "Returns a link to Google's consent page that lets the app read the user's calendar. With access=write it also asks for permission to create and change events, which booking meetings requires. The link has to be opened in the browser that requested it, which gets a short-lived cookie with the link's state.",
+		params: []openapi.Parameter{
+			{Name: "access", In: "query", Description: "Requested calendar access", Schema: &openapi.Schema{Type: "string", Enum: []any{"read", "write"}}, Example: "read"},
+			queryParam("email", "string", "Email of the Google account to preselect", "anna@example.com"),
+		},
+		responses: []response{
+			{http.StatusOK, "Authorization link", ""},
+			{http.StatusBadRequest, "Invalid access level", nil},
+		},
+	},
+	{
+		method: "GET", path: "/oauth2callback", id: "oauthCallback", tag: "User",
+		summary:     "Complete authorization",
+		description: "Google redirects the user here after consent. The state has to match the cookie set with the consent link. The user's ID token is verified, the access token stored under the verified email and a session token returned for calls made as the user.",
+		params: []openapi.Parameter{
+			queryParam("code", "string", "Authorization code issued by Google", nil),
+			queryParam("state", "string", "State passed to the consent page", nil),
+		},
+		responses: []response{
+			{http.StatusOK, "Authorization successful; data holds the session token", SignIn{}},
+			{http.StatusBadRequest, "No code in request, or state not matching the consent link's cookie", nil},
+			{http.StatusUnauthorized, "ID token could not be verified", nil},
+			{http.StatusInternalServerError, "Error during authorization", nil},
+		},
+	},
+	{
+		method: "GET", path: "/users/{email}/availability", id: "checkAvailability", tag: "Calendar", scope: data.ScopeReadAvailability,
+		summary:     "Check a user's availability",
+		description: "Returns the free slots of the user within their working hours, as tentative when the user has only been invited.",
+		params: params([]openapi.Parameter{pathParam("email", "User email", "anna@example.com")}, slotParams, []openapi.Parameter{
+			{Name: "all_day", In: "query", Description: "Whether all-day events block their days", Schema: &openapi.Schema{Type: "string", Enum: []any{"busy", "ignore"}}, Example: "busy"},
+		}),
+		responses: []response{
+			{http.StatusOK, "Free slots", []data.TimeSlot{}},
+			{http.StatusBadRequest, "Invalid query parameters", nil},
+			{http.StatusForbidden, "User's authorization expired or was revoked", nil},
+			{http.StatusNotFound, "User has not authorized the app", nil},
+		},
+	},
+	{
+		method: "GET", path: "/users/{email}/preferences", id: "getPreferences", tag: "User", scope: data.ScopeReadAvailability,
+		summary:     "Get a user's preferences",
+		description: "Returns the user's working hours, time zone and booking constraints.",
+		params:      []openapi.Parameter{pathParam("email", "User email", "anna@example.com")},
+		responses: []response{
+			{http.StatusOK, "User preferences", data.Preferences{}},
+			{http.StatusNotFound, "User not found", nil},
+		},
+	},
+	{
+		method: "PUT", path: "/users/{email}/preferences", id: "updatePreferences", tag: "User", scope: data.ScopeBookMeetings,
+		summary:     "Set a user's preferences",
+		description: "Replaces the user's working hours, time zone and booking constraints. Users set their own preferences; calls with the admin scope may set anyone's.",
+		actor:       true,
+		params:      []openapi.Parameter{pathParam("email", "User email", "anna@example.com")},
+		body:        data.Preferences{}, bodyNote: "New preferences; email is taken from the path",
+		responses: []response{
+			{http.StatusOK, "Preferences saved", data.Preferences{}},
+			{http.StatusBadRequest, "Invalid preferences", nil},
+			{http.StatusForbidden, "Not the acting user", nil},
+			{http.StatusNotFound, "User not found", nil},
+		},
+	},
+	{
+		method: "POST", path: "/add-user-to-group", id: "addUserToGroup", tag: "Group", scope: data.ScopeAdmin,
+		summary:     "Add a user to a group",
+		description: "Adds the user to the group as a member (default) or an admin. Admins may reschedule and cancel meetings booked for the group.",
+		body:        AddUserToGroupRequest{},
+		responses: []response{
+			{http.StatusOK, "User added to group", nil},
+			{http.StatusBadRequest, "Invalid body or role", nil},
+		},
+	},
+	{
+		method: "GET", path: "/list-users", id: "listUsers", tag: "User", scope: data.ScopeReadAvailability,
+		summary:     "List users",
+		description: "Returns the emails of all users who authorized the app.",
+		responses: []response{
+			{http.StatusOK, "Emails of the users", []string{}},
+		},
+	},
+	{
+		method: "GET", path: "/list-groups", id: "listGroups", tag: "Group", scope: data.ScopeReadAvailability,
+		summary:     "List groups",
+		description: "Returns the names of all groups.",
+		responses: []response{
+			{http.StatusOK, "Names of the groups", []string{}},
+		},
+	},
+	{
+		method: "GET", path: "/groups/{name}/availability", id: "groupAvailability", tag: "Group", scope: data.ScopeReadAvailability,
+		summary:     "Check a group's availability",
+		description: "Returns the slots in which all members are free or, with required, optional or min_attendees, the slots ranked by how many members can attend. Members who have not authorized the app are listed as unavailable.",
+		params: params([]openapi.Parameter{pathParam("name", "Group name", "design")}, slotParams, []openapi.Parameter{
+			queryParam("required", "string", "Comma separated emails of members who must attend", "anna@example.com"),
+			queryParam("optional", "string", "Comma separated emails of members who are nice to have", "bob@example.com"),
+			queryParam("min_attendees", "integer", "Minimum number of attendees who must be free", 3),
+			queryParam("room_capacity", "integer", "Require a free room with at least this many seats", 6),
+			queryParam("room_building", "string", "Require the room to be in this building", "Krakow HQ"),
+			queryParam("room_features", "string", "Comma separated features the room must have", "video"),
+		}),
+		responses: []response{
+			{http.StatusOK, "Group availability", data.GroupAvailability{}},
+			{http.StatusBadRequest, "Invalid query parameters or a quorum email outside the group", nil},
+			{http.StatusNotFound, "Group not found", nil},
+		},
+	},
+	{
+		method: "PUT", path: "/groups/{name}/distribution", id: "setGroupDistribution", tag: "Group", scope: data.ScopeBookMeetings,
+		summary:     "Set a group's distribution mode",
+		description: "Makes meetings booked with the group go to one host picked round_robin, least_busy or weighted instead of every member. An empty mode makes every member attend again. Only an admin of the group may change it.",
+		actor:       true,
+		params:      []openapi.Parameter{pathParam("name", "Group name", "support")},
+		body:        data.Distribution{},
+		responses: []response{
+			{http.StatusOK, "Distribution updated", data.Distribution{}},
+			{http.StatusBadRequest, "Invalid mode or a weight for someone outside the group", nil},
+			{http.StatusForbidden, "Not an admin of the group", nil},
+			{http.StatusNotFound, "Group not found", nil},
+		},
+	},
+	{
+		method: "POST", path: "/meetings", id: "createMeeting", tag: "Meeting", scope: data.ScopeBookMeetings,
+		summary:     "Book a meeting",
+		description: "Creates the event on the organizer's calendar with a Google Meet link and invites the attendees and group members. A recurrence books a series, checked against every attendee's calendar first; a room requirement books the smallest free matching room. The organizer has to be the acting user unless the call has the admin scope.",
+		actor:       true,
+		body:        CreateMeetingRequest{},
+		responses: []response{
+			{http.StatusCreated, "Meeting created", data.Meeting{}},
+			{http.StatusBadRequest, "Invalid meeting", nil},
+			{http.StatusForbidden, "Organizer is not the acting user, or has to grant write access; data is the consent link", ""},
+			{http.StatusNotFound, "Organizer or group not found", nil},
+			{http.StatusConflict, "Attendees are busy at some occurrences, or no room or host is free", []data.OccurrenceConflict{}},
+		},
+	},
+	{
+		method: "PATCH", path: "/meetings/{id}", id: "updateMeeting", tag: "Meeting", scope: data.ScopeBookMeetings,
+		summary:     "Reschedule a meeting",
+		description: "Changes the title, description or time of a meeting booked through the API and notifies the attendees. A recurring meeting cannot be moved. Only the organizer or an admin of one of the meeting's groups may change it.",
+		actor:       true,
+		params:      []openapi.Parameter{pathParam("id", "Google Calendar event ID", "fake-event-1")},
+		body:        UpdateMeetingRequest{},
+		responses: []response{
+			{http.StatusOK, "Meeting updated", data.Meeting{}},
+			{http.StatusBadRequest, "Invalid change", nil},
+			{http.StatusForbidden, "Not allowed to change the meeting", nil},
+			{http.StatusNotFound, "Meeting not found", nil},
+			{http.StatusConflict, "Attendees or the room are busy at the new time, which data lists, or the meeting is recurring", []string{}},
+		},
+	},
+	{
+		method: "DELETE", path: "/meetings/{id}", id: "cancelMeeting", tag: "Meeting", scope: data.ScopeBookMeetings,
+		summary:     "Cancel a meeting",
+		description: "Cancels a meeting booked through the API and notifies the attendees. Only the organizer or an admin of one of the meeting's groups may cancel it.",
+		actor:       true,
+		params:      []openapi.Parameter{pathParam("id", "Google Calendar event ID", "fake-event-1")},
+		responses: []response{
+			{http.StatusOK, "Meeting cancelled", data.Meeting{}},
+			{http.StatusForbidden, "Not allowed to cancel the meeting", nil},
+			{http.StatusNotFound, "Meeting not found", nil},
+			{http.StatusConflict, "Meeting already cancelled", nil},
+		},
+	},
+	{
+		method: "POST", path: "/holds", id: "createHold", tag: "Meeting", scope: data.ScopeBookMeetings,
+		summary:     "Hold a slot",
+		description: "Reserves the slot of a proposed meeting for ttl (default 15m), making it busy for the organizer and attendees until it is confirmed or released. A group with a distribution mode has its host picked and held instead of every member. The organizer has to be the acting user unless the call has the admin scope.",
+		actor:       true,
+		body:        CreateHoldRequest{},
+		responses: []response{
+			{http.StatusCreated, "Slot held", data.Hold{}},
+			{http.StatusBadRequest, "Invalid hold", nil},
+			{http.StatusForbidden, "Organizer is not the acting user, or has to grant write access; data is the consent link", ""},
+			{http.StatusNotFound, "Organizer or group not found", nil},
+			{http.StatusConflict, "Slot overlaps an active hold or no host is available", nil},
+		},
+	},
+	{
+		method: "POST", path: "/holds/{id}/confirm", id: "confirmHold", tag: "Meeting", scope: data.ScopeBookMeetings,
+		summary:     "Confirm a hold",
+		description: "Books the meeting of an active hold. Only the organizer may confirm it.",
+		actor:       true,
+		params:      []openapi.Parameter{pathParam("id", "Hold ID", nil)},
+		responses: []response{
+			{http.StatusCreated, "Meeting created", data.Meeting{}},
+			{http.StatusForbidden, "Not allowed to confirm the hold", nil},
+			{http.StatusNotFound, "Hold, organizer or group not found", nil},
+			{http.StatusConflict, "Hold expired or already confirmed", nil},
+		},
+	},
+	{
+		method: "POST", path: "/suggestions", id: "suggestTimes", tag: "Meeting", scope: data.ScopeReadAvailability,
+		summary:     "Suggest meeting times",
+		description: "Ranks candidate times for a meeting with the attendees and groups, scoring preferred hours, how soon the time is, back-to-back meetings, lunch and short gaps. Every suggestion carries an explanation.",
+		body:        SuggestionsRequest{},
+		responses: []response{
+			{http.StatusOK, "Suggested times", data.Suggestions{}},
+			{http.StatusBadRequest, "Invalid search", nil},
+			{http.StatusNotFound, "Group not found", nil},
+		},
+	},
+	{
+		method: "POST", path: "/parse-window", id: "parseWindow", tag: "Calendar", scope: data.ScopeReadAvailability,
+		summary:     "Parse a time window",
+		description: "Converts a phrase such as \"next Tuesday afternoon\" into the ranges it refers to, with an interpretation to read back to the user and a confidence between 0 and 1.",
+		body:        ParseWindowRequest{},
+		responses: []response{
+			{http.StatusOK, "Interpreted window", timeparse.Window{}},
+			{http.StatusBadRequest, "No time recognized, the time has passed or invalid time zone", nil},
+			{http.StatusNotFound, "User not found", nil},
+		},
+	},
+	{
+		method: "GET", path: "/resources", id: "listResources", tag: "Resource", scope: data.ScopeReadAvailability,
+		summary:     "List rooms",
+		description: "Returns the registered rooms, optionally only those with enough seats, in a building or with some features.",
+		params: []openapi.Parameter{
+			queryParam("capacity", "integer", "Least number of seats", 6),
+			queryParam("building", "string", "Building the room is in", "Krakow HQ"),
+			queryParam("features", "string", "Comma separated features the room must have", "video"),
+		},
+		responses: []response{
+			{http.StatusOK, "Rooms", []data.Resource{}},
+			{http.StatusBadRequest, "Invalid query parameters", nil},
+		},
+	},
+	{
+		method: "POST", path: "/resources", id: "createResource", tag: "Resource", scope: data.ScopeAdmin,
+		summary:     "Register a room",
+		description: "Registers a Google resource calendar as a bookable room. Only admins may manage rooms.",
+		actor:       true,
+		body:        data.Resource{},
+		responses: []response{
+			{http.StatusCreated, "Room registered", data.Resource{}},
+			{http.StatusBadRequest, "Invalid room", nil},
+			{http.StatusForbidden, "Not an admin", nil},
+			{http.StatusConflict, "Room already registered", nil},
+		},
+	},
+	{
+		method: "PUT", path: "/resources/{id}", id: "updateResource", tag: "Resource", scope: data.ScopeAdmin,
+		summary:     "Update a room",
+		description: "Replaces the details of a registered room. Only admins may manage rooms.",
+		actor:       true,
+		params:      []openapi.Parameter{pathParam("id", "Calendar ID of the room", nil)},
+		body:        data.Resource{}, bodyNote: "Room details; calendar_id is taken from the path",
+		responses: []response{
+			{http.StatusOK, "Room updated", data.Resource{}},
+			{http.StatusBadRequest, "Invalid room", nil},
+			{http.StatusForbidden, "Not an admin", nil},
+			{http.StatusNotFound, "Room not found", nil},
+		},
+	},
+	{
+		method: "DELETE", path: "/resources/{id}", id: "deleteResource", tag: "Resource", scope: data.ScopeAdmin,
+		summary:     "Remove a room",
+		description: "Removes a registered room. Only admins may manage rooms.",
+		actor:       true,
+		params:      []openapi.Parameter{pathParam("id", "Calendar ID of the room", nil)},
+		responses: []response{
+			{http.StatusOK, "Room removed", nil},
+			{http.StatusForbidden, "Not an admin", nil},
+			{http.StatusNotFound, "Room not found", nil},
+		},
+	},
+	{
+		method: "POST", path: "/booking-pages", id: "createBookingPage", tag: "Booking", scope: data.ScopeBookMeetings,
+		summary:     "Create a booking page",
+		description: "Creates a public page on which guests book meetings with the user, or with a group of which the user is an admin, under the page's rules.",
+		actor:       true,
+		body:        data.BookingPage{}, bodyNote: "Booking page; owner is taken from the header",
+		responses: []response{
+			{http.StatusCreated, "Booking page created", data.BookingPage{}},
+			{http.StatusBadRequest, "Invalid booking page", nil},
+			{http.StatusForbidden, "Not an admin of the group", nil},
+			{http.StatusConflict, "Slug already in use", nil},
+		},
+	},
+	{
+		method: "DELETE", path: "/booking-pages/{slug}", id: "deleteBookingPage", tag: "Booking", scope: data.ScopeBookMeetings,
+		summary:     "Remove a booking page",
+		description: "Removes a booking page. Only its owner may remove it.",
+		actor:       true,
+		params:      []openapi.Parameter{pathParam("slug", "Booking page slug", "anna-intro")},
+		responses: []response{
+			{http.StatusOK, "Booking page removed", nil},
+			{http.StatusForbidden, "Not the owner of the page", nil},
+			{http.StatusNotFound, "Booking page not found", nil},
+		},
+	},
+	{
+		method: "GET", path: "/book/{slug}", id: "getBookingPage", tag: "Booking",
+		summary:     "Show a booking page",
+		description: "Returns the page's questions and the times it offers. Rate limited per client.",
+		params: []openapi.Parameter{
+			pathParam("slug", "Booking page slug", "anna-intro"),
+			queryParam("tz", "string", "IANA time zone of the times (default UTC)", "Europe/Warsaw"),
+		},
+		responses: []response{
+			{http.StatusOK, "Booking page", BookingPageView{}},
+			{http.StatusBadRequest, "Invalid time zone", nil},
+			{http.StatusNotFound, "Booking page not found", nil},
+			{http.StatusTooManyRequests, "Too many requests", nil},
+		},
+	},
+	{
+		method: "POST", path: "/book/{slug}", id: "book", tag: "Booking",
+		summary:     "Book through a booking page",
+		description: "Books one of the page's times with the guest as attendee and returns the guest's view of the meeting. Rate limited per client.",
+		params:      []openapi.Parameter{pathParam("slug", "Booking page slug", "anna-intro")},
+		body:        BookRequest{},
+		responses: []response{
+			{http.StatusCreated, "Meeting booked", BookingView{}},
+			{http.StatusBadRequest, "Missing guest email or required answer", nil},
+			{http.StatusNotFound, "Booking page not found", nil},
+			{http.StatusConflict, "Time not available or booking limit reached", nil},
+			{http.StatusTooManyRequests, "Too many requests", nil},
+		},
+	},
+	{
+		method: "POST", path: "/polls", id: "createPoll", tag: "Poll", scope: data.ScopeBookMeetings,
+		summary:     "Create a scheduling poll",
+		description: "Asks participants which of the candidate slots suit them. Each participant's vote token is returned only in this response. The organizer has to be the acting user unless the call has the admin scope.",
+		actor:       true,
+		body:        data.PollRequest{},
+		responses: []response{
+			{http.StatusCreated, "Poll created", data.Poll{}},
+			{http.StatusBadRequest, "Invalid poll", nil},
+			{http.StatusForbidden, "Organizer is not the acting user, or has to grant write access; data is the consent link", ""},
+			{http.StatusNotFound, "Organizer not found", nil},
+		},
+	},
+	{
+		method: "GET", path: "/polls/{id}", id: "getPoll", tag: "Poll", scope: data.ScopeReadAvailability,
+		summary:     "Show a scheduling poll",
+		description: "Returns the poll's slots with the participants who answered yes, if_needed or no to each.",
+		params:      []openapi.Parameter{pathParam("id", "Poll ID", nil)},
+		responses: []response{
+			{http.StatusOK, "Poll", data.Poll{}},
+			{http.StatusNotFound, "Poll not found", nil},
+		},
+	},
+	{
+		method: "POST", path: "/polls/{id}/votes/{token}", id: "vote", tag: "Poll",
+		summary:     "Vote on a scheduling poll",
+		description: "Records one answer per slot for the participant the token was issued to, replacing earlier answers.",
+		params:      []openapi.Parameter{pathParam("id", "Poll ID", nil), pathParam("token", "Participant's vote token", nil)},
+		body:        VoteRequest{},
+		responses: []response{
+			{http.StatusOK, "Vote recorded", nil},
+			{http.StatusBadRequest, "Invalid answers", nil},
+			{http.StatusForbidden, "Vote link is not valid", nil},
+			{http.StatusNotFound, "Poll not found", nil},
+			{http.StatusConflict, "Poll is closed", nil},
+		},
+	},
+	{
+		method: "POST", path: "/polls/{id}/close", id: "closePoll", tag: "Poll", scope: data.ScopeBookMeetings,
+		summary:     "Close a scheduling poll",
+		description: "Closes the poll and books the given slot, or the one most participants can attend, with every participant. Only the organizer may close it.",
+		actor:       true,
+		params:      []openapi.Parameter{pathParam("id", "Poll ID", nil)},
+		body:        ClosePollRequest{}, optional: true,
+		responses: []response{
+			{http.StatusCreated, "Meeting created", ClosedPoll{}},
+			{http.StatusBadRequest, "Invalid slot", nil},
+			{http.StatusForbidden, "Not the organizer of the poll", nil},
+			{http.StatusNotFound, "Poll not found", nil},
+			{http.StatusConflict, "Poll is already closed", nil},
+		},
+	},
+	{
+		method: "POST", path: "/sessions", id: "startSession", tag: "Session", scope: data.ScopeBookMeetings,
+		summary:     "Start a scheduling session",
+		description: "Starts a multi-turn search for a meeting organized by the user and returns the ranked candidate times. The session expires 30 minutes after its last turn.",
+		actor:       true,
+		body:        data.SessionConstraints{}, bodyNote: "What to search for; organizer is taken from the header",
+		responses: []response{
+			{http.StatusCreated, "Session started", data.Session{}},
+			{http.StatusBadRequest, "Invalid session", nil},
+			{http.StatusNotFound, "Group not found", nil},
+		},
+	},
+	{
+		method: "GET", path: "/sessions/{id}", id: "getSession", tag: "Session", scope: data.ScopeBookMeetings,
+		summary:     "Show a scheduling session",
+		description: "Returns the session's constraints and candidate times. Only the organizer may read it.",
+		actor:       true,
+		params:      []openapi.Parameter{pathParam("id", "Session ID", nil)},
+		responses: []response{
+			{http.StatusOK, "Session", data.Session{}},
+			{http.StatusForbidden, "Not the organizer of the session", nil},
+			{http.StatusNotFound, "Session not found", nil},
+			{http.StatusConflict, "Session expired", nil},
+		},
+	},
+	{
+		method: "POST", path: "/sessions/{id}/turns", id: "applyTurn", tag: "Session", scope: data.ScopeBookMeetings,
+		summary:     "Refine a scheduling session",
+		description: "Excludes days, changes the duration, window or attendees and returns new candidates, or with pick books the chosen candidate.",
+		actor:       true,
+		params:      []openapi.Parameter{pathParam("id", "Session ID", nil)},
+		body:        data.Turn{},
+		responses: []response{
+			{http.StatusOK, "Session refined", TurnResult{}},
+			{http.StatusCreated, "Meeting created", TurnResult{}},
+			{http.StatusBadRequest, "Invalid turn", nil},
+			{http.StatusForbidden, "Not the organizer, or the organizer has to grant write access", ""},
+			{http.StatusNotFound, "Session or group not found", nil},
+			{http.StatusConflict, "Session expired or already booked", nil},
+		},
+	},
+	{
+		method: "POST", path: "/api-keys", id: "createAPIKey", tag: "Auth", scope: data.ScopeAdmin,
+		summary:     "Issue an API key",
+		description: "Issues a key for a service calling the API, returned only in this response.",
+		body:        CreateAPIKeyRequest{},
+		responses: []response{
+			{http.StatusCreated, "API key issued", data.APIKey{}},
+			{http.StatusBadRequest, "Invalid key", nil},
+		},
+	},
+	{
+		method: "GET", path: "/api-keys", id: "listAPIKeys", tag: "Auth", scope: data.ScopeAdmin,
+		summary:     "List API keys",
+		description: "Returns every issued key with its scopes and expiry, without the secrets.",
+		responses: []response{
+			{http.StatusOK, "API keys", []data.APIKey{}},
+		},
+	},
+	{
+		method: "DELETE", path: "/api-keys/{id}", id: "deleteAPIKey", tag: "Auth", scope: data.ScopeAdmin,
+		summary:     "Revoke an API key",
+		description: "Revokes the key, which stops working immediately.",
+		params:      []openapi.Parameter{pathParam("id", "API key ID", nil)},
+		responses: []response{
+			{http.StatusOK, "API key revoked", nil},
+			{http.StatusNotFound, "API key not found", nil},
+		},
+	},
+}
+
+// tags describes the groups the endpoints are listed in.
+var tags = []openapi.Tag{
+	{Name: "User", Description: "Authorization and preferences of calendar users"},
+	{Name: "Calendar", Description: "Availability of a single user"},
+	{Name: "Group", Description: "Groups of users and their availability"},
+	{Name: "Meeting", Description: "Finding times and booking meetings"},
+	{Name: "Resource", Description: "Bookable rooms"},
+	{Name: "Booking", Description: "Public booking pages"},
+	{Name: "Poll", Description: "Polls for participants whose calendars cannot be read"},
+	{Name: "Session", Description: "Multi-turn scheduling conversations"},
+	{Name: "Auth", Description: "API keys of services calling the API"},
+}
+
+// envelope is the schema of a jsonResponse whose data is described by dataSchema, or
+// which carries no data when dataSchema is nil.
+func envelope(failure bool, dataSchema *openapi.Schema) *openapi.Schema {
+	s := &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"error":   {Type: "boolean", Example: failure},
+			"message": {Type: "string"},
+		},
+		Required: []string{"error", "message"},
+	}
+	if dataSchema != nil {
+		s.Properties["data"] = dataSchema
+	}
+	return s
+}
+
+// apiDocument builds the OpenAPI document of the endpoints.
+func apiDocument() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:          "Calendar Meeting Scheduler API",
+		Description:    "API for scheduling meetings and managing users/groups using Google Calendar.",
+		Version:        "1.0",
+		TermsOfService: "http://example.com/terms/",
+		Contact:        &openapi.Contact{Name: "API Support", Email: "karolmalicki.001@gmail.com"},
+		License:        &openapi.License{Name: "MIT", URL: "https://opensource.org/licenses/MIT"},
+	})
+	doc.Tags = tags
+	doc.Components.SecuritySchemes[apiKeyScheme] = &openapi.SecurityScheme{
+		Type:        "apiKey",
+		In:          "header",
+		Name:        apiKeyHeader,
+		Description: "API key issued with POST /api-keys, limited to its scopes: read-availability, book-meetings or admin",
+	}
+	doc.Components.SecuritySchemes[bearerScheme] = &openapi.SecurityScheme{
+		Type:        "http",
+		Scheme:      "bearer",
+		Description: "Session token returned after signing in with Google, acting as the signed in user",
+	}
+	doc.Components.SecuritySchemes[userEmailScheme] = &openapi.SecurityScheme{
+		Type:        "apiKey",
+		In:          "header",
+		Name:        actorHeader,
+		Description: "Email of the user an API key call acts on behalf of",
+	}
+
+	for _, e := range endpoints {
+		op := &openapi.Operation{
+			OperationID: e.id,
+			Summary:     e.summary,
+			Description: e.description,
+			Tags:        []string{e.tag},
+			Parameters:  e.params,
+			Responses:   map[string]*openapi.Response{},
+		}
+		if e.body != nil {
+			op.RequestBody = &openapi.RequestBody{
+				Description: e.bodyNote,
+				Required:    !e.optional,
+				Content:     openapi.JSON(doc.Schema(e.body)),
+			}
+		}
+		if e.scope != "" {
+			// Either an API key, naming the acting user if the endpoint needs one, or a
+			// session token, which names the user itself
+			withKey := openapi.SecurityRequirement{apiKeyScheme: {}}
+			unauthorized := "Missing or invalid credentials"
+			if e.actor {
+				withKey[userEmailScheme] = []string{}
+				unauthorized += fmt.Sprintf(", or missing %s header", actorHeader)
+			}
+			op.Security = []openapi.SecurityRequirement{withKey, {bearerScheme: {}}}
+			op.Description += fmt.Sprintf(" Requires the %s scope.", e.scope)
+			op.Responses["401"] = &openapi.Response{
+				Description: unauthorized,
+				Content:     openapi.JSON(envelope(true, nil)),
+			}
+			op.Responses["403"] = &openapi.Response{
+				Description: fmt.Sprintf("Credentials lack the %s scope", e.scope),
+				Content:     openapi.JSON(envelope(true, nil)),
+			}
+		}
+		op.Responses["500"] = &openapi.Response{
+			Description: "Internal error",
+			Content:     openapi.JSON(envelope(true, nil)),
+		}
+		for _, resp := range e.responses {
+			var dataSchema *openapi.Schema
+			if resp.data != nil {
+				dataSchema = doc.Schema(resp.data)
+			}
+			description := resp.description
+			if resp.status == http.StatusForbidden && e.scope != "" {
+				description += fmt.Sprintf(", or the credentials lack the %s scope", e.scope)
+			}
+			op.Responses[fmt.Sprint(resp.status)] = &openapi.Response{
+				Description: description,
+				Content:     openapi.JSON(envelope(resp.status >= 400, dataSchema)),
+			}
+		}
+		doc.Add(e.method, e.path, op)
+	}
+
+	return doc
+}
+
+// document is built once, on the first request for it.
+var document = sync.OnceValue(apiDocument)
+
+// OpenAPI serves the OpenAPI 3.0 document of the API, e.g. for import as a WatsonX
+// Assistant custom extension. The server is the address the document was requested
+// from.
+func (app *Config) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	scheme := "http"
+	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+
+	doc := *document()
+	doc.Servers = []openapi.Server{{URL: fmt.Sprintf("%s://%s", scheme, r.Host)}}
+
+	err := app.writeJSON(w, http.StatusOK, doc)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// docRoutes serve the documentation and are not described in it.
+var docRoutes = map[string]bool{
+	"/swagger/*":    true,
+	"/openapi.json": true,
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	app := &Config{}
+	doc := apiDocument()
+
+	routed := map[string]bool{}
+	err := chi.Walk(app.routes().(chi.Routes), func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if docRoutes[route] {
+			return nil
+		}
+		routed[method+" "+route] = true
+		if doc.Operation(method, route) == nil {
+			t.Errorf("%s %s is routed but missing from the OpenAPI document", method, route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walking routes: %v", err)
+	}
+
+	for path, item := range doc.Paths {
+		for method := range item {
+			if !routed[strings.ToUpper(method)+" "+path] {
+				t.Errorf("%s %s is documented but not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	doc := apiDocument()
+	pathParams := regexp.MustCompile(`\{([^}]+)\}`)
+
+	ids := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			if op.OperationID == "" || ids[op.OperationID] {
+				t.Errorf("%s %s: operation ID %q is empty or not unique", method, path, op.OperationID)
+			}
+			ids[op.OperationID] = true
+
+			if op.Responses["200"] == nil && op.Responses["201"] == nil {
+				t.Errorf("%s %s: no success response", method, path)
+			}
+			for _, match := range pathParams.FindAllStringSubmatch(path, -1) {
+				found := false
+				for _, p := range op.Parameters {
+					found = found || (p.In == "path" && p.Name == match[1])
+				}
+				if !found {
+					t.Errorf("%s %s: path parameter %s is not described", method, path, match[1])
+				}
+			}
+			for _, req := range op.Security {
+				for scheme := range req {
+					if doc.Components.SecuritySchemes[scheme] == nil {
+						t.Errorf("%s %s: unknown security scheme %s", method, path, scheme)
+					}
+				}
+			}
+		}
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("encoding document: %v", err)
+	}
+	refs := regexp.MustCompile(`"\$ref":"#/components/schemas/([^"]+)"`)
+	for _, match := range refs.FindAllStringSubmatch(string(out), -1) {
+		if doc.Components.Schemas[match[1]] == nil {
+			t.Errorf("reference to undefined schema %s", match[1])
+		}
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	app := &Config{}
+
+	req := httptest.NewRequest(http.MethodGet, "http://scheduler.example.com/openapi.json", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths map[string]any `json:"paths"`
+	}
+	err := json.Unmarshal(rr.Body.Bytes(), &doc)
+	if err != nil {
+		t.Fatalf("decoding document: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.0.") {
+		t.Errorf("expected OpenAPI 3.0, got %q", doc.OpenAPI)
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "https://scheduler.example.com" {
+		t.Errorf("expected server https://scheduler.example.com, got %+v", doc.Servers)
+	}
+	if doc.Paths["/meetings"] == nil {
+		t.Error("expected /meetings to be documented")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"calendar-extension/data"
+	"calendar-extension/timeparse"
+)
+
+// ParseWindowRequest is a phrase describing when to meet and whose time zone to read
+// it in.
+type ParseWindowRequest struct {
+	Text string `json:"text" example:"next Tuesday afternoon"`
+	// TZ is the IANA time zone to read the phrase in. Without it the time zone in the
+	// preferences of Email is used, or UTC.
+	TZ    string `json:"tz" example:"Europe/Warsaw"`
+	Email string `json:"email" example:"anna@example.com"`
+}
+
+// ParseWindow turns a phrase into concrete time ranges
+// @Summary Parse a time window
+// @Description Converts an English phrase such as "next Tuesday afternoon", "sometime this week after 3pm" or "an hour at the end of the month" into the ranges it refers to, in the given time zone or in the time zone of the user with email. Relative days, weekdays, dates, parts of the day, times and lengths of meetings are understood.
+// @Description The response echoes the interpretation in words so it can be confirmed with the user, with a confidence that drops when words were not understood or had to be guessed. ranges can be passed on to the availability endpoints, which also accept the phrase itself as when.
+// @Tags Calendar
+// @Accept  json
+// @Produce  json
+// @Param phrase body ParseWindowRequest true "Phrase and time zone"
+// @Success 200 {object} jsonResponse{data=timeparse.Window} "Interpreted window"
+// @Failure 400 {object} jsonResponse "No time recognized, the time has passed or invalid time zone"
+// @Failure 404 {object} jsonResponse "User not found"
+// @Failure 500 {object} jsonResponse "Error reading preferences"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /parse-window [post]
+func (app *Config) ParseWindow(w http.ResponseWriter, r *http.Request) {
+	var req ParseWindowRequest
+	err := app.readJSON(w, r, &req)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+	if req.Text == "" {
+		app.errorJSON(w, errors.New("text is required"), http.StatusBadRequest)
+		return
+	}
+
+	tz := req.TZ
+	if tz == "" && req.Email != "" {
+		prefs, err := app.Models.GetPreferences(req.Email)
+		switch {
+		case errors.Is(err, data.ErrUserNotFound):
+			app.errorJSON(w, fmt.Errorf("user %s not found", req.Email), http.StatusNotFound)
+			return
+		case err != nil:
+			app.errorJSON(w, fmt.Errorf("failed to get preferences: %w", err), http.StatusInternalServerError)
+			return
+		}
+		tz = prefs.TimeZone
+	}
+
+	loc := time.UTC
+	if tz != "" {
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			app.errorJSON(w, fmt.Errorf("invalid tz %q: must be an IANA time zone name", tz), http.StatusBadRequest)
+			return
+		}
+	}
+
+	window, err := timeparse.Parse(req.Text, time.Now(), loc)
+	if errors.Is(err, timeparse.ErrNoTime) || errors.Is(err, timeparse.ErrInPast) {
+		app.errorJSON(w, fmt.Errorf("cannot interpret %q: %w", req.Text, err), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("failed to parse window: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Interpreted window",
+		Data:    window,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
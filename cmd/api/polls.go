@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"calendar-extension/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// VoteRequest is a participant's answers to a poll, one per slot in order.
+type VoteRequest struct {
+	Answers []string `json:"answers" enums:"yes,if_needed,no" example:"yes,if_needed,no"`
+}
+
+// ClosePollRequest picks the slot a poll is closed with. Without a slot the one most
+// participants can attend is booked.
+type ClosePollRequest struct {
+	Slot *int `json:"slot" example:"1"`
+}
+
+// ClosedPoll is a closed poll with the meeting booked for it.
+type ClosedPoll struct {
+	Poll    *data.Poll    `json:"poll"`
+	Meeting *data.Meeting `json:"meeting"`
+}
+
+// CreatePoll proposes candidate times to participants
+// @Summary Create a scheduling poll
+// @Description Asks participants, who may be outside the organization, which of the candidate slots suit them. Each participant gets a token for their vote link, POST /polls/{id}/votes/{token}, returned only in this response.
+// @Description With only_free the slots in which the organizer is busy or outside their working hours are dropped first. The organizer needs write access, as closing the poll books the meeting on their calendar, and has to be the user in the X-User-Email header unless the call has the admin scope.
+// @Tags Poll
+// @Accept  json
+// @Produce  json
+// @Param X-User-Email header string true "Email of the organizer"
+// @Param poll body data.PollRequest true "Poll details"
+// @Success 201 {object} jsonResponse{data=data.Poll} "Poll created"
+// @Failure 400 {object} jsonResponse "Invalid poll"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse{data=string} "Organizer is not the acting user, or has to grant write access"
+// @Failure 404 {object} jsonResponse "Organizer not found"
+// @Failure 500 {object} jsonResponse "Error creating poll"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /polls [post]
+func (app *Config) CreatePoll(w http.ResponseWriter, r *http.Request) {
+	var req data.PollRequest
+	err := app.readJSON(w, r, &req)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	err = req.Validate()
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if !app.requireActor(w, r, req.Organizer) {
+		return
+	}
+
+	poll, err := app.Models.CreatePoll(r.Context(), req)
+	switch {
+	case errors.Is(err, data.ErrInvalidPoll):
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	case errors.Is(err, data.ErrWriteAccessRequired), errors.Is(err, data.ErrInvalidToken):
+		app.writeAccessRequired(w, req.Organizer)
+		return
+	case errors.Is(err, data.ErrTokenNotFound):
+		app.errorJSON(w, fmt.Errorf("organizer %s has not authorized the app", req.Organizer), http.StatusNotFound)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to create poll: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Poll created",
+		Data:    poll,
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// GetPoll shows a poll and its tallies
+// @Summary Show a scheduling poll
+// @Description Returns the poll's slots, each listing the participants who answered yes, if_needed or no, and which participants have voted. Vote tokens are not included.
+// @Tags Poll
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Poll ID"
+// @Success 200 {object} jsonResponse{data=data.Poll} "Poll"
+// @Failure 404 {object} jsonResponse "Poll not found"
+// @Failure 500 {object} jsonResponse "Error reading poll"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /polls/{id} [get]
+func (app *Config) GetPoll(w http.ResponseWriter, r *http.Request) {
+	poll, err := app.Models.GetPoll(chi.URLParam(r, "id"))
+	if errors.Is(err, data.ErrPollNotFound) {
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("failed to get poll: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Poll",
+		Data:    poll,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// Vote records a participant's answers to a poll
+// @Summary Vote on a scheduling poll
+// @Description Records the answers of the participant the token was issued to, one of yes, if_needed or no per slot in order. Voting again replaces the earlier answers until the poll is closed.
+// @Tags Poll
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Poll ID"
+// @Param token path string true "Participant's vote token"
+// @Param vote body VoteRequest true "Answers"
+// @Success 200 {object} jsonResponse "Vote recorded"
+// @Failure 400 {object} jsonResponse "Invalid answers"
+// @Failure 403 {object} jsonResponse "Vote link is not valid"
+// @Failure 404 {object} jsonResponse "Poll not found"
+// @Failure 409 {object} jsonResponse "Poll is closed"
+// @Failure 500 {object} jsonResponse "Error recording vote"
+// @Router /polls/{id}/votes/{token} [post]
+func (app *Config) Vote(w http.ResponseWriter, r *http.Request) {
+	var req VoteRequest
+	err := app.readJSON(w, r, &req)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	err = app.Models.Vote(chi.URLParam(r, "id"), chi.URLParam(r, "token"), req.Answers)
+	switch {
+	case errors.Is(err, data.ErrPollNotFound):
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	case errors.Is(err, data.ErrVoteLinkInvalid):
+		app.errorJSON(w, err, http.StatusForbidden)
+		return
+	case errors.Is(err, data.ErrInvalidVote):
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	case errors.Is(err, data.ErrPollClosed):
+		app.errorJSON(w, err, http.StatusConflict)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to record vote: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Vote recorded",
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// ClosePoll closes a poll and books the winning slot
+// @Summary Close a scheduling poll
+// @Description Closes the poll and books a meeting with every participant on the organizer's calendar, with a Google Meet link and invitations. The given slot is booked, or else the one most participants can attend, preferring yes over if_needed answers and then the earlier slot.
+// @Description Only the organizer, identified by the X-User-Email header, may close a poll.
+// @Tags Poll
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Poll ID"
+// @Param X-User-Email header string true "Email of the organizer"
+// @Param close body ClosePollRequest false "Slot to book"
+// @Success 201 {object} jsonResponse{data=ClosedPoll} "Meeting created"
+// @Failure 400 {object} jsonResponse "Invalid slot"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse "Not the organizer of the poll"
+// @Failure 404 {object} jsonResponse "Poll not found"
+// @Failure 409 {object} jsonResponse "Poll is already closed"
+// @Failure 500 {object} jsonResponse "Error closing poll"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /polls/{id}/close [post]
+func (app *Config) ClosePoll(w http.ResponseWriter, r *http.Request) {
+	actor, ok := app.actor(w, r)
+	if !ok {
+		return
+	}
+
+	var req ClosePollRequest
+	if r.ContentLength != 0 {
+		err := app.readJSON(w, r, &req)
+		if err != nil {
+			app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	poll, meeting, err := app.Models.ClosePoll(r.Context(), chi.URLParam(r, "id"), actor, req.Slot)
+	switch {
+	case errors.Is(err, data.ErrPollNotFound):
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	case errors.Is(err, data.ErrNotAllowed):
+		app.errorJSON(w, errors.New("only the organizer may close a poll"), http.StatusForbidden)
+		return
+	case errors.Is(err, data.ErrInvalidPoll):
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	case errors.Is(err, data.ErrPollClosed):
+		app.errorJSON(w, err, http.StatusConflict)
+		return
+	case errors.Is(err, data.ErrTokenNotFound):
+		app.errorJSON(w, fmt.Errorf("organizer %s has not authorized the app", actor), http.StatusNotFound)
+		return
+	case err != nil:
+		app.meetingError(w, actor, err)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Poll closed and meeting created",
+		Data:    ClosedPoll{Poll: poll, Meeting: meeting},
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"calendar-extension/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetPreferences returns a user's scheduling preferences
+// @Summary Get user preferences
+// @Description Returns the user's time zone, working hours per weekday, minimum meeting notice and maximum meetings per day. Users who have not set preferences work nine to five on weekdays in the time zone of their Google Calendar, or in UTC if it is not known.
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Param email path string true "User email"
+// @Success 200 {object} jsonResponse{data=data.Preferences} "User preferences"
+// @Failure 404 {object} jsonResponse "User not found"
+// @Failure 500 {object} jsonResponse "Error getting preferences"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /users/{email}/preferences [get]
+func (app *Config) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	email := chi.URLParam(r, "email")
+
+	prefs, err := app.Models.GetPreferences(email)
+	switch {
+	case errors.Is(err, data.ErrUserNotFound):
+		app.errorJSON(w, fmt.Errorf("user %s not found", email), http.StatusNotFound)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to get preferences: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "User preferences",
+		Data:    prefs,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// UpdatePreferences replaces a user's scheduling preferences
+// @Summary Set user preferences
+// @Description Replaces the user's scheduling preferences. working_hours maps weekday names (monday to sunday) to lists of ranges such as {"start": "09:00", "end": "13:00"}, so split shifts and weekend hours can be expressed; days that are left out are days off. Times are wall-clock times in timezone, which has to be an IANA time zone name. A max_meetings_per_day of 0 means no limit. Users set their own preferences; calls with the admin scope may set anyone's.
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Param email path string true "User email"
+// @Param X-User-Email header string true "Email of the user, unless the call has the admin scope"
+// @Param preferences body data.Preferences true "New preferences; email is taken from the path"
+// @Success 200 {object} jsonResponse{data=data.Preferences} "Preferences saved"
+// @Failure 400 {object} jsonResponse "Invalid preferences"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse "Not the acting user"
+// @Failure 404 {object} jsonResponse "User not found"
+// @Failure 500 {object} jsonResponse "Error saving preferences"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /users/{email}/preferences [put]
+func (app *Config) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	email := chi.URLParam(r, "email")
+	if !app.requireActor(w, r, email) {
+		return
+	}
+
+	var prefs data.Preferences
+	err := app.readJSON(w, r, &prefs)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+	prefs.Email = email
+	if prefs.WorkingHours == nil {
+		prefs.WorkingHours = data.WorkingHours{}
+	}
+
+	err = app.Models.SavePreferences(prefs)
+	switch {
+	case errors.Is(err, data.ErrInvalidPreferences):
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	case errors.Is(err, data.ErrUserNotFound):
+		app.errorJSON(w, fmt.Errorf("user %s not found", email), http.StatusNotFound)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to save preferences: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Preferences saved",
+		Data:    prefs,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateWindow counts the requests of one client in the current window.
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// rateLimiter allows each client, identified by IP address, at most limit requests
+// per window.
+type rateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	clients map[string]*rateWindow
+	pruned  time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, clients: map[string]*rateWindow{}}
+}
+
+// allow records a request from key at now and reports whether it is within the
+// limit, and if not, how long until the client may retry.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Forget clients whose windows have ended so the map does not grow without bound
+	if now.Sub(l.pruned) > l.window {
+		for client, w := range l.clients {
+			if now.Sub(w.start) >= l.window {
+				delete(l.clients, client)
+			}
+		}
+		l.pruned = now
+	}
+
+	w, ok := l.clients[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &rateWindow{start: now}
+		l.clients[key] = w
+	}
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// rateLimit returns middleware that rejects requests over the limit of l with 429 Too
+// Many Requests.
+func (app *Config) rateLimit(l *rateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				client = r.RemoteAddr
+			}
+
+			ok, retry := l.allow(client, time.Now())
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())+1))
+				app.errorJSON(w, fmt.Errorf("too many requests, retry in %s", retry.Round(time.Second)), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"calendar-extension/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// requireAdmin checks that the X-User-Email header names an admin and that the call
+// carries the admin scope, sending the error response and returning false otherwise.
+// The scope matters for API keys, which could otherwise name any admin in the header.
+func (app *Config) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	actor, ok := app.actor(w, r)
+	if !ok {
+		return false
+	}
+	if !app.Admins[actor] {
+		app.errorJSON(w, fmt.Errorf("%s is not an admin", actor), http.StatusForbidden)
+		return false
+	}
+	if !data.HasScope(credentialScopes(r), data.ScopeAdmin) {
+		app.errorJSON(w, fmt.Errorf("credentials lack the %s scope", data.ScopeAdmin), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// ListResources lists the bookable rooms
+// @Summary List rooms
+// @Description Lists the registered rooms, smallest first, optionally only those with at least capacity seats, in building and with all of features.
+// @Tags Resource
+// @Accept  json
+// @Produce  json
+// @Param capacity query int false "Least number of seats"
+// @Param building query string false "Building the room is in"
+// @Param features query string false "Comma separated features the room must have, e.g. video"
+// @Success 200 {object} jsonResponse{data=[]data.Resource} "Rooms"
+// @Failure 400 {object} jsonResponse "Invalid query parameters"
+// @Failure 500 {object} jsonResponse "Error listing rooms"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /resources [get]
+func (app *Config) ListResources(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseRoomRequirement(r, "")
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	resources, err := app.Models.ListResources(filter)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("failed to list resources: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Resources",
+		Data:    resources,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// CreateResource registers a room
+// @Summary Register a room
+// @Description Registers a Google resource calendar as a bookable room. Only admins, identified by the X-User-Email header, may manage rooms.
+// @Tags Resource
+// @Accept  json
+// @Produce  json
+// @Param X-User-Email header string true "Email of the admin"
+// @Param resource body data.Resource true "Room details"
+// @Success 201 {object} jsonResponse{data=data.Resource} "Room registered"
+// @Failure 400 {object} jsonResponse "Invalid room"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse "Not an admin"
+// @Failure 409 {object} jsonResponse "Room already registered"
+// @Failure 500 {object} jsonResponse "Error registering room"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /resources [post]
+func (app *Config) CreateResource(w http.ResponseWriter, r *http.Request) {
+	if !app.requireAdmin(w, r) {
+		return
+	}
+
+	var resource data.Resource
+	err := app.readJSON(w, r, &resource)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+	if resource.Features == nil {
+		resource.Features = []string{}
+	}
+
+	err = resource.Validate()
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = app.Models.CreateResource(resource)
+	switch {
+	case errors.Is(err, data.ErrResourceExists):
+		app.errorJSON(w, err, http.StatusConflict)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to create resource: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Resource created",
+		Data:    resource,
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// UpdateResource changes a room
+// @Summary Update a room
+// @Description Replaces the name, capacity, building and features of a room. Only admins, identified by the X-User-Email header, may manage rooms.
+// @Tags Resource
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Calendar ID of the room"
+// @Param X-User-Email header string true "Email of the admin"
+// @Param resource body data.Resource true "Room details; calendar_id is taken from the path"
+// @Success 200 {object} jsonResponse{data=data.Resource} "Room updated"
+// @Failure 400 {object} jsonResponse "Invalid room"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse "Not an admin"
+// @Failure 404 {object} jsonResponse "Room not found"
+// @Failure 500 {object} jsonResponse "Error updating room"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /resources/{id} [put]
+func (app *Config) UpdateResource(w http.ResponseWriter, r *http.Request) {
+	if !app.requireAdmin(w, r) {
+		return
+	}
+
+	var resource data.Resource
+	err := app.readJSON(w, r, &resource)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+	resource.CalendarID = chi.URLParam(r, "id")
+	if resource.Features == nil {
+		resource.Features = []string{}
+	}
+
+	err = resource.Validate()
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = app.Models.UpdateResource(resource)
+	switch {
+	case errors.Is(err, data.ErrResourceNotFound):
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to update resource: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Resource updated",
+		Data:    resource,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// DeleteResource removes a room
+// @Summary Remove a room
+// @Description Removes a room so it is no longer booked. Meetings already booked in it are kept. Only admins, identified by the X-User-Email header, may manage rooms.
+// @Tags Resource
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Calendar ID of the room"
+// @Param X-User-Email header string true "Email of the admin"
+// @Success 200 {object} jsonResponse "Room removed"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse "Not an admin"
+// @Failure 404 {object} jsonResponse "Room not found"
+// @Failure 500 {object} jsonResponse "Error removing room"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /resources/{id} [delete]
+func (app *Config) DeleteResource(w http.ResponseWriter, r *http.Request) {
+	if !app.requireAdmin(w, r) {
+		return
+	}
+
+	calendarID := chi.URLParam(r, "id")
+	err := app.Models.DeleteResource(calendarID)
+	switch {
+	case errors.Is(err, data.ErrResourceNotFound):
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to delete resource: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Resource deleted",
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
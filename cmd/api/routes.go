@@ -8,24 +8,18 @@ import (
 	"github.com/go-chi/cors"
 	httpSwagger "github.com/swaggo/http-swagger"
 
+	"calendar-extension/data"
 	_ "calendar-extension/docs"
 )
 
 // routes sets up the API endpoints for the application
-// @Summary Set up API routes for the application
-// @Description Defines all the routes and the associated handlers for the application.
-// @Tags Routes
-// @Accept  json
-// @Produce  json
-// @Success 200 {string} string "Routes set successfully"
-// @Router /routes [get]
 func (app *Config) routes() http.Handler {
 	mux := chi.NewRouter()
 
 	mux.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"POST", "PUT", "GET", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Content-Type", "Authorization", "X-CSRF-Token"},
+		AllowedMethods:   []string{"POST", "PUT", "PATCH", "GET", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Content-Type", "Authorization", "X-CSRF-Token", "X-User-Email", "X-API-Key"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
@@ -33,14 +27,60 @@ func (app *Config) routes() http.Handler {
 
 	mux.Use(middleware.Heartbeat("/ping"))
 
+	// Signing in, the documentation and the links handed to people outside the service
+	// are public
 	mux.Post("/add-user", app.AddUser)
 	mux.Get("/oauth2callback", app.OAuthCallback)
-	mux.Get("/check-availability", app.CheckAvailability)
-	mux.Post("/add-user-to-group", app.AddUserToGroup)
-	mux.Get("/list-users", app.ListUsers)
-	mux.Get("/list-groups", app.ListGroups)
-
+	mux.With(app.rateLimit(newRateLimiter(bookingViewLimit, bookingViewWindow))).Get("/book/{slug}", app.GetBookingPage)
+	mux.With(app.rateLimit(newRateLimiter(bookingLimit, bookingWindow))).Post("/book/{slug}", app.Book)
+	mux.Post("/polls/{id}/votes/{token}", app.Vote)
 	mux.Get("/swagger/*", httpSwagger.WrapHandler)
+	mux.Get("/openapi.json", app.OpenAPI)
+
+	mux.Group(func(mux chi.Router) {
+		mux.Use(app.requireScope(data.ScopeReadAvailability))
+
+		mux.Get("/users/{email}/availability", app.CheckAvailability)
+		mux.Get("/users/{email}/preferences", app.GetPreferences)
+		mux.Get("/list-users", app.ListUsers)
+		mux.Get("/list-groups", app.ListGroups)
+		mux.Get("/groups/{name}/availability", app.GroupAvailability)
+		mux.Post("/suggestions", app.SuggestTimes)
+		mux.Post("/parse-window", app.ParseWindow)
+		mux.Get("/resources", app.ListResources)
+		mux.Get("/polls/{id}", app.GetPoll)
+	})
+
+	mux.Group(func(mux chi.Router) {
+		mux.Use(app.requireScope(data.ScopeBookMeetings))
+
+		mux.Put("/users/{email}/preferences", app.UpdatePreferences)
+		mux.Put("/groups/{name}/distribution", app.SetGroupDistribution)
+		mux.Post("/meetings", app.CreateMeeting)
+		mux.Patch("/meetings/{id}", app.UpdateMeeting)
+		mux.Delete("/meetings/{id}", app.CancelMeeting)
+		mux.Post("/holds", app.CreateHold)
+		mux.Post("/holds/{id}/confirm", app.ConfirmHold)
+		mux.Post("/booking-pages", app.CreateBookingPage)
+		mux.Delete("/booking-pages/{slug}", app.DeleteBookingPage)
+		mux.Post("/polls", app.CreatePoll)
+		mux.Post("/polls/{id}/close", app.ClosePoll)
+		mux.Post("/sessions", app.StartSession)
+		mux.Get("/sessions/{id}", app.GetSession)
+		mux.Post("/sessions/{id}/turns", app.ApplyTurn)
+	})
+
+	mux.Group(func(mux chi.Router) {
+		mux.Use(app.requireScope(data.ScopeAdmin))
+
+		mux.Post("/add-user-to-group", app.AddUserToGroup)
+		mux.Post("/resources", app.CreateResource)
+		mux.Put("/resources/{id}", app.UpdateResource)
+		mux.Delete("/resources/{id}", app.DeleteResource)
+		mux.Post("/api-keys", app.CreateAPIKey)
+		mux.Get("/api-keys", app.ListAPIKeys)
+		mux.Delete("/api-keys/{id}", app.DeleteAPIKey)
+	})
 
 	return mux
 }
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"calendar-extension/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// sessionTTL is how long a session is kept after its last turn.
+	sessionTTL = 30 * time.Minute
+	// sessionReapInterval is how often expired sessions are removed.
+	sessionReapInterval = 5 * time.Minute
+)
+
+// TurnResult is a session after a turn, with the meeting booked by pick.
+type TurnResult struct {
+	Session *data.Session `json:"session"`
+	Meeting *data.Meeting `json:"meeting,omitempty"`
+}
+
+// sessionError sends the response matching an error from reading or refining a session.
+func (app *Config) sessionError(w http.ResponseWriter, actor string, err error) {
+	switch {
+	case errors.Is(err, data.ErrSessionNotFound), errors.Is(err, data.ErrGroupNotFound):
+		app.errorJSON(w, err, http.StatusNotFound)
+	case errors.Is(err, data.ErrNotAllowed):
+		app.errorJSON(w, errors.New("only the organizer may use a session"), http.StatusForbidden)
+	case errors.Is(err, data.ErrSessionExpired), errors.Is(err, data.ErrSessionBooked):
+		app.errorJSON(w, err, http.StatusConflict)
+	case errors.Is(err, data.ErrInvalidTurn):
+		app.errorJSON(w, err, http.StatusBadRequest)
+	case errors.Is(err, data.ErrTokenNotFound):
+		app.errorJSON(w, fmt.Errorf("organizer %s has not authorized the app", actor), http.StatusNotFound)
+	default:
+		app.meetingError(w, actor, err)
+	}
+}
+
+// StartSession starts a scheduling session
+// @Summary Start a scheduling session
+// @Description Starts a multi-turn search for a meeting of the user in the X-User-Email header with the attendees and the members of the groups. The window is given by when, a phrase such as "next week", or by from and to (default: the next seven days); a length in when sets duration_minutes if it is not given (default 30).
+// @Description The session keeps its constraints and the candidate times, up to limit (default 5), ranked like /suggestions. It expires 30 minutes after its last turn.
+// @Tags Session
+// @Accept  json
+// @Produce  json
+// @Param X-User-Email header string true "Email of the organizer"
+// @Param session body data.SessionConstraints true "What to search for; organizer is taken from the header"
+// @Success 201 {object} jsonResponse{data=data.Session} "Session started"
+// @Failure 400 {object} jsonResponse "Invalid session"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 404 {object} jsonResponse "Group not found"
+// @Failure 500 {object} jsonResponse "Error starting session"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /sessions [post]
+func (app *Config) StartSession(w http.ResponseWriter, r *http.Request) {
+	actor, ok := app.actor(w, r)
+	if !ok {
+		return
+	}
+
+	var constraints data.SessionConstraints
+	err := app.readJSON(w, r, &constraints)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+	constraints.Organizer = actor
+
+	session, err := app.Models.StartSession(r.Context(), constraints, sessionTTL)
+	switch {
+	case errors.Is(err, data.ErrInvalidSession):
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	case errors.Is(err, data.ErrGroupNotFound):
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to start session: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Session started",
+		Data:    session,
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// GetSession shows a scheduling session
+// @Summary Show a scheduling session
+// @Description Returns the session's constraints and current candidate times. Only the organizer, identified by the X-User-Email header, may read a session.
+// @Tags Session
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Session ID"
+// @Param X-User-Email header string true "Email of the organizer"
+// @Success 200 {object} jsonResponse{data=data.Session} "Session"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse "Not the organizer of the session"
+// @Failure 404 {object} jsonResponse "Session not found"
+// @Failure 409 {object} jsonResponse "Session expired"
+// @Failure 500 {object} jsonResponse "Error reading session"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /sessions/{id} [get]
+func (app *Config) GetSession(w http.ResponseWriter, r *http.Request) {
+	actor, ok := app.actor(w, r)
+	if !ok {
+		return
+	}
+
+	session, err := app.Models.GetSession(chi.URLParam(r, "id"), actor)
+	if err != nil {
+		app.sessionError(w, actor, err)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: "Session",
+		Data:    session,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// ApplyTurn refines a scheduling session
+// @Summary Refine a scheduling session
+// @Description Applies one turn of the conversation and returns the session with new candidate times:
+// @Description exclude_days removes weekdays or YYYY-MM-DD dates in days, change_duration sets duration_minutes, change_window replaces the window with the when phrase, and add_attendee and remove_attendee change the attendee or group.
+// @Description pick books the candidate at position option, counted from 1, with a Google Meet link and returns the meeting with a 201. Each turn keeps the session for another 30 minutes.
+// @Tags Session
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Session ID"
+// @Param X-User-Email header string true "Email of the organizer"
+// @Param turn body data.Turn true "Refinement"
+// @Success 200 {object} jsonResponse{data=TurnResult} "Session refined"
+// @Success 201 {object} jsonResponse{data=TurnResult} "Meeting created"
+// @Failure 400 {object} jsonResponse "Invalid turn"
+// @Failure 401 {object} jsonResponse "Missing X-User-Email header"
+// @Failure 403 {object} jsonResponse{data=string} "Not the organizer, or the organizer has to grant write access"
+// @Failure 404 {object} jsonResponse "Session or group not found"
+// @Failure 409 {object} jsonResponse "Session expired or already booked"
+// @Failure 500 {object} jsonResponse "Error applying turn"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /sessions/{id}/turns [post]
+func (app *Config) ApplyTurn(w http.ResponseWriter, r *http.Request) {
+	actor, ok := app.actor(w, r)
+	if !ok {
+		return
+	}
+
+	var turn data.Turn
+	err := app.readJSON(w, r, &turn)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	session, meeting, err := app.Models.ApplyTurn(r.Context(), chi.URLParam(r, "id"), actor, turn, sessionTTL)
+	if err != nil {
+		app.sessionError(w, actor, err)
+		return
+	}
+
+	status, message := http.StatusOK, "Session refined"
+	if meeting != nil {
+		status, message = http.StatusCreated, "Meeting created"
+	}
+	response := jsonResponse{
+		Error:   false,
+		Message: message,
+		Data:    TurnResult{Session: session, Meeting: meeting},
+	}
+
+	err = app.writeJSON(w, status, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// removeExpiredSessions removes sessions past their TTL every interval. Expired
+// sessions already refuse turns; this keeps the table small.
+func (app *Config) removeExpiredSessions(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		removed, err := app.Models.DeleteExpiredSessions()
+		if err != nil {
+			log.Printf("Error removing expired sessions: %v", err)
+			continue
+		}
+		if removed > 0 {
+			log.Printf("Removed %d expired sessions", removed)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"calendar-extension/data"
+)
+
+const (
+	defaultSuggestionLimit = 5
+	maxSuggestionLimit     = 20
+)
+
+// SuggestionsRequest is the body of a search for meeting times.
+type SuggestionsRequest struct {
+	Attendees []string `json:"attendees" example:"anna@example.com,bob@example.com"`
+	Groups    []string `json:"groups" example:"design"`
+	// Duration is the length of the meeting as a Go duration.
+	Duration string    `json:"duration" example:"30m"`
+	From     time.Time `json:"from" format:"date-time" example:"2024-05-06T00:00:00+02:00"`
+	To       time.Time `json:"to" format:"date-time" example:"2024-05-10T00:00:00+02:00"`
+	TZ       string    `json:"tz" example:"Europe/Warsaw"`
+	Limit    int       `json:"limit" example:"5"`
+	// PreferredHours maps attendee emails to the hours they prefer to meet in.
+	PreferredHours map[string]data.HourRange `json:"preferred_hours"`
+	Weights        *data.SuggestionWeights   `json:"weights"`
+	ConstraintParams
+}
+
+// suggestionRequest validates req and converts it into a data.SuggestionRequest.
+func (req SuggestionsRequest) suggestionRequest() (data.SuggestionRequest, error) {
+	suggestion := data.SuggestionRequest{
+		Attendees:      req.Attendees,
+		Groups:         req.Groups,
+		Limit:          defaultSuggestionLimit,
+		PreferredHours: map[string]data.HourRange{},
+		Weights:        data.DefaultSuggestionWeights,
+		Opts: data.SlotOptions{
+			MinDuration: defaultMinDuration,
+			Location:    time.UTC,
+		},
+	}
+	opts := &suggestion.Opts
+
+	if len(req.Attendees) == 0 && len(req.Groups) == 0 {
+		return suggestion, errors.New("attendees or groups are required")
+	}
+
+	if req.TZ != "" {
+		loc, err := time.LoadLocation(req.TZ)
+		if err != nil {
+			return suggestion, fmt.Errorf("invalid tz %q: must be an IANA time zone name", req.TZ)
+		}
+		opts.Location = loc
+	}
+
+	opts.From = time.Now().In(opts.Location)
+	if !req.From.IsZero() {
+		opts.From = req.From.In(opts.Location)
+	}
+	opts.To = opts.From.Add(defaultAvailabilityWindow)
+	if !req.To.IsZero() {
+		opts.To = req.To.In(opts.Location)
+	}
+	err := checkWindow(*opts)
+	if err != nil {
+		return suggestion, err
+	}
+
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			return suggestion, fmt.Errorf("invalid duration %q: must be a positive duration such as 30m or 1h", req.Duration)
+		}
+		opts.MinDuration = d
+	}
+
+	opts.Overrides, err = req.ConstraintParams.overrides()
+	if err != nil {
+		return suggestion, err
+	}
+
+	if req.Limit != 0 {
+		if req.Limit < 0 || req.Limit > maxSuggestionLimit {
+			return suggestion, fmt.Errorf("limit must be between 1 and %d", maxSuggestionLimit)
+		}
+		suggestion.Limit = req.Limit
+	}
+
+	for email, hours := range req.PreferredHours {
+		if hours.End <= hours.Start {
+			return suggestion, fmt.Errorf("preferred hours of %s must end after they start", email)
+		}
+		suggestion.PreferredHours[email] = hours
+	}
+
+	if req.Weights != nil {
+		w := *req.Weights
+		if w.PreferredHours < 0 || w.Soon < 0 || w.BackToBack < 0 || w.Lunch < 0 || w.Fragmentation < 0 {
+			return suggestion, errors.New("weights must not be negative")
+		}
+		suggestion.Weights = w
+	}
+
+	return suggestion, nil
+}
+
+// SuggestTimes suggests meeting times
+// @Summary Suggest meeting times
+// @Description Returns the best times, up to limit (default 5, at most 20), in which all attendees and the members of the listed groups are free for the whole duration (default 30m) between from and to (default: the next seven days).
+// @Description Each suggestion is scored between 0 and 1 from these factors: preferred_hours (attendees' preferred hours), soon (earlier is better), back_to_back (attendees get a break before and after), lunch (12:00-13:00 in tz stays free) and fragmentation (no unusably short gaps are left). weights sets how much each factor counts; all count equally by default and a zero weight switches a factor off.
+// @Description Attendees' buffers, minimum notice, meeting caps and breaks from their preferences apply; the constraint fields of the body override them for this search.
+// @Description Every suggestion carries an explanation that can be read back to the user. Suggestions never overlap each other.
+// @Tags Meeting
+// @Accept  json
+// @Produce  json
+// @Param search body SuggestionsRequest true "Attendees, duration and window"
+// @Success 200 {object} jsonResponse{data=data.Suggestions} "Suggested times"
+// @Failure 400 {object} jsonResponse "Invalid search"
+// @Failure 404 {object} jsonResponse "Group not found"
+// @Failure 500 {object} jsonResponse "Error suggesting times"
+// @Security ApiKey
+// @Security BearerAuth
+// @Router /suggestions [post]
+func (app *Config) SuggestTimes(w http.ResponseWriter, r *http.Request) {
+	var req SuggestionsRequest
+	err := app.readJSON(w, r, &req)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("invalid request body: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	search, err := req.suggestionRequest()
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	suggestions, err := app.Models.SuggestTimes(r.Context(), search)
+	switch {
+	case errors.Is(err, data.ErrGroupNotFound):
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	case err != nil:
+		app.errorJSON(w, fmt.Errorf("failed to suggest times: %w", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := jsonResponse{
+		Error:   false,
+		Message: fmt.Sprintf("%d suggested times", len(suggestions.Suggestions)),
+		Data:    suggestions,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,205 @@
+package data
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned for keys that were never issued or have been revoked.
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrAPIKeyExpired is returned for keys past their expiry.
+	ErrAPIKeyExpired = errors.New("api key expired")
+	// ErrInvalidAPIKey is returned for keys that cannot be issued.
+	ErrInvalidAPIKey = errors.New("invalid api key")
+)
+
+// Scopes limit what a caller may do.
+const (
+	// ScopeReadAvailability allows reading users, groups, availability and suggestions.
+	ScopeReadAvailability = "read-availability"
+	// ScopeBookMeetings allows booking, changing and cancelling meetings.
+	ScopeBookMeetings = "book-meetings"
+	// ScopeAdmin allows everything, including managing groups, rooms and API keys.
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every scope a caller can be given.
+var Scopes = []string{ScopeReadAvailability, ScopeBookMeetings, ScopeAdmin}
+
+// apiKeyPrefix starts every key, so leaked keys are easy to recognize.
+const apiKeyPrefix = "cal_"
+
+// APIKey is a credential for a service calling the API, such as the WatsonX extension.
+// Only a hash of the key is stored.
+type APIKey struct {
+	ID     string   `json:"id" example:"5d41402abc4b2a76b9719d911017c592"`
+	Name   string   `json:"name" example:"WatsonX extension"`
+	Scopes []string `json:"scopes" enums:"read-availability,book-meetings,admin" example:"read-availability,book-meetings"`
+	// ExpiresAt is when the key stops working; keys without it do not expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" format:"date-time" example:"2025-01-01T00:00:00Z"`
+	CreatedAt time.Time  `json:"created_at" format:"date-time" example:"2024-05-06T10:00:00Z"`
+	// Key is the secret to send in the X-API-Key header. It is only returned when the
+	// key is created.
+	Key string `json:"key,omitempty" example:"cal_9f86d081884c7d659a2feaa0c55ad015"`
+}
+
+// HasScope reports whether scopes grant scope. The admin scope grants every scope.
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// hashAPIKey returns the stored form of key. Keys are random, so a plain hash is enough
+// to keep them from being read back.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// splitScopes reads scopes stored comma separated.
+func splitScopes(stored string) []string {
+	if stored == "" {
+		return []string{}
+	}
+	return strings.Split(stored, ",")
+}
+
+// CreateAPIKey issues a key with scopes, expiring at expiresAt unless it is nil. The
+// returned key holds the secret, which cannot be read back later.
+func (m *Models) CreateAPIKey(name string, scopes []string, expiresAt *time.Time) (*APIKey, error) {
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidAPIKey)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKey)
+	}
+	for _, scope := range scopes {
+		known := false
+		for _, s := range Scopes {
+			known = known || s == scope
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: unknown scope %q, must be one of %s", ErrInvalidAPIKey, scope, strings.Join(Scopes, ", "))
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIKey)
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	key := &APIKey{
+		ID:        id,
+		Name:      name,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now().UTC(),
+		Key:       apiKeyPrefix + secret,
+	}
+
+	query := `
+		INSERT INTO api_keys (id, name, key_hash, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = m.DB.Exec(query, key.ID, key.Name, hashAPIKey(key.Key), strings.Join(scopes, ","), expiresAt, key.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// AuthenticateAPIKey returns the key matching the secret sent by a caller. It returns
+// ErrAPIKeyNotFound for unknown keys and ErrAPIKeyExpired for expired ones.
+func (m *Models) AuthenticateAPIKey(secret string) (*APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	query := `SELECT id, name, scopes, expires_at, created_at FROM api_keys WHERE key_hash = $1`
+
+	var key APIKey
+	var scopes string
+	var expiresAt sql.NullTime
+	err := m.DB.QueryRow(query, hashAPIKey(secret)).Scan(&key.ID, &key.Name, &scopes, &expiresAt, &key.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	key.Scopes = splitScopes(scopes)
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+		if !expiresAt.Time.After(time.Now()) {
+			return nil, ErrAPIKeyExpired
+		}
+	}
+
+	return &key, nil
+}
+
+// ListAPIKeys returns every issued key, newest first, without the secrets.
+func (m *Models) ListAPIKeys() ([]APIKey, error) {
+	rows, err := m.DB.Query(`SELECT id, name, scopes, expires_at, created_at FROM api_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var key APIKey
+		var scopes string
+		var expiresAt sql.NullTime
+		err := rows.Scan(&key.ID, &key.Name, &scopes, &expiresAt, &key.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		key.Scopes = splitScopes(scopes)
+		if expiresAt.Valid {
+			key.ExpiresAt = &expiresAt.Time
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// DeleteAPIKey revokes the key with id.
+func (m *Models) DeleteAPIKey(id string) error {
+	result, err := m.DB.Exec(`DELETE FROM api_keys WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete api key: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete api key: %w", err)
+	}
+	if rows == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package data
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func expectAPIKey(mock sqlmock.Sqlmock, secret, scopes string, expiresAt any) {
+	mock.ExpectQuery(`SELECT id, name, scopes, expires_at, created_at FROM api_keys WHERE key_hash = \$1`).
+		WithArgs(hashAPIKey(secret)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "scopes", "expires_at", "created_at"}).
+			AddRow("key-1", "WatsonX extension", scopes, expiresAt, time.Now()))
+}
+
+// capture is an argument matcher that remembers the value it was given.
+type capture struct{ value driver.Value }
+
+func (c *capture) Match(v driver.Value) bool {
+	c.value = v
+	return true
+}
+
+func TestCreateAPIKeyStoresHash(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	stored := &capture{}
+	mock.ExpectExec(`INSERT INTO api_keys`).
+		WithArgs(sqlmock.AnyArg(), "WatsonX extension", stored, "read-availability,book-meetings", nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	key, err := models.CreateAPIKey("WatsonX extension", []string{ScopeReadAvailability, ScopeBookMeetings}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(key.Key, apiKeyPrefix) || len(key.Key) != len(apiKeyPrefix)+32 {
+		t.Errorf("unexpected key %q", key.Key)
+	}
+	if stored.value != hashAPIKey(key.Key) {
+		t.Errorf("expected the hash of the key to be stored, got %v", stored.value)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCreateAPIKeyValidates(t *testing.T) {
+	db, _, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name      string
+		keyName   string
+		scopes    []string
+		expiresAt *time.Time
+	}{
+		{"missing name", "", []string{ScopeAdmin}, nil},
+		{"no scopes", "ci", nil, nil},
+		{"unknown scope", "ci", []string{"write-everything"}, nil},
+		{"expired", "ci", []string{ScopeAdmin}, &past},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := models.CreateAPIKey(tt.keyName, tt.scopes, tt.expiresAt)
+			if !errors.Is(err, ErrInvalidAPIKey) {
+				t.Errorf("expected ErrInvalidAPIKey, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	secret := apiKeyPrefix + "9f86d081884c7d659a2feaa0c55ad015"
+
+	expectAPIKey(mock, secret, "read-availability", time.Now().Add(time.Hour))
+	key, err := models.AuthenticateAPIKey(secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !HasScope(key.Scopes, ScopeReadAvailability) || HasScope(key.Scopes, ScopeBookMeetings) {
+		t.Errorf("expected only the read-availability scope, got %v", key.Scopes)
+	}
+
+	expectAPIKey(mock, secret, "admin", time.Now().Add(-time.Minute))
+	if _, err := models.AuthenticateAPIKey(secret); !errors.Is(err, ErrAPIKeyExpired) {
+		t.Errorf("expected ErrAPIKeyExpired, got %v", err)
+	}
+
+	mock.ExpectQuery(`SELECT id, name, scopes, expires_at, created_at FROM api_keys`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "scopes", "expires_at", "created_at"}))
+	if _, err := models.AuthenticateAPIKey(secret); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("expected ErrAPIKeyNotFound, got %v", err)
+	}
+
+	// Keys without the prefix are rejected without a query.
+	if _, err := models.AuthenticateAPIKey("not-a-key"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("expected ErrAPIKeyNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	if !HasScope([]string{ScopeAdmin}, ScopeBookMeetings) {
+		t.Error("expected admin to grant book-meetings")
+	}
+	if HasScope([]string{ScopeReadAvailability}, ScopeBookMeetings) {
+		t.Error("expected read-availability not to grant book-meetings")
+	}
+}
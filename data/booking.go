@@ -0,0 +1,430 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrBookingPageNotFound is returned when no booking page has the slug.
+	ErrBookingPageNotFound = errors.New("booking page not found")
+	// ErrBookingPageExists is returned when creating a booking page with a slug in use.
+	ErrBookingPageExists = errors.New("booking page already exists")
+	// ErrInvalidBookingPage is returned for booking pages that cannot be stored.
+	ErrInvalidBookingPage = errors.New("invalid booking page")
+	// ErrInvalidBooking is returned for bookings missing a guest or required answers.
+	ErrInvalidBooking = errors.New("invalid booking")
+	// ErrTimeUnavailable is returned when booking a time the page does not offer.
+	ErrTimeUnavailable = errors.New("time is not available")
+	// ErrGuestLimitReached is returned when a guest already has the most bookings the
+	// page allows.
+	ErrGuestLimitReached = errors.New("guest has reached the booking limit of the page")
+)
+
+const (
+	// defaultBookingWindowDays is how far ahead a booking page offers times by default.
+	defaultBookingWindowDays = 14
+	// maxBookingWindowDays is how far ahead a booking page may offer times.
+	maxBookingWindowDays = 90
+	// defaultBookingsPerGuest is how many meetings a guest may book through a page by default.
+	defaultBookingsPerGuest = 1
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{2,63}$`)
+
+// BookingQuestion is a question guests answer when booking.
+type BookingQuestion struct {
+	ID       string `json:"id" example:"company"`
+	Label    string `json:"label" example:"Which company are you with?"`
+	Required bool   `json:"required" example:"true"`
+}
+
+// BookingRules limit when and how often guests can book through a page. Zero fields
+// keep the owner's preferences or the defaults.
+type BookingRules struct {
+	// WindowDays is how many days ahead times are offered, 14 by default.
+	WindowDays int `json:"window_days" example:"14"`
+	// MinNoticeMinutes overrides the owner's minimum notice.
+	MinNoticeMinutes int `json:"min_notice_minutes" example:"240"`
+	// BufferMinutes is kept free before and after every meeting.
+	BufferMinutes int `json:"buffer_minutes" example:"10"`
+	// MaxPerDay closes days with this many meetings.
+	MaxPerDay int `json:"max_per_day" example:"3"`
+	// MaxBookingsPerGuest caps the meetings one guest email can book, 1 by default.
+	MaxBookingsPerGuest int `json:"max_bookings_per_guest" example:"1"`
+}
+
+// BookingPage is a public page through which people outside the service book
+// meetings with its owner, or with a group the owner administers.
+type BookingPage struct {
+	Slug        string `json:"slug" example:"anna-intro"`
+	Owner       string `json:"owner" example:"anna@example.com"`
+	Group       string `json:"group,omitempty" example:"sales"`
+	Title       string `json:"title" example:"Intro call"`
+	Description string `json:"description,omitempty" example:"A short call to get to know each other"`
+	// DurationMinutes is the length of the meetings booked through the page.
+	DurationMinutes int               `json:"duration_minutes" example:"30"`
+	Questions       []BookingQuestion `json:"questions"`
+	Rules           BookingRules      `json:"rules"`
+}
+
+// Validate checks that p can be stored and fills in default rules.
+func (p *BookingPage) Validate() error {
+	switch {
+	case !slugPattern.MatchString(p.Slug):
+		return fmt.Errorf("%w: slug must be 3 to 64 lowercase letters, digits or dashes", ErrInvalidBookingPage)
+	case p.Owner == "":
+		return fmt.Errorf("%w: owner is required", ErrInvalidBookingPage)
+	case p.Title == "":
+		return fmt.Errorf("%w: title is required", ErrInvalidBookingPage)
+	case p.DurationMinutes < 5 || p.DurationMinutes > 8*60:
+		return fmt.Errorf("%w: duration_minutes must be between 5 and 480", ErrInvalidBookingPage)
+	case p.Rules.WindowDays < 0 || p.Rules.WindowDays > maxBookingWindowDays:
+		return fmt.Errorf("%w: window_days must be between 1 and %d", ErrInvalidBookingPage, maxBookingWindowDays)
+	case p.Rules.MinNoticeMinutes < 0 || p.Rules.BufferMinutes < 0 || p.Rules.MaxPerDay < 0 || p.Rules.MaxBookingsPerGuest < 0:
+		return fmt.Errorf("%w: rules cannot be negative", ErrInvalidBookingPage)
+	}
+
+	seen := map[string]bool{}
+	for _, question := range p.Questions {
+		if question.ID == "" || question.Label == "" {
+			return fmt.Errorf("%w: questions need an id and a label", ErrInvalidBookingPage)
+		}
+		if seen[question.ID] {
+			return fmt.Errorf("%w: question id %s is used twice", ErrInvalidBookingPage, question.ID)
+		}
+		seen[question.ID] = true
+	}
+
+	if p.Questions == nil {
+		p.Questions = []BookingQuestion{}
+	}
+	if p.Rules.WindowDays == 0 {
+		p.Rules.WindowDays = defaultBookingWindowDays
+	}
+	if p.Rules.MaxBookingsPerGuest == 0 {
+		p.Rules.MaxBookingsPerGuest = defaultBookingsPerGuest
+	}
+	return nil
+}
+
+// duration returns the length of the page's meetings.
+func (p *BookingPage) duration() time.Duration {
+	return time.Duration(p.DurationMinutes) * time.Minute
+}
+
+// overrides returns the constraint overrides set by the page's rules.
+func (p *BookingPage) overrides() ConstraintOverrides {
+	var overrides ConstraintOverrides
+	if p.Rules.MinNoticeMinutes > 0 {
+		notice := time.Duration(p.Rules.MinNoticeMinutes) * time.Minute
+		overrides.MinNotice = &notice
+	}
+	if p.Rules.BufferMinutes > 0 {
+		buffer := time.Duration(p.Rules.BufferMinutes) * time.Minute
+		overrides.BufferBefore, overrides.BufferAfter = &buffer, &buffer
+	}
+	if p.Rules.MaxPerDay > 0 {
+		overrides.MaxPerDay = &p.Rules.MaxPerDay
+	}
+	return overrides
+}
+
+// Booking is a guest's request to book a time offered by a booking page.
+type Booking struct {
+	GuestEmail string
+	GuestName  string
+	Start      time.Time
+	// Answers maps question IDs to the guest's answers.
+	Answers map[string]string
+}
+
+// CreateBookingPage stores page, which has to be valid. Pages for a group can only be
+// created by its admins, or ErrNotAllowed is returned. It returns ErrBookingPageExists
+// if the slug is taken.
+func (m *Models) CreateBookingPage(page BookingPage) error {
+	if page.Group != "" {
+		admin, err := m.IsGroupAdmin(page.Owner, []string{page.Group})
+		if err != nil {
+			return err
+		}
+		if !admin {
+			return ErrNotAllowed
+		}
+	}
+
+	questions, err := json.Marshal(page.Questions)
+	if err != nil {
+		return fmt.Errorf("failed to encode questions: %w", err)
+	}
+	rules, err := json.Marshal(page.Rules)
+	if err != nil {
+		return fmt.Errorf("failed to encode rules: %w", err)
+	}
+
+	query := `
+		INSERT INTO booking_pages (slug, owner, group_name, title, description, duration_minutes, questions, rules)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (slug) DO NOTHING
+	`
+	result, err := m.DB.Exec(query, page.Slug, page.Owner, page.Group, page.Title, page.Description,
+		page.DurationMinutes, string(questions), string(rules))
+	if err != nil {
+		return fmt.Errorf("failed to save booking page: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save booking page: %w", err)
+	}
+	if rows == 0 {
+		return ErrBookingPageExists
+	}
+
+	return nil
+}
+
+// GetBookingPage returns the booking page with slug.
+func (m *Models) GetBookingPage(slug string) (*BookingPage, error) {
+	query := `
+		SELECT owner, group_name, title, description, duration_minutes, questions, rules
+		FROM booking_pages WHERE slug = $1
+	`
+
+	page := &BookingPage{Slug: slug}
+	var questions, rules string
+	err := m.DB.QueryRow(query, slug).Scan(&page.Owner, &page.Group, &page.Title, &page.Description,
+		&page.DurationMinutes, &questions, &rules)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBookingPageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking page: %w", err)
+	}
+
+	err = json.Unmarshal([]byte(questions), &page.Questions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode questions: %w", err)
+	}
+	err = json.Unmarshal([]byte(rules), &page.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode rules: %w", err)
+	}
+
+	return page, nil
+}
+
+// DeleteBookingPage removes the booking page with slug on behalf of actor, who has
+// to own it. Meetings booked through the page are kept.
+func (m *Models) DeleteBookingPage(slug, actor string) error {
+	page, err := m.GetBookingPage(slug)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(page.Owner, actor) {
+		return ErrNotAllowed
+	}
+
+	_, err = m.DB.Exec(`DELETE FROM booking_pages WHERE slug = $1`, slug)
+	if err != nil {
+		return fmt.Errorf("failed to delete booking page: %w", err)
+	}
+
+	return nil
+}
+
+// BookingTimes returns the start times page offers between now and the end of its
+// window, as slots of the page's duration in loc. Times come from the owner's free
+// slots, or the group's for group pages, and start on the quarter hour.
+func (m *Models) BookingTimes(ctx context.Context, page *BookingPage, loc *time.Location) ([]TimeSlot, error) {
+	from := time.Now().In(loc)
+	opts := SlotOptions{
+		From:        from,
+		To:          from.AddDate(0, 0, page.Rules.WindowDays),
+		MinDuration: page.duration(),
+		Location:    loc,
+		Overrides:   page.overrides(),
+	}
+
+	var slots []TimeSlot
+	if page.Group != "" {
+		availability, err := m.GetGroupFreeSlots(ctx, page.Group, opts, Quorum{})
+		if err != nil {
+			return nil, err
+		}
+		slots = availability.Slots
+	} else {
+		var err error
+		slots, err = m.GetFreeSlots(ctx, page.Owner, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Slots of groups that assign a host may overlap, so times are collected once each
+	times := []TimeSlot{}
+	offered := map[int64]bool{}
+	for _, slot := range slots {
+		if slot.Status != SlotFree {
+			continue
+		}
+		start := slot.Start.Truncate(suggestionStep)
+		if start.Before(slot.Start) {
+			start = start.Add(suggestionStep)
+		}
+		for ; !start.Add(page.duration()).After(slot.End); start = start.Add(page.duration()) {
+			if offered[start.Unix()] {
+				continue
+			}
+			offered[start.Unix()] = true
+			times = append(times, NewTimeSlot(start.In(loc), start.Add(page.duration()).In(loc), SlotFree))
+		}
+	}
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Start.Before(times[j].Start)
+	})
+	return times, nil
+}
+
+// Book books booking through the page with slug: the meeting is created on the
+// owner's calendar, with the members of the page's group for group pages, and the
+// guest is invited. The start time has to be one the page offers, or
+// ErrTimeUnavailable is returned, and guests who already booked the most meetings
+// the page allows get ErrGuestLimitReached.
+func (m *Models) Book(ctx context.Context, slug string, booking Booking) (*Meeting, error) {
+	page, err := m.GetBookingPage(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	booking.GuestEmail = strings.ToLower(strings.TrimSpace(booking.GuestEmail))
+	if booking.GuestEmail == "" || !strings.Contains(booking.GuestEmail, "@") {
+		return nil, fmt.Errorf("%w: a guest email is required", ErrInvalidBooking)
+	}
+	for _, question := range page.Questions {
+		if question.Required && strings.TrimSpace(booking.Answers[question.ID]) == "" {
+			return nil, fmt.Errorf("%w: %s is required", ErrInvalidBooking, question.Label)
+		}
+	}
+
+	start, end := booking.Start, booking.Start.Add(page.duration())
+	now := time.Now()
+	if start.Before(now) || start.After(now.AddDate(0, 0, page.Rules.WindowDays)) {
+		return nil, ErrTimeUnavailable
+	}
+
+	// Bookings through the page serialize on its row so that neither a guest's limit
+	// nor a time can be booked twice by concurrent requests
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`SELECT slug FROM booking_pages WHERE slug = $1 FOR UPDATE`, slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock booking page: %w", err)
+	}
+	var booked int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM page_bookings WHERE slug = $1 AND guest_email = $2`, slug, booking.GuestEmail).Scan(&booked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count bookings: %w", err)
+	}
+	if booked >= page.Rules.MaxBookingsPerGuest {
+		return nil, ErrGuestLimitReached
+	}
+
+	// The time has to be among those the page offers right now, on the same grid
+	times, err := m.BookingTimes(ctx, page, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	offered := false
+	for _, t := range times {
+		if t.Start.Equal(start) {
+			offered = true
+			break
+		}
+	}
+	if !offered {
+		return nil, ErrTimeUnavailable
+	}
+
+	req := MeetingRequest{
+		Organizer:   page.Owner,
+		Attendees:   []string{booking.GuestEmail},
+		Title:       page.Title,
+		Description: bookingDescription(page, booking),
+		Start:       start,
+		End:         end,
+	}
+	if booking.GuestName != "" {
+		req.Title = fmt.Sprintf("%s with %s", page.Title, booking.GuestName)
+	}
+	if page.Group != "" {
+		req.Groups = []string{page.Group}
+	}
+
+	answers, err := json.Marshal(booking.Answers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode answers: %w", err)
+	}
+
+	meeting, err := m.CreateMeeting(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO page_bookings (slug, guest_email, guest_name, event_id, answers)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = tx.Exec(query, slug, booking.GuestEmail, booking.GuestName, meeting.EventID, string(answers))
+	if err != nil {
+		return nil, m.cancelBooking(ctx, meeting, fmt.Errorf("failed to save booking: %w", err))
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, m.cancelBooking(ctx, meeting, fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	return meeting, nil
+}
+
+// cancelBooking cancels meeting, booked through a page whose booking could not be
+// recorded, so that no event is left that does not count against the guest's limit.
+// It returns err, with the cancellation's error if that fails too.
+func (m *Models) cancelBooking(ctx context.Context, meeting *Meeting, err error) error {
+	_, cancelErr := m.CancelMeeting(ctx, meeting.EventID, meeting.Organizer)
+	if cancelErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to cancel meeting: %w", cancelErr))
+	}
+	return err
+}
+
+// bookingDescription describes a booked meeting with the guest's answers.
+func bookingDescription(page *BookingPage, booking Booking) string {
+	lines := []string{}
+	if page.Description != "" {
+		lines = append(lines, page.Description, "")
+	}
+	guest := booking.GuestEmail
+	if booking.GuestName != "" {
+		guest = fmt.Sprintf("%s <%s>", booking.GuestName, booking.GuestEmail)
+	}
+	lines = append(lines, "Booked through "+page.Slug+" by "+guest)
+	for _, question := range page.Questions {
+		if answer := booking.Answers[question.ID]; answer != "" {
+			lines = append(lines, question.Label+" "+answer)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
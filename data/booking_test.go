@@ -0,0 +1,216 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func expectBookingPage(mock sqlmock.Sqlmock, slug, owner, questions, rules string) {
+	mock.ExpectQuery(`SELECT owner, group_name, title, description, duration_minutes, questions, rules`).
+		WithArgs(slug).
+		WillReturnRows(sqlmock.NewRows([]string{"owner", "group_name", "title", "description", "duration_minutes", "questions", "rules"}).
+			AddRow(owner, "", "Intro call", "", 30, questions, rules))
+}
+
+func expectPageLock(mock sqlmock.Sqlmock, slug string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT slug FROM booking_pages WHERE slug = \$1 FOR UPDATE`).WithArgs(slug).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestBookingPageValidate(t *testing.T) {
+	page := BookingPage{Slug: "anna-intro", Owner: "anna@example.com", Title: "Intro call", DurationMinutes: 30}
+	if err := page.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Rules.WindowDays != defaultBookingWindowDays || page.Rules.MaxBookingsPerGuest != defaultBookingsPerGuest || page.Questions == nil {
+		t.Errorf("expected defaults to be filled in, got %+v", page)
+	}
+
+	invalid := []BookingPage{
+		{Slug: "Anna Intro", Owner: "anna@example.com", Title: "Intro call", DurationMinutes: 30},
+		{Slug: "anna-intro", Owner: "anna@example.com", Title: "Intro call"},
+		{Slug: "anna-intro", Owner: "anna@example.com", Title: "Intro call", DurationMinutes: 30, Rules: BookingRules{WindowDays: 365}},
+		{Slug: "anna-intro", Owner: "anna@example.com", Title: "Intro call", DurationMinutes: 30,
+			Questions: []BookingQuestion{{ID: "company", Label: "Company"}, {ID: "company", Label: "Team"}}},
+	}
+	for _, page := range invalid {
+		if err := page.Validate(); !errors.Is(err, ErrInvalidBookingPage) {
+			t.Errorf("expected ErrInvalidBookingPage for %+v, got %v", page, err)
+		}
+	}
+}
+
+func TestBook(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	owner := "anna@example.com"
+	questions := `[{"id": "company", "label": "Company?", "required": true}]`
+	rules := `{"window_days": 14, "max_bookings_per_guest": 1}`
+
+	// A weekday morning next week, inside the default working hours
+	day := time.Now().UTC().AddDate(0, 0, 7).Truncate(24 * time.Hour)
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, 1)
+	}
+	start := day.Add(10 * time.Hour)
+
+	// A required answer is missing
+	expectBookingPage(mock, "anna-intro", owner, questions, rules)
+	_, err := models.Book(context.Background(), "anna-intro", Booking{GuestEmail: "partner@example.org", Start: start})
+	if !errors.Is(err, ErrInvalidBooking) {
+		t.Errorf("expected ErrInvalidBooking, got %v", err)
+	}
+
+	// A free time off the page's half-hour grid is not offered
+	expectBookingPage(mock, "anna-intro", owner, questions, rules)
+	expectPageLock(mock, "anna-intro")
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM page_bookings`).
+		WithArgs("anna-intro", "partner@example.org").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	expectToken(mock, owner, "anna-token")
+	expectPreferences(mock, owner)
+	expectHolds(mock)
+	mock.ExpectRollback()
+	_, err = models.Book(context.Background(), "anna-intro", Booking{
+		GuestEmail: "partner@example.org",
+		Start:      start.Add(15 * time.Minute),
+		Answers:    map[string]string{"company": "Example"},
+	})
+	if !errors.Is(err, ErrTimeUnavailable) {
+		t.Errorf("expected ErrTimeUnavailable, got %v", err)
+	}
+
+	// The guest has used up their bookings
+	expectBookingPage(mock, "anna-intro", owner, questions, rules)
+	expectPageLock(mock, "anna-intro")
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM page_bookings`).
+		WithArgs("anna-intro", "partner@example.org").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+	_, err = models.Book(context.Background(), "anna-intro", Booking{
+		GuestEmail: "Partner@Example.org",
+		Start:      start,
+		Answers:    map[string]string{"company": "Example"},
+	})
+	if !errors.Is(err, ErrGuestLimitReached) {
+		t.Errorf("expected ErrGuestLimitReached, got %v", err)
+	}
+
+	// A free time is booked with the guest invited
+	expectBookingPage(mock, "anna-intro", owner, questions, rules)
+	expectPageLock(mock, "anna-intro")
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM page_bookings`).
+		WithArgs("anna-intro", "partner@example.org").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	expectToken(mock, owner, "anna-token")
+	expectPreferences(mock, owner)
+	expectHolds(mock)
+	expectScopes(mock, owner, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, owner, "anna-token")
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO meetings`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_attendees`).WithArgs("fake-event-1", "partner@example.org").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`INSERT INTO page_bookings`).
+		WithArgs("anna-intro", "partner@example.org", "Pat Partner", "fake-event-1", `{"company":"Example"}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	meeting, err := models.Book(context.Background(), "anna-intro", Booking{
+		GuestEmail: "partner@example.org",
+		GuestName:  "Pat Partner",
+		Start:      start,
+		Answers:    map[string]string{"company": "Example"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meeting.Title != "Intro call with Pat Partner" || !strings.Contains(meeting.Description, "Company? Example") {
+		t.Errorf("unexpected meeting: %+v", meeting)
+	}
+	if len(meeting.Attendees) != 1 || meeting.Attendees[0] != "partner@example.org" {
+		t.Errorf("expected the guest to be invited, got %v", meeting.Attendees)
+	}
+
+	// The booked time is no longer offered
+	expectBookingPage(mock, "anna-intro", owner, questions, rules)
+	expectPageLock(mock, "anna-intro")
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM page_bookings`).
+		WithArgs("anna-intro", "carol@example.org").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	expectToken(mock, owner, "anna-token")
+	expectPreferences(mock, owner)
+	expectHolds(mock)
+	mock.ExpectRollback()
+	_, err = models.Book(context.Background(), "anna-intro", Booking{
+		GuestEmail: "carol@example.org",
+		Start:      start,
+		Answers:    map[string]string{"company": "Example"},
+	})
+	if !errors.Is(err, ErrTimeUnavailable) {
+		t.Errorf("expected ErrTimeUnavailable, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestBookCancelsMeetingNotRecorded(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	owner := "anna@example.com"
+	day := time.Now().UTC().AddDate(0, 0, 7).Truncate(24 * time.Hour)
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, 1)
+	}
+	start := day.Add(10 * time.Hour)
+
+	expectBookingPage(mock, "anna-intro", owner, "[]", `{"window_days": 14, "max_bookings_per_guest": 1}`)
+	expectPageLock(mock, "anna-intro")
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM page_bookings`).
+		WithArgs("anna-intro", "partner@example.org").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	expectToken(mock, owner, "anna-token")
+	expectPreferences(mock, owner)
+	expectHolds(mock)
+	expectScopes(mock, owner, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, owner, "anna-token")
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO meetings`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_attendees`).WithArgs("fake-event-1", "partner@example.org").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`INSERT INTO page_bookings`).WillReturnError(errors.New("connection reset"))
+	expectMeeting(mock, "fake-event-1", owner, start, start.Add(30*time.Minute), []string{"partner@example.org"}, nil)
+	expectScopes(mock, owner, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, owner, "anna-token")
+	mock.ExpectExec(`UPDATE meetings SET status`).WithArgs(MeetingCancelled, "fake-event-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	_, err := models.Book(context.Background(), "anna-intro", Booking{GuestEmail: "partner@example.org", Start: start})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if events := fake.Events(owner); len(events) != 0 {
+		t.Errorf("expected the event to be deleted, got %v", events)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
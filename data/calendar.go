@@ -0,0 +1,91 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrInvalidToken is returned by a CalendarProvider when the user's token was
+	// rejected, typically because it expired or access was revoked.
+	ErrInvalidToken = errors.New("calendar token is invalid")
+	// ErrEventNotFound is returned by a CalendarProvider when an event does not exist.
+	ErrEventNotFound = errors.New("event not found")
+)
+
+// BusyPeriod is a period in which a calendar is occupied.
+type BusyPeriod struct {
+	Start  time.Time
+	End    time.Time
+	Status SlotStatus
+}
+
+// Event is a calendar event as created or updated by the service.
+type Event struct {
+	ID          string
+	Title       string
+	Description string
+	Start       time.Time
+	End         time.Time
+	Attendees   []string
+	// Resources are the calendars of the rooms booked for the event.
+	Resources []string
+	// Conference asks the provider to attach a video conference when creating the event.
+	Conference bool
+	HTMLLink   string
+	MeetURL    string
+	// Recurrence holds the RRULE lines of a recurring event, which repeats at the wall
+	// clock time of Start in TimeZone.
+	Recurrence []string
+	TimeZone   string
+}
+
+// CalendarInfo describes a calendar in a user's calendar list.
+type CalendarInfo struct {
+	ID       string `json:"id" example:"anna@example.com"`
+	Summary  string `json:"summary" example:"Anna Nowak"`
+	TimeZone string `json:"time_zone" example:"Europe/Warsaw"`
+	Primary  bool   `json:"primary" example:"true"`
+}
+
+// CalendarProvider is the calendar backend Models reads availability from and writes
+// events to. Calendar IDs are the owners' emails, which identify their primary calendars.
+type CalendarProvider interface {
+	// BusyPeriods returns the busy and tentative periods of calendarID between
+	// opts.From and opts.To, honouring opts.IgnoreAllDay.
+	BusyPeriods(ctx context.Context, token *oauth2.Token, calendarID string, opts SlotOptions) ([]BusyPeriod, error)
+	// FreeBusy returns the busy periods of every calendar in calendarIDs that token can
+	// read between from and to. Calendars it cannot read are returned in failed with
+	// the reason instead.
+	FreeBusy(ctx context.Context, token *oauth2.Token, calendarIDs []string, from, to time.Time) (busy map[string][]BusyPeriod, failed map[string]string, err error)
+	// CreateEvent inserts event into calendarID, notifying its attendees, and returns it
+	// with its ID and links set.
+	CreateEvent(ctx context.Context, token *oauth2.Token, calendarID string, event *Event) (*Event, error)
+	// UpdateEvent changes the non-zero fields of the event with event.ID in calendarID
+	// and notifies its attendees.
+	UpdateEvent(ctx context.Context, token *oauth2.Token, calendarID string, event *Event) (*Event, error)
+	// DeleteEvent removes the event with eventID from calendarID and notifies its attendees.
+	DeleteEvent(ctx context.Context, token *oauth2.Token, calendarID, eventID string) error
+	// ListCalendars returns the calendars in the calendar list of account.
+	ListCalendars(ctx context.Context, token *oauth2.Token, account string) ([]CalendarInfo, error)
+	// TimeZone returns the IANA time zone set in the calendar settings of account.
+	TimeZone(ctx context.Context, token *oauth2.Token, account string) (string, error)
+}
+
+// splitPeriods converts busy periods into the busy and tentative intervals used by
+// the slot algorithm. Free periods are dropped.
+func splitPeriods(periods []BusyPeriod) ([]interval, []interval) {
+	var busy, tentative []interval
+	for _, period := range periods {
+		switch period.Status {
+		case SlotBusy:
+			busy = append(busy, interval{start: period.Start, end: period.End})
+		case SlotTentative:
+			tentative = append(tentative, interval{start: period.Start, end: period.End})
+		}
+	}
+	return busy, tentative
+}
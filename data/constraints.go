@@ -0,0 +1,166 @@
+package data
+
+import (
+	"time"
+)
+
+const (
+	// defaultBreak is the break that ends a run of meetings when a user limits
+	// continuous meeting time without choosing a break length.
+	defaultBreak = 15 * time.Minute
+	// memberLookaround widens group searches so that meetings just outside the window,
+	// and the rest of the weeks it touches, count towards members' constraints.
+	memberLookaround = 7 * 24 * time.Hour
+)
+
+// Constraints limit when new meetings can be booked for a user.
+type Constraints struct {
+	// BufferBefore and BufferAfter are kept free around every meeting.
+	BufferBefore time.Duration
+	BufferAfter  time.Duration
+	// MinNotice is how far ahead of now meetings have to be booked.
+	MinNotice time.Duration
+	// MaxPerDay and MaxPerWeek close days and Monday-to-Sunday weeks that already have
+	// that many meetings. Zero means no cap.
+	MaxPerDay  int
+	MaxPerWeek int
+	// MaxContinuous is the longest run of meetings separated by less than Break that
+	// a new meeting may extend. Zero means no limit.
+	MaxContinuous time.Duration
+	Break         time.Duration
+}
+
+// Constraints returns the booking constraints set in p.
+func (p Preferences) Constraints() Constraints {
+	return Constraints{
+		BufferBefore:  time.Duration(p.BufferBeforeMinutes) * time.Minute,
+		BufferAfter:   time.Duration(p.BufferAfterMinutes) * time.Minute,
+		MinNotice:     time.Duration(p.MinNoticeMinutes) * time.Minute,
+		MaxPerDay:     p.MaxMeetingsPerDay,
+		MaxPerWeek:    p.MaxMeetingsPerWeek,
+		MaxContinuous: time.Duration(p.MaxContinuousMinutes) * time.Minute,
+		Break:         time.Duration(p.BreakMinutes) * time.Minute,
+	}
+}
+
+// ConstraintOverrides replace constraints from user preferences for a single search.
+// Nil fields keep the users' own values.
+type ConstraintOverrides struct {
+	BufferBefore  *time.Duration
+	BufferAfter   *time.Duration
+	MinNotice     *time.Duration
+	MaxPerDay     *int
+	MaxPerWeek    *int
+	MaxContinuous *time.Duration
+	Break         *time.Duration
+}
+
+// apply returns c with the overridden fields replaced.
+func (o ConstraintOverrides) apply(c Constraints) Constraints {
+	if o.BufferBefore != nil {
+		c.BufferBefore = *o.BufferBefore
+	}
+	if o.BufferAfter != nil {
+		c.BufferAfter = *o.BufferAfter
+	}
+	if o.MinNotice != nil {
+		c.MinNotice = *o.MinNotice
+	}
+	if o.MaxPerDay != nil {
+		c.MaxPerDay = *o.MaxPerDay
+	}
+	if o.MaxPerWeek != nil {
+		c.MaxPerWeek = *o.MaxPerWeek
+	}
+	if o.MaxContinuous != nil {
+		c.MaxContinuous = *o.MaxContinuous
+	}
+	if o.Break != nil {
+		c.Break = *o.Break
+	}
+	if c.MaxContinuous > 0 && c.Break == 0 {
+		c.Break = defaultBreak
+	}
+	return c
+}
+
+// lookaround is how far before and after a search window meetings have to be read for
+// c to be applied inside it.
+func (c Constraints) lookaround() time.Duration {
+	switch {
+	case c.MaxPerWeek > 0:
+		return 7 * 24 * time.Hour
+	case c.MaxPerDay > 0:
+		return 24 * time.Hour
+	}
+	return max(c.BufferBefore, c.BufferAfter, c.MaxContinuous+c.Break)
+}
+
+// blocked returns the time c keeps free of new meetings, given the user's meetings.
+// Days and weeks are counted in loc. Continuous meeting time is enforced conservatively:
+// the break after a run starts where a meeting joining the run would exceed the limit.
+func (c Constraints) blocked(meetings []interval, from, now time.Time, loc *time.Location) []interval {
+	var blocked []interval
+
+	if c.MinNotice > 0 {
+		blocked = append(blocked, interval{start: from, end: now.Add(c.MinNotice)})
+	}
+
+	for _, meeting := range meetings {
+		blocked = append(blocked,
+			interval{start: meeting.start.Add(-c.BufferBefore), end: meeting.start},
+			interval{start: meeting.end, end: meeting.end.Add(c.BufferAfter)})
+	}
+
+	if c.MaxPerDay > 0 || c.MaxPerWeek > 0 {
+		perDay := map[time.Time]int{}
+		perWeek := map[time.Time]int{}
+		for _, meeting := range meetings {
+			day := atClock(meeting.start.In(loc), 0, loc)
+			perDay[day]++
+			perWeek[weekStart(day, loc)]++
+		}
+		for day, count := range perDay {
+			if c.MaxPerDay > 0 && count >= c.MaxPerDay {
+				blocked = append(blocked, interval{start: day, end: atClock(day.AddDate(0, 0, 1), 0, loc)})
+			}
+		}
+		for week, count := range perWeek {
+			if c.MaxPerWeek > 0 && count >= c.MaxPerWeek {
+				blocked = append(blocked, interval{start: week, end: atClock(week.AddDate(0, 0, 7), 0, loc)})
+			}
+		}
+	}
+
+	if c.MaxContinuous > 0 {
+		for _, run := range meetingRuns(meetings, c.Break) {
+			after := maxTime(run.end, run.start.Add(c.MaxContinuous))
+			before := minTime(run.start, run.end.Add(-c.MaxContinuous))
+			blocked = append(blocked,
+				interval{start: after, end: after.Add(c.Break)},
+				interval{start: before.Add(-c.Break), end: before})
+		}
+	}
+
+	return blocked
+}
+
+// weekStart returns the Monday midnight in loc starting the week of day.
+func weekStart(day time.Time, loc *time.Location) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return atClock(day.AddDate(0, 0, -offset), 0, loc)
+}
+
+// meetingRuns merges meetings separated by less than breakLength into runs.
+func meetingRuns(meetings []interval, breakLength time.Duration) []interval {
+	var runs []interval
+	for _, meeting := range mergeIntervals(meetings) {
+		last := len(runs) - 1
+		if last >= 0 && meeting.start.Sub(runs[last].end) < breakLength {
+			runs[last].end = maxTime(runs[last].end, meeting.end)
+			continue
+		}
+		runs = append(runs, meeting)
+	}
+	return runs
+}
@@ -0,0 +1,126 @@
+package data
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestConstraintsBlocked(t *testing.T) {
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	meetings := []interval{
+		{start: at(10, 0), end: at(11, 0)},
+		{start: at(11, 0), end: at(12, 0)},
+		{start: at(14, 0), end: at(14, 30)},
+	}
+	opts := SlotOptions{From: day, To: day.Add(24 * time.Hour), MinDuration: 15 * time.Minute, Location: time.UTC}
+
+	free := func(c Constraints, now time.Time) []interval {
+		busy := append(append([]interval{}, meetings...), c.blocked(meetings, opts.From, now, time.UTC)...)
+		return freeIntervals(busy, opts)
+	}
+
+	tests := []struct {
+		name        string
+		constraints Constraints
+		now         time.Time
+		expected    []interval
+	}{
+		{
+			name:        "buffers and continuous meeting time",
+			constraints: Constraints{BufferBefore: 5 * time.Minute, BufferAfter: 10 * time.Minute, MaxContinuous: 2 * time.Hour, Break: 15 * time.Minute},
+			now:         day,
+			expected: []interval{
+				{start: at(9, 0), end: at(9, 45)},
+				{start: at(12, 30), end: at(13, 55)},
+				{start: at(14, 40), end: at(16, 0)},
+				{start: at(16, 15), end: at(17, 0)},
+			},
+		},
+		{
+			name:        "minimum notice",
+			constraints: Constraints{MinNotice: 3 * time.Hour},
+			now:         at(11, 30),
+			expected: []interval{
+				{start: at(14, 30), end: at(17, 0)},
+			},
+		},
+		{
+			name:        "daily cap",
+			constraints: Constraints{MaxPerDay: 3},
+			now:         day,
+		},
+		{
+			name:        "weekly cap",
+			constraints: Constraints{MaxPerWeek: 3},
+			now:         day,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := free(tt.constraints, tt.now)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range tt.expected {
+				if !got[i].start.Equal(tt.expected[i].start) || !got[i].end.Equal(tt.expected[i].end) {
+					t.Errorf("expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+
+	// The weekly cap closes the rest of the week too
+	c := Constraints{MaxPerWeek: 3}
+	tuesday := opts
+	tuesday.From, tuesday.To = day.AddDate(0, 0, 1), day.AddDate(0, 0, 2)
+	if got := freeIntervals(append(append([]interval{}, meetings...), c.blocked(meetings, tuesday.From, day, time.UTC)...), tuesday); len(got) != 0 {
+		t.Errorf("expected the week to be closed, got %v", got)
+	}
+}
+
+func TestGetFreeSlotsOverridesConstraints(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	email := "anna@example.com"
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	fake.AddBusy(email, BusyPeriod{Start: day.Add(10 * time.Hour), End: day.Add(11 * time.Hour), Status: SlotBusy})
+	expectToken(mock, email, "anna-token")
+	mock.ExpectQuery(`SELECT p.timezone, p.working_hours`).
+		WithArgs(email).
+		WillReturnRows(preferenceRows().
+			AddRow("UTC", `{"monday": [{"start": "09:00", "end": "17:00"}], "tuesday": [{"start": "09:00", "end": "17:00"}]}`, 0, 3, 0, 0, 30, 0, 0))
+	expectHolds(mock)
+
+	// The user allows three meetings a day; the request allows only one
+	maxPerDay := 1
+	slots, err := models.GetFreeSlots(context.Background(), email, SlotOptions{
+		From:        day,
+		To:          day.Add(48 * time.Hour),
+		MinDuration: 30 * time.Minute,
+		Location:    time.UTC,
+		Overrides:   ConstraintOverrides{MaxPerDay: &maxPerDay},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(slots) != 1 || !slots[0].Start.Equal(day.Add(33*time.Hour)) || !slots[0].End.Equal(day.Add(41*time.Hour)) {
+		t.Errorf("expected only Tuesday to be free, got %v", slots)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
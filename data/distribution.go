@@ -0,0 +1,340 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrInvalidDistribution is returned for unknown distribution modes and weights.
+	ErrInvalidDistribution = errors.New("invalid distribution")
+	// ErrNoHostAvailable is returned when no member of a group can host a meeting.
+	ErrNoHostAvailable = errors.New("no member of the group is available to host")
+)
+
+// Distribution modes of a group. Without one every member attends the group's meetings.
+const (
+	// DistributeRoundRobin takes turns in alphabetical order, skipping busy members.
+	DistributeRoundRobin = "round_robin"
+	// DistributeLeastBusy picks the member with the least meeting time in the week.
+	DistributeLeastBusy = "least_busy"
+	// DistributeWeighted spreads meetings in proportion to the members' weights.
+	DistributeWeighted = "weighted"
+)
+
+// Distribution sets how a group's meetings are handed to a single host.
+type Distribution struct {
+	Mode string `json:"mode" enums:"round_robin,least_busy,weighted" example:"round_robin"`
+	// Weights are the members' shares of meetings in weighted mode, 1 by default.
+	Weights map[string]int `json:"weights,omitempty"`
+}
+
+// IsZero reports whether d leaves every member attending.
+func (d Distribution) IsZero() bool {
+	return d.Mode == ""
+}
+
+// Validate checks that d names a known mode and positive weights.
+func (d Distribution) Validate() error {
+	switch d.Mode {
+	case "", DistributeRoundRobin, DistributeLeastBusy, DistributeWeighted:
+	default:
+		return fmt.Errorf("%w: mode must be round_robin, least_busy, weighted or empty", ErrInvalidDistribution)
+	}
+	for email, weight := range d.Weights {
+		if weight < 1 {
+			return fmt.Errorf("%w: weight of %s must be at least 1", ErrInvalidDistribution, email)
+		}
+	}
+	return nil
+}
+
+// GroupDistribution returns the distribution mode of groupName, with the members'
+// weights in weighted mode.
+func (m *Models) GroupDistribution(groupName string) (*Distribution, error) {
+	d := &Distribution{}
+	err := m.DB.QueryRow(`SELECT distribution FROM groups WHERE name = $1`, groupName).Scan(&d.Mode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group distribution: %w", err)
+	}
+	if d.Mode != DistributeWeighted {
+		return d, nil
+	}
+
+	rows, err := m.DB.Query(`SELECT user_email, weight FROM user_groups WHERE group_name = $1`, groupName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query member weights: %w", err)
+	}
+	defer rows.Close()
+
+	d.Weights = map[string]int{}
+	for rows.Next() {
+		var email string
+		var weight int
+		err := rows.Scan(&email, &weight)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan member weight: %w", err)
+		}
+		d.Weights[email] = weight
+	}
+
+	return d, nil
+}
+
+// SetGroupDistribution sets the distribution of groupName on behalf of actor, who has
+// to be an admin of the group. Weights can only be given to members.
+func (m *Models) SetGroupDistribution(groupName, actor string, d Distribution) error {
+	members, err := m.GroupMembers(groupName)
+	if err != nil {
+		return err
+	}
+
+	admin, err := m.IsGroupAdmin(actor, []string{groupName})
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return ErrNotAllowed
+	}
+
+	for email := range d.Weights {
+		if !contains(members, email) {
+			return fmt.Errorf("%w: %s", ErrNotGroupMember, email)
+		}
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE groups SET distribution = $1 WHERE name = $2`, d.Mode, groupName)
+	if err != nil {
+		return fmt.Errorf("failed to update group distribution: %w", err)
+	}
+
+	for _, email := range members {
+		weight, ok := d.Weights[email]
+		if !ok {
+			weight = 1
+		}
+		_, err = tx.Exec(`UPDATE user_groups SET weight = $1 WHERE group_name = $2 AND user_email = $3`, weight, groupName, email)
+		if err != nil {
+			return fmt.Errorf("failed to update member weight: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// assignment summarizes the meetings a group has handed to one host.
+type assignment struct {
+	count int
+	last  time.Time
+}
+
+// assignmentHistory returns the meetings groupName has handed to each host.
+func assignmentHistory(tx *sql.Tx, groupName string) (map[string]assignment, error) {
+	query := `SELECT host, COUNT(*), MAX(assigned_at) FROM group_assignments WHERE group_name = $1 GROUP BY host`
+	rows, err := tx.Query(query, groupName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assignment history: %w", err)
+	}
+	defer rows.Close()
+
+	history := map[string]assignment{}
+	for rows.Next() {
+		var host string
+		var a assignment
+		err := rows.Scan(&host, &a.count, &a.last)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan assignment: %w", err)
+		}
+		history[host] = a
+	}
+
+	return history, nil
+}
+
+// pickHost chooses the member of groupName who hosts a meeting during slot according
+// to d, and records the assignment without an event so that concurrent meetings see
+// it; the returned ID is that of the assignment. Only members who are free, within
+// their working hours and constraints, can be chosen; ErrNoHostAvailable is returned
+// when there are none.
+func (m *Models) pickHost(ctx context.Context, groupName string, d *Distribution, slot interval) (string, int, error) {
+	members, err := m.GroupMembers(groupName)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// The whole week is read so least-busy can compare the members' load
+	week := weekStart(slot.start.UTC(), time.UTC)
+	from, to := minTime(week, slot.start), maxTime(week.AddDate(0, 0, 7), slot.end)
+	results := m.membersBusy(ctx, members, SlotOptions{From: from, To: to})
+
+	held, err := m.heldIntervals(from, to)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var candidates []string
+	load := map[string]time.Duration{}
+	for i, result := range results {
+		if result.err != nil {
+			return "", 0, fmt.Errorf("failed to read calendar of %s: %w", members[i], result.err)
+		}
+		if result.reason != "" {
+			continue
+		}
+
+		meetings := mergeIntervals(append(result.busy, held[members[i]]...))
+		unavailable, err := m.unavailableTime(members[i], meetings, SlotOptions{From: slot.start, To: slot.end})
+		if err != nil {
+			return "", 0, err
+		}
+		if overlapsAny(slot, meetings) || overlapsAny(slot, unavailable) {
+			continue
+		}
+
+		candidates = append(candidates, members[i])
+		for _, meeting := range meetings {
+			load[members[i]] += minTime(meeting.end, week.AddDate(0, 0, 7)).Sub(maxTime(meeting.start, week))
+		}
+	}
+	if len(candidates) == 0 {
+		return "", 0, ErrNoHostAvailable
+	}
+
+	// The group row is locked until the assignment is recorded, so that concurrent
+	// meetings of the group pick their hosts one after another
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`SELECT name FROM groups WHERE name = $1 FOR UPDATE`, groupName)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to lock group: %w", err)
+	}
+
+	history, err := assignmentHistory(tx, groupName)
+	if err != nil {
+		return "", 0, err
+	}
+
+	host := chooseHost(d, candidates, load, history)
+
+	var id int
+	query := `INSERT INTO group_assignments (group_name, host) VALUES ($1, $2) RETURNING id`
+	err = tx.QueryRow(query, groupName, host).Scan(&id)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to save host assignment: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return host, id, nil
+}
+
+// releaseHost deletes the assignment with the given ID, for a meeting that could not
+// be booked after its host was picked.
+func (m *Models) releaseHost(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM group_assignments WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to release host assignment: %w", err)
+	}
+
+	return nil
+}
+
+// chooseHost picks the host of a meeting among candidates according to d, given the
+// candidates' load over the week and the group's assignment history.
+func chooseHost(d *Distribution, candidates []string, load map[string]time.Duration, history map[string]assignment) string {
+	switch d.Mode {
+	case DistributeRoundRobin:
+		// The next member after the last host, in alphabetical order
+		var last string
+		var lastAt time.Time
+		for host, a := range history {
+			if a.last.After(lastAt) {
+				last, lastAt = host, a.last
+			}
+		}
+		for _, email := range candidates {
+			if email > last {
+				return email
+			}
+		}
+		return candidates[0]
+
+	case DistributeLeastBusy:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return load[candidates[i]] < load[candidates[j]]
+		})
+		return candidates[0]
+
+	default:
+		// The member furthest behind their share of meetings
+		weight := func(email string) int {
+			if w, ok := d.Weights[email]; ok {
+				return w
+			}
+			return 1
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			a, b := candidates[i], candidates[j]
+			// history[a].count/weight(a) < history[b].count/weight(b), without division
+			left, right := history[a].count*weight(b), history[b].count*weight(a)
+			if left != right {
+				return left < right
+			}
+			return weight(a) > weight(b)
+		})
+		return candidates[0]
+	}
+}
+
+// unionSlots returns the free time of any of the attendees, for groups whose meetings
+// need only one host. Every slot is free for one attendee: slots of different attendees
+// may overlap, but are not joined into time that none of them could host alone. Slots
+// within another one are left out.
+func unionSlots(attendees []attendeeBusy, opts SlotOptions) []TimeSlot {
+	var free []interval
+	for _, attendee := range attendees {
+		free = append(free, freeIntervals(attendee.busy, opts)...)
+	}
+	sort.Slice(free, func(i, j int) bool {
+		if !free[i].start.Equal(free[j].start) {
+			return free[i].start.Before(free[j].start)
+		}
+		return free[i].end.After(free[j].end)
+	})
+
+	slots := []TimeSlot{}
+	var covered time.Time
+	for _, period := range free {
+		if !period.end.After(covered) {
+			continue
+		}
+		slots = append(slots, NewTimeSlot(period.start, period.end, SlotFree))
+		covered = period.end
+	}
+	return slots
+}
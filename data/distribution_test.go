@@ -0,0 +1,309 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func expectDistribution(mock sqlmock.Sqlmock, group, mode string) {
+	mock.ExpectQuery(`SELECT distribution FROM groups WHERE name =`).
+		WithArgs(group).
+		WillReturnRows(sqlmock.NewRows([]string{"distribution"}).AddRow(mode))
+}
+
+func expectMembers(mock sqlmock.Sqlmock, group string, members ...string) {
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WithArgs(group).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	rows := sqlmock.NewRows([]string{"user_email"})
+	for _, email := range members {
+		rows.AddRow(email)
+	}
+	mock.ExpectQuery(`SELECT user_email FROM user_groups WHERE group_name =`).
+		WithArgs(group).
+		WillReturnRows(rows)
+}
+
+// expectHostCandidates expects the queries pickHost makes to check members' calendars.
+func expectHostCandidates(mock sqlmock.Sqlmock, group string, members ...string) {
+	expectMembers(mock, group, members...)
+	for _, email := range members {
+		expectToken(mock, email, email+"-token")
+	}
+	expectHolds(mock)
+	for _, email := range members {
+		expectPreferences(mock, email)
+	}
+}
+
+// expectAssignment expects pickHost to read the history of group under its lock and
+// record host as assignment 1.
+func expectAssignment(mock sqlmock.Sqlmock, group string, rows *sqlmock.Rows, host string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT name FROM groups WHERE name = \$1 FOR UPDATE`).WithArgs(group).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT host, COUNT\(\*\), MAX\(assigned_at\) FROM group_assignments`).
+		WithArgs(group).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`INSERT INTO group_assignments \(group_name, host\) VALUES \(\$1, \$2\) RETURNING id`).
+		WithArgs(group, host).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+}
+
+func historyRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"host", "count", "max"})
+}
+
+func TestPickHost(t *testing.T) {
+	slot := interval{start: time.Date(2024, 5, 8, 10, 0, 0, 0, time.UTC), end: time.Date(2024, 5, 8, 10, 30, 0, 0, time.UTC)}
+	earlier := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		distribution Distribution
+		busy         map[string][]interval
+		history      *sqlmock.Rows
+		expected     string
+	}{
+		{
+			name:         "round robin continues after the last host",
+			distribution: Distribution{Mode: DistributeRoundRobin},
+			history:      historyRows().AddRow("ann@example.com", 4, earlier.Add(time.Hour)).AddRow("bob@example.com", 3, earlier),
+			expected:     "bob@example.com",
+		},
+		{
+			name:         "round robin skips busy members",
+			distribution: Distribution{Mode: DistributeRoundRobin},
+			busy:         map[string][]interval{"bob@example.com": {slot}},
+			history:      historyRows().AddRow("ann@example.com", 4, earlier),
+			expected:     "cid@example.com",
+		},
+		{
+			name:         "round robin wraps around",
+			distribution: Distribution{Mode: DistributeRoundRobin},
+			history:      historyRows().AddRow("cid@example.com", 1, earlier),
+			expected:     "ann@example.com",
+		},
+		{
+			name:         "least busy this week",
+			distribution: Distribution{Mode: DistributeLeastBusy},
+			busy: map[string][]interval{
+				"ann@example.com": {{start: slot.start.Add(-48 * time.Hour), end: slot.start.Add(-45 * time.Hour)}},
+				"bob@example.com": {{start: slot.start.Add(-48 * time.Hour), end: slot.start.Add(-47 * time.Hour)}},
+				"cid@example.com": {{start: slot.start.Add(2 * time.Hour), end: slot.start.Add(4 * time.Hour)}},
+			},
+			history:  historyRows(),
+			expected: "bob@example.com",
+		},
+		{
+			name:         "weighted keeps members at their share",
+			distribution: Distribution{Mode: DistributeWeighted, Weights: map[string]int{"ann@example.com": 3}},
+			history:      historyRows().AddRow("ann@example.com", 2, earlier).AddRow("bob@example.com", 1, earlier).AddRow("cid@example.com", 1, earlier),
+			expected:     "ann@example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer db.Close()
+
+			fake := NewFakeCalendar()
+			models := NewModels(db)
+			models.Calendar = fake
+			for email, periods := range tt.busy {
+				for _, period := range periods {
+					fake.AddBusy(email, BusyPeriod{Start: period.start, End: period.end, Status: SlotBusy})
+				}
+			}
+
+			expectHostCandidates(mock, "support", "ann@example.com", "bob@example.com", "cid@example.com")
+			expectAssignment(mock, "support", tt.history, tt.expected)
+
+			host, assignment, err := models.pickHost(context.Background(), "support", &tt.distribution, slot)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if host != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, host)
+			}
+			if assignment != 1 {
+				t.Errorf("expected assignment 1, got %d", assignment)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatalf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateMeetingAssignsHost(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	organizer := "org@example.com"
+	start := time.Date(2024, 5, 8, 10, 0, 0, 0, time.UTC)
+
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "org-token")
+	expectDistribution(mock, "support", DistributeRoundRobin)
+	expectHostCandidates(mock, "support", "ann@example.com", "bob@example.com")
+	expectAssignment(mock, "support", historyRows().AddRow("ann@example.com", 1, start.Add(-time.Hour)), "bob@example.com")
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO meetings`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_attendees`).WithArgs("fake-event-1", "partner@example.org").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_attendees`).WithArgs("fake-event-1", "bob@example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_groups`).WithArgs("fake-event-1", "support").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE group_assignments SET event_id`).WithArgs("fake-event-1", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	meeting, err := models.CreateMeeting(context.Background(), MeetingRequest{
+		Organizer: organizer,
+		Attendees: []string{"partner@example.org"},
+		Groups:    []string{"support"},
+		Title:     "Support call",
+		Start:     start,
+		End:       start.Add(30 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meeting.Host != "bob@example.com" {
+		t.Errorf("expected bob to host, got %q", meeting.Host)
+	}
+	if len(meeting.Attendees) != 2 || meeting.Attendees[1] != "bob@example.com" {
+		t.Errorf("expected the partner and the host only, got %v", meeting.Attendees)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCreateMeetingReleasesHostWhenNotBooked(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	organizer := "org@example.com"
+	start := time.Date(2024, 5, 8, 10, 0, 0, 0, time.UTC)
+
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "org-token")
+	expectDistribution(mock, "support", DistributeRoundRobin)
+	expectHostCandidates(mock, "support", "ann@example.com", "bob@example.com")
+	expectAssignment(mock, "support", historyRows(), "ann@example.com")
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO meetings`).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+	mock.ExpectExec(`DELETE FROM group_assignments WHERE id = \$1`).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := models.CreateMeeting(context.Background(), MeetingRequest{
+		Organizer: organizer,
+		Groups:    []string{"support"},
+		Title:     "Support call",
+		Start:     start,
+		End:       start.Add(30 * time.Minute),
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if events := fake.Events(organizer); len(events) != 0 {
+		t.Errorf("expected the event to be deleted, got %v", events)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetGroupFreeSlotsUnionForDistributedGroup(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	fake.AddBusy("ann@example.com", BusyPeriod{Start: day.Add(9 * time.Hour), End: day.Add(12 * time.Hour), Status: SlotBusy})
+	fake.AddBusy("bob@example.com", BusyPeriod{Start: day.Add(11 * time.Hour), End: day.Add(17 * time.Hour), Status: SlotBusy})
+
+	expectMembers(mock, "support", "ann@example.com", "bob@example.com")
+	expectDistribution(mock, "support", DistributeLeastBusy)
+	expectToken(mock, "ann@example.com", "ann-token")
+	expectToken(mock, "bob@example.com", "bob-token")
+	expectHolds(mock)
+	expectPreferences(mock, "ann@example.com")
+	expectPreferences(mock, "bob@example.com")
+
+	availability, err := models.GetGroupFreeSlots(context.Background(), "support", SlotOptions{
+		From:        day,
+		To:          day.Add(24 * time.Hour),
+		MinDuration: 30 * time.Minute,
+		Location:    time.UTC,
+	}, Quorum{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Bob is free until 11 and Ann from 12
+	expected := []interval{
+		{start: day.Add(9 * time.Hour), end: day.Add(11 * time.Hour)},
+		{start: day.Add(12 * time.Hour), end: day.Add(17 * time.Hour)},
+	}
+	if availability.Distribution != DistributeLeastBusy || len(availability.Slots) != len(expected) {
+		t.Fatalf("expected slots %v, got %+v", expected, availability)
+	}
+	for i, slot := range availability.Slots {
+		if !slot.Start.Equal(expected[i].start) || !slot.End.Equal(expected[i].end) {
+			t.Errorf("expected slots %v, got %v", expected, availability.Slots)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUnionSlotsKeepsMembersApart(t *testing.T) {
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	// Ann is free 9-10 and Bob 10-11, so no one can host 9:30-10:30; Carol's 9:15-9:45
+	// lies within Ann's slot
+	attendees := []attendeeBusy{
+		{email: "ann@example.com", busy: []interval{{start: at(10, 0), end: at(17, 0)}}},
+		{email: "bob@example.com", busy: []interval{{start: at(9, 0), end: at(10, 0)}, {start: at(11, 0), end: at(17, 0)}}},
+		{email: "carol@example.com", busy: []interval{{start: at(9, 0), end: at(9, 15)}, {start: at(9, 45), end: at(17, 0)}}},
+	}
+
+	slots := unionSlots(attendees, SlotOptions{From: day, To: day.Add(24 * time.Hour), MinDuration: 30 * time.Minute, Location: time.UTC})
+
+	expected := []interval{
+		{start: at(9, 0), end: at(10, 0)},
+		{start: at(10, 0), end: at(11, 0)},
+	}
+	if len(slots) != len(expected) {
+		t.Fatalf("expected slots %v, got %v", expected, slots)
+	}
+	for i, slot := range slots {
+		if !slot.Start.Equal(expected[i].start) || !slot.End.Equal(expected[i].end) {
+			t.Errorf("expected slots %v, got %v", expected, slots)
+		}
+	}
+}
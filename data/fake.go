@@ -0,0 +1,295 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// FakeCalendar is an in-memory CalendarProvider for tests and local development. Every
+// calendar starts empty and events created through it block their owner's time.
+type FakeCalendar struct {
+	mu      sync.Mutex
+	events  map[string][]*Event
+	busy    map[string][]BusyPeriod
+	hidden  map[string]string
+	revoked map[string]bool
+	zones   map[string]string
+	nextID  int
+}
+
+// NewFakeCalendar returns an empty FakeCalendar.
+func NewFakeCalendar() *FakeCalendar {
+	return &FakeCalendar{
+		events:  map[string][]*Event{},
+		busy:    map[string][]BusyPeriod{},
+		hidden:  map[string]string{},
+		revoked: map[string]bool{},
+		zones:   map[string]string{},
+	}
+}
+
+// AddBusy blocks a period of calendarID without creating an event.
+func (f *FakeCalendar) AddBusy(calendarID string, period BusyPeriod) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.busy[calendarID] = append(f.busy[calendarID], period)
+}
+
+// Hide makes the free/busy of calendarID readable only with ownerAccessToken, as if it
+// were not shared with the rest of the domain.
+func (f *FakeCalendar) Hide(calendarID, ownerAccessToken string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hidden[calendarID] = ownerAccessToken
+}
+
+// Revoke makes every call with accessToken fail with ErrInvalidToken.
+func (f *FakeCalendar) Revoke(accessToken string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.revoked[accessToken] = true
+}
+
+// SetTimeZone sets the time zone in the calendar settings of account, which is UTC
+// until set.
+func (f *FakeCalendar) SetTimeZone(account, timeZone string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.zones[account] = timeZone
+}
+
+// Events returns a copy of the events in calendarID ordered by start time.
+func (f *FakeCalendar) Events(calendarID string) []Event {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	events := make([]Event, 0, len(f.events[calendarID]))
+	for _, event := range f.events[calendarID] {
+		events = append(events, *event)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events
+}
+
+func (f *FakeCalendar) checkToken(token *oauth2.Token) error {
+	if token == nil || f.revoked[token.AccessToken] {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// periods returns the busy periods of calendarID overlapping from..to. The caller holds f.mu.
+func (f *FakeCalendar) periods(calendarID string, from, to time.Time) []BusyPeriod {
+	var periods []BusyPeriod
+	for _, period := range f.busy[calendarID] {
+		if period.Start.Before(to) && from.Before(period.End) {
+			periods = append(periods, period)
+		}
+	}
+	for _, event := range f.calendarEvents(calendarID) {
+		for _, start := range eventStarts(event) {
+			end := start.Add(event.End.Sub(event.Start))
+			if start.Before(to) && from.Before(end) {
+				periods = append(periods, BusyPeriod{Start: start, End: end, Status: SlotBusy})
+			}
+		}
+	}
+	return periods
+}
+
+// calendarEvents returns the events on calendarID, including events elsewhere that
+// book it as a resource. The caller holds f.mu.
+func (f *FakeCalendar) calendarEvents(calendarID string) []*Event {
+	events := append([]*Event{}, f.events[calendarID]...)
+	for owner, ownerEvents := range f.events {
+		if owner == calendarID {
+			continue
+		}
+		for _, event := range ownerEvents {
+			if contains(event.Resources, calendarID) {
+				events = append(events, event)
+			}
+		}
+	}
+	return events
+}
+
+// eventStarts returns the start of every occurrence of event.
+func eventStarts(event *Event) []time.Time {
+	if len(event.Recurrence) == 0 {
+		return []time.Time{event.Start}
+	}
+
+	loc, err := time.LoadLocation(event.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	rule, err := ParseRecurrence(event.Recurrence[0])
+	if err != nil {
+		return []time.Time{event.Start}
+	}
+	starts, err := rule.Occurrences(event.Start.In(loc))
+	if err != nil {
+		return []time.Time{event.Start}
+	}
+	return starts
+}
+
+// BusyPeriods returns the busy and tentative periods of calendarID.
+func (f *FakeCalendar) BusyPeriods(ctx context.Context, token *oauth2.Token, calendarID string, opts SlotOptions) ([]BusyPeriod, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkToken(token); err != nil {
+		return nil, err
+	}
+	return f.periods(calendarID, opts.From, opts.To), nil
+}
+
+// FreeBusy returns the busy periods of calendarIDs. Tentative periods are reported as
+// busy, as the FreeBusy API does, and hidden calendars are reported as failed.
+func (f *FakeCalendar) FreeBusy(ctx context.Context, token *oauth2.Token, calendarIDs []string, from, to time.Time) (map[string][]BusyPeriod, map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkToken(token); err != nil {
+		return nil, nil, err
+	}
+
+	busy := map[string][]BusyPeriod{}
+	failed := map[string]string{}
+	for _, id := range calendarIDs {
+		if owner, ok := f.hidden[id]; ok && owner != token.AccessToken {
+			failed[id] = "notFound"
+			continue
+		}
+		periods := []BusyPeriod{}
+		for _, period := range f.periods(id, from, to) {
+			period.Status = SlotBusy
+			periods = append(periods, period)
+		}
+		busy[id] = periods
+	}
+	return busy, failed, nil
+}
+
+// CreateEvent stores a copy of event in calendarID and gives it an ID and link.
+func (f *FakeCalendar) CreateEvent(ctx context.Context, token *oauth2.Token, calendarID string, event *Event) (*Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkToken(token); err != nil {
+		return nil, err
+	}
+
+	f.nextID++
+	created := *event
+	created.ID = fmt.Sprintf("fake-event-%d", f.nextID)
+	created.HTMLLink = "https://calendar.example.com/event/" + created.ID
+	if created.Conference {
+		created.MeetURL = "https://meet.example.com/" + created.ID
+	}
+	f.events[calendarID] = append(f.events[calendarID], &created)
+
+	result := created
+	return &result, nil
+}
+
+// UpdateEvent applies the non-zero fields of event to the stored event with event.ID.
+func (f *FakeCalendar) UpdateEvent(ctx context.Context, token *oauth2.Token, calendarID string, event *Event) (*Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkToken(token); err != nil {
+		return nil, err
+	}
+
+	for _, stored := range f.events[calendarID] {
+		if stored.ID != event.ID {
+			continue
+		}
+		if event.Title != "" {
+			stored.Title = event.Title
+		}
+		if event.Description != "" {
+			stored.Description = event.Description
+		}
+		if !event.Start.IsZero() {
+			stored.Start = event.Start
+		}
+		if !event.End.IsZero() {
+			stored.End = event.End
+		}
+		if event.TimeZone != "" {
+			stored.TimeZone = event.TimeZone
+		}
+		if event.Recurrence != nil {
+			stored.Recurrence = event.Recurrence
+		}
+		if event.Attendees != nil {
+			stored.Attendees = event.Attendees
+		}
+		if event.Resources != nil {
+			stored.Resources = event.Resources
+		}
+		result := *stored
+		return &result, nil
+	}
+
+	return nil, ErrEventNotFound
+}
+
+// DeleteEvent removes the event with eventID from calendarID.
+func (f *FakeCalendar) DeleteEvent(ctx context.Context, token *oauth2.Token, calendarID, eventID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkToken(token); err != nil {
+		return err
+	}
+
+	events := f.events[calendarID]
+	for i, stored := range events {
+		if stored.ID == eventID {
+			f.events[calendarID] = append(events[:i], events[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrEventNotFound
+}
+
+// ListCalendars returns the primary calendar of account.
+func (f *FakeCalendar) ListCalendars(ctx context.Context, token *oauth2.Token, account string) ([]CalendarInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkToken(token); err != nil {
+		return nil, err
+	}
+	return []CalendarInfo{{ID: account, Summary: account, TimeZone: f.zone(account), Primary: true}}, nil
+}
+
+// TimeZone returns the time zone set for account with SetTimeZone, or UTC.
+func (f *FakeCalendar) TimeZone(ctx context.Context, token *oauth2.Token, account string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkToken(token); err != nil {
+		return "", err
+	}
+	return f.zone(account), nil
+}
+
+// zone returns the time zone of account. f.mu must be held.
+func (f *FakeCalendar) zone(account string) string {
+	if zone, ok := f.zones[account]; ok {
+		return zone
+	}
+	return "UTC"
+}
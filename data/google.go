@@ -0,0 +1,431 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+var oauthConfig = &oauth2.Config{
+	ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
+	ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+	RedirectURL:  "http://localhost:80/oauth2callback",
+	Scopes:       []string{calendar.CalendarReadonlyScope},
+	Endpoint:     google.Endpoint,
+}
+
+// freeBusyBatchSize is the maximum number of calendars in one FreeBusy query.
+const freeBusyBatchSize = 50
+
+// GoogleCalendar is the CalendarProvider backed by the Google Calendar API.
+type GoogleCalendar struct{}
+
+// NewGoogleCalendar returns a CalendarProvider talking to Google Calendar.
+func NewGoogleCalendar() *GoogleCalendar {
+	return &GoogleCalendar{}
+}
+
+// calendarService returns a Google Calendar client acting with token.
+func calendarService(ctx context.Context, token *oauth2.Token) (*calendar.Service, error) {
+	client := oauthConfig.Client(ctx, token)
+
+	srv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create calendar service: %w", err)
+	}
+
+	return srv, nil
+}
+
+// googleError maps errors meaning the token was rejected or the event does not exist
+// to ErrInvalidToken and ErrEventNotFound, keeping the original error in the message.
+func googleError(err error) error {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusUnauthorized:
+			return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+		case http.StatusNotFound, http.StatusGone:
+			return fmt.Errorf("%w: %v", ErrEventNotFound, err)
+		}
+	}
+
+	return err
+}
+
+// BusyPeriods lists the events of calendarID and classifies the time they block.
+// Only the fields needed for that are requested, so titles and descriptions never
+// reach the service.
+func (g *GoogleCalendar) BusyPeriods(ctx context.Context, token *oauth2.Token, calendarID string, opts SlotOptions) ([]BusyPeriod, error) {
+	srv, err := calendarService(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch the events in the requested window, page by page
+	var timeZone string
+	var items []*calendar.Event
+	err = srv.Events.List(calendarID).
+		TimeMin(opts.From.Format(time.RFC3339)).
+		TimeMax(opts.To.Format(time.RFC3339)).
+		SingleEvents(true).
+		OrderBy("startTime").
+		Fields("nextPageToken", "timeZone", "items(id,status,transparency,start,end,attendees(self,responseStatus))").
+		Pages(ctx, func(events *calendar.Events) error {
+			timeZone = events.TimeZone
+			items = append(items, events.Items...)
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve calendar events: %w", googleError(err))
+	}
+
+	// All-day events carry dates without a zone; they start at midnight in the calendar's zone
+	calendarLocation := time.UTC
+	if timeZone != "" {
+		calendarLocation, err = time.LoadLocation(timeZone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown calendar time zone %q: %w", timeZone, err)
+		}
+	}
+
+	var periods []BusyPeriod
+	for _, event := range items {
+		status := eventStatus(event)
+		if status == SlotFree {
+			continue
+		}
+
+		eventPeriod, allDay, err := eventInterval(event, calendarLocation)
+		if err != nil {
+			return nil, err
+		}
+		if allDay && opts.IgnoreAllDay {
+			continue
+		}
+
+		periods = append(periods, BusyPeriod{Start: eventPeriod.start, End: eventPeriod.end, Status: status})
+	}
+
+	return periods, nil
+}
+
+// FreeBusy asks the FreeBusy API for the busy periods of calendarIDs, using as many
+// queries as the batch size requires.
+func (g *GoogleCalendar) FreeBusy(ctx context.Context, token *oauth2.Token, calendarIDs []string, from, to time.Time) (map[string][]BusyPeriod, map[string]string, error) {
+	srv, err := calendarService(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	busy := map[string][]BusyPeriod{}
+	failed := map[string]string{}
+	for start := 0; start < len(calendarIDs); start += freeBusyBatchSize {
+		end := min(start+freeBusyBatchSize, len(calendarIDs))
+
+		request := &calendar.FreeBusyRequest{
+			TimeMin: from.Format(time.RFC3339),
+			TimeMax: to.Format(time.RFC3339),
+		}
+		for _, id := range calendarIDs[start:end] {
+			request.Items = append(request.Items, &calendar.FreeBusyRequestItem{Id: id})
+		}
+
+		response, err := srv.Freebusy.Query(request).Context(ctx).Do()
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to query free/busy: %w", googleError(err))
+		}
+
+		for _, id := range calendarIDs[start:end] {
+			result, ok := response.Calendars[id]
+			if !ok {
+				failed[id] = "calendar missing from free/busy response"
+				continue
+			}
+			if len(result.Errors) > 0 {
+				var reasons []string
+				for _, e := range result.Errors {
+					reasons = append(reasons, e.Reason)
+				}
+				failed[id] = strings.Join(reasons, ", ")
+				continue
+			}
+
+			periods := []BusyPeriod{}
+			for _, period := range result.Busy {
+				busyStart, err := time.Parse(time.RFC3339, period.Start)
+				if err != nil {
+					return nil, nil, fmt.Errorf("error parsing busy period start: %w", err)
+				}
+				busyEnd, err := time.Parse(time.RFC3339, period.End)
+				if err != nil {
+					return nil, nil, fmt.Errorf("error parsing busy period end: %w", err)
+				}
+				periods = append(periods, BusyPeriod{Start: busyStart, End: busyEnd, Status: SlotBusy})
+			}
+			busy[id] = periods
+		}
+	}
+
+	return busy, failed, nil
+}
+
+// CreateEvent inserts event into calendarID, requesting a Google Meet conference when
+// event.Conference is set, and emails the invitation to every attendee.
+func (g *GoogleCalendar) CreateEvent(ctx context.Context, token *oauth2.Token, calendarID string, event *Event) (*Event, error) {
+	srv, err := calendarService(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	googleEvent := toGoogleEvent(event)
+	if event.Conference {
+		requestID, err := randomID()
+		if err != nil {
+			return nil, err
+		}
+		googleEvent.ConferenceData = &calendar.ConferenceData{
+			CreateRequest: &calendar.CreateConferenceRequest{
+				RequestId:             requestID,
+				ConferenceSolutionKey: &calendar.ConferenceSolutionKey{Type: "hangoutsMeet"},
+			},
+		}
+	}
+
+	created, err := srv.Events.Insert(calendarID, googleEvent).
+		ConferenceDataVersion(1).
+		SendUpdates("all").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create event: %w", googleError(err))
+	}
+
+	return fromGoogleEvent(created)
+}
+
+// UpdateEvent patches the event with event.ID in calendarID and emails the change to
+// every attendee.
+func (g *GoogleCalendar) UpdateEvent(ctx context.Context, token *oauth2.Token, calendarID string, event *Event) (*Event, error) {
+	srv, err := calendarService(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := srv.Events.Patch(calendarID, event.ID, toGoogleEvent(event)).
+		SendUpdates("all").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to update event: %w", googleError(err))
+	}
+
+	return fromGoogleEvent(updated)
+}
+
+// DeleteEvent removes the event with eventID from calendarID and emails the
+// cancellation to every attendee.
+func (g *GoogleCalendar) DeleteEvent(ctx context.Context, token *oauth2.Token, calendarID, eventID string) error {
+	srv, err := calendarService(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	err = srv.Events.Delete(calendarID, eventID).
+		SendUpdates("all").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return fmt.Errorf("unable to delete event: %w", googleError(err))
+	}
+
+	return nil
+}
+
+// ListCalendars returns the calendar list of the token's owner.
+func (g *GoogleCalendar) ListCalendars(ctx context.Context, token *oauth2.Token, account string) ([]CalendarInfo, error) {
+	srv, err := calendarService(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars := []CalendarInfo{}
+	err = srv.CalendarList.List().Pages(ctx, func(list *calendar.CalendarList) error {
+		for _, entry := range list.Items {
+			calendars = append(calendars, CalendarInfo{
+				ID:       entry.Id,
+				Summary:  entry.Summary,
+				TimeZone: entry.TimeZone,
+				Primary:  entry.Primary,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list calendars: %w", googleError(err))
+	}
+
+	return calendars, nil
+}
+
+// TimeZone returns the time zone from the calendar settings of the token's owner.
+func (g *GoogleCalendar) TimeZone(ctx context.Context, token *oauth2.Token, account string) (string, error) {
+	srv, err := calendarService(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	setting, err := srv.Settings.Get("timezone").Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("unable to read time zone setting: %w", googleError(err))
+	}
+
+	return setting.Value, nil
+}
+
+// eventStatus classifies how event occupies the user's time. Cancelled instances,
+// events shown as free and invitations the user declined do not block anything, and
+// invitations answered with "maybe" only block tentatively.
+func eventStatus(event *calendar.Event) SlotStatus {
+	if event.Status == "cancelled" || event.Transparency == "transparent" {
+		return SlotFree
+	}
+
+	for _, attendee := range event.Attendees {
+		if !attendee.Self {
+			continue
+		}
+		switch attendee.ResponseStatus {
+		case "declined":
+			return SlotFree
+		case "tentative":
+			return SlotTentative
+		}
+	}
+
+	if event.Status == "tentative" {
+		return SlotTentative
+	}
+
+	return SlotBusy
+}
+
+// eventInterval returns the time range covered by event and whether it is an all-day
+// event. All-day dates are parsed as midnights in loc, and since Google's end date is
+// exclusive a multi-day event blocks every day up to, but not including, that date.
+func eventInterval(event *calendar.Event, loc *time.Location) (interval, bool, error) {
+	if event.Start == nil || event.End == nil {
+		return interval{}, false, fmt.Errorf("event %s has no start or end", event.Id)
+	}
+
+	if event.Start.DateTime == "" {
+		start, err := time.ParseInLocation(time.DateOnly, event.Start.Date, loc)
+		if err != nil {
+			return interval{}, true, fmt.Errorf("error parsing event start date: %w", err)
+		}
+		end, err := time.ParseInLocation(time.DateOnly, event.End.Date, loc)
+		if err != nil {
+			return interval{}, true, fmt.Errorf("error parsing event end date: %w", err)
+		}
+		return interval{start: start, end: end}, true, nil
+	}
+
+	start, err := time.Parse(time.RFC3339, event.Start.DateTime)
+	if err != nil {
+		return interval{}, false, fmt.Errorf("error parsing event start time: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, event.End.DateTime)
+	if err != nil {
+		return interval{}, false, fmt.Errorf("error parsing event end time: %w", err)
+	}
+	return interval{start: start, end: end}, false, nil
+}
+
+// randomID returns a random hex identifier, used to make conference creation idempotent.
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate request id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// toGoogleEvent converts event into the Calendar API representation. Zero fields are
+// left out, so the result can also be used to patch an existing event.
+func toGoogleEvent(event *Event) *calendar.Event {
+	googleEvent := &calendar.Event{
+		Summary:     event.Title,
+		Description: event.Description,
+	}
+	if !event.Start.IsZero() {
+		googleEvent.Start = &calendar.EventDateTime{DateTime: event.Start.Format(time.RFC3339), TimeZone: event.TimeZone}
+	}
+	if !event.End.IsZero() {
+		googleEvent.End = &calendar.EventDateTime{DateTime: event.End.Format(time.RFC3339), TimeZone: event.TimeZone}
+	}
+	googleEvent.Recurrence = event.Recurrence
+	for _, email := range event.Attendees {
+		googleEvent.Attendees = append(googleEvent.Attendees, &calendar.EventAttendee{Email: email})
+	}
+	for _, email := range event.Resources {
+		googleEvent.Attendees = append(googleEvent.Attendees, &calendar.EventAttendee{Email: email, Resource: true})
+	}
+	return googleEvent
+}
+
+// fromGoogleEvent converts a timed Calendar API event into an Event.
+func fromGoogleEvent(googleEvent *calendar.Event) (*Event, error) {
+	event := &Event{
+		ID:          googleEvent.Id,
+		Title:       googleEvent.Summary,
+		Description: googleEvent.Description,
+		HTMLLink:    googleEvent.HtmlLink,
+		MeetURL:     googleEvent.HangoutLink,
+		Recurrence:  googleEvent.Recurrence,
+	}
+	if googleEvent.Start != nil {
+		event.TimeZone = googleEvent.Start.TimeZone
+	}
+	if googleEvent.ConferenceData != nil {
+		for _, entryPoint := range googleEvent.ConferenceData.EntryPoints {
+			if entryPoint.EntryPointType == "video" {
+				event.MeetURL = entryPoint.Uri
+				break
+			}
+		}
+	}
+
+	period, _, err := eventInterval(googleEvent, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	event.Start, event.End = period.start, period.end
+
+	for _, attendee := range googleEvent.Attendees {
+		if attendee.Resource {
+			event.Resources = append(event.Resources, attendee.Email)
+		} else {
+			event.Attendees = append(event.Attendees, attendee.Email)
+		}
+	}
+
+	return event, nil
+}
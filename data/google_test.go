@@ -0,0 +1,73 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestEventInterval(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+
+	vacation := &calendar.Event{
+		Start: &calendar.EventDateTime{Date: "2024-05-06"},
+		End:   &calendar.EventDateTime{Date: "2024-05-08"},
+	}
+	busy, allDay, err := eventInterval(vacation, warsaw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !allDay {
+		t.Errorf("expected vacation to be an all-day event")
+	}
+	if !busy.start.Equal(time.Date(2024, 5, 6, 0, 0, 0, 0, warsaw)) || !busy.end.Equal(time.Date(2024, 5, 8, 0, 0, 0, 0, warsaw)) {
+		t.Errorf("unexpected all-day interval: %v", busy)
+	}
+
+	meeting := &calendar.Event{
+		Start: &calendar.EventDateTime{DateTime: "2024-05-06T10:00:00+02:00"},
+		End:   &calendar.EventDateTime{DateTime: "2024-05-06T11:00:00+02:00"},
+	}
+	busy, allDay, err = eventInterval(meeting, warsaw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if allDay {
+		t.Errorf("expected meeting not to be an all-day event")
+	}
+	if busy.end.Sub(busy.start) != time.Hour {
+		t.Errorf("unexpected meeting interval: %v", busy)
+	}
+}
+
+func TestEventStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    *calendar.Event
+		expected SlotStatus
+	}{
+		{"opaque", &calendar.Event{Status: "confirmed"}, SlotBusy},
+		{"cancelled", &calendar.Event{Status: "cancelled"}, SlotFree},
+		{"show as free", &calendar.Event{Status: "confirmed", Transparency: "transparent"}, SlotFree},
+		{"declined", &calendar.Event{Attendees: []*calendar.EventAttendee{
+			{Email: "other@example.com", ResponseStatus: "accepted"},
+			{Email: "me@example.com", Self: true, ResponseStatus: "declined"},
+		}}, SlotFree},
+		{"maybe", &calendar.Event{Attendees: []*calendar.EventAttendee{
+			{Email: "me@example.com", Self: true, ResponseStatus: "tentative"},
+		}}, SlotTentative},
+		{"unanswered", &calendar.Event{Attendees: []*calendar.EventAttendee{
+			{Email: "me@example.com", Self: true, ResponseStatus: "needsAction"},
+		}}, SlotBusy},
+	}
+
+	for _, tt := range tests {
+		if status := eventStatus(tt.event); status != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, status)
+		}
+	}
+}
@@ -0,0 +1,460 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrNotGroupMember is returned when a quorum names someone outside the group.
+var ErrNotGroupMember = errors.New("not a member of the group")
+
+// Quorum relaxes a group search from "every member is free" to "the required members
+// and at least MinAttendees people are free". When Required and Optional are both
+// empty every member of the group is optional; otherwise only the listed members are
+// taken into account.
+type Quorum struct {
+	Required     []string
+	Optional     []string
+	MinAttendees int
+}
+
+// IsZero reports whether q leaves the default "every member is free" search in place.
+func (q Quorum) IsZero() bool {
+	return len(q.Required) == 0 && len(q.Optional) == 0 && q.MinAttendees == 0
+}
+
+// QuorumSlot is a slot in which a quorum of the group is available.
+type QuorumSlot struct {
+	Slot TimeSlot `json:"slot"`
+	// AvailableOptional is the number of optional members who can make it.
+	AvailableOptional int `json:"available_optional" example:"6"`
+	// Attendees are the members who are free for the whole slot.
+	Attendees []string `json:"attendees"`
+	// Missing are the members who are busy during at least part of the slot.
+	Missing []string `json:"missing"`
+}
+
+// UnavailableMember is a group member whose calendar could not be read.
+type UnavailableMember struct {
+	Email  string `json:"email" example:"anna@example.com"`
+	Reason string `json:"reason" example:"user has not authorized the app"`
+}
+
+// GroupAvailability is the result of a group free-slot search.
+type GroupAvailability struct {
+	Group string `json:"group" example:"design"`
+	// Members are the members whose calendars were taken into account.
+	Members []string `json:"members"`
+	// Unavailable are the members left out because their token is missing or invalid.
+	Unavailable []UnavailableMember `json:"unavailable_members"`
+	// Slots are the periods in which every member in Members is available. They are
+	// only computed when no quorum was requested.
+	Slots []TimeSlot `json:"slots"`
+	// RankedSlots are the periods in which the requested quorum is available, ordered
+	// by how many optional members can make it.
+	RankedSlots []QuorumSlot `json:"ranked_slots,omitempty"`
+	// Distribution is the group's host assignment mode. When set, Slots are the periods
+	// in which any member is available to host.
+	Distribution string `json:"distribution,omitempty" example:"round_robin"`
+}
+
+// memberBusy holds the busy time of one group member, or why it could not be read.
+type memberBusy struct {
+	busy   []interval
+	reason string
+	err    error
+}
+
+// GetGroupFreeSlots returns the slots between opts.From and opts.To in which every
+// member of groupName with a valid token is available for at least opts.MinDuration.
+// With a non-zero quorum it returns RankedSlots instead, in which the quorum is met.
+// Busy time comes from the FreeBusy API, which does not distinguish tentative events,
+// so group slots are always free ones. Slots fall within every counted member's working
+// hours and respect their booking constraints, and active holds count as meetings of
+// the people they reserve. Members without a usable token are reported in
+// Unavailable instead of failing the search. With opts.Room set, time in which no
+// matching room is free counts as busy for everyone. Groups that hand meetings to a
+// single host are available whenever any member is.
+func (m *Models) GetGroupFreeSlots(ctx context.Context, groupName string, opts SlotOptions, quorum Quorum) (*GroupAvailability, error) {
+	members, err := m.GroupMembers(groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	distribution, err := m.GroupDistribution(groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	required := map[string]bool{}
+	if len(quorum.Required) > 0 || len(quorum.Optional) > 0 {
+		isMember := map[string]bool{}
+		for _, email := range members {
+			isMember[email] = true
+		}
+
+		var listed []string
+		for _, email := range append(append([]string{}, quorum.Required...), quorum.Optional...) {
+			if !isMember[email] {
+				return nil, fmt.Errorf("%w: %s", ErrNotGroupMember, email)
+			}
+			if !contains(listed, email) {
+				listed = append(listed, email)
+			}
+		}
+		for _, email := range quorum.Required {
+			required[email] = true
+		}
+		members = listed
+	}
+
+	fetch := opts
+	fetch.From = opts.From.Add(-memberLookaround)
+	fetch.To = opts.To.Add(memberLookaround)
+
+	results := m.membersBusy(ctx, members, fetch)
+
+	held, err := m.heldIntervals(fetch.From, fetch.To)
+	if err != nil {
+		return nil, err
+	}
+
+	availability := &GroupAvailability{
+		Group:        groupName,
+		Members:      []string{},
+		Unavailable:  []UnavailableMember{},
+		Slots:        []TimeSlot{},
+		Distribution: distribution.Mode,
+	}
+
+	var busy []interval
+	var attendees []attendeeBusy
+	for i, result := range results {
+		if result.err != nil {
+			return nil, fmt.Errorf("failed to read calendar of %s: %w", members[i], result.err)
+		}
+		if result.reason != "" {
+			availability.Unavailable = append(availability.Unavailable, UnavailableMember{Email: members[i], Reason: result.reason})
+			continue
+		}
+
+		meetings := append(result.busy, held[members[i]]...)
+		unavailable, err := m.unavailableTime(members[i], meetings, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		blocked := append(meetings, unavailable...)
+		availability.Members = append(availability.Members, members[i])
+		busy = append(busy, blocked...)
+		attendees = append(attendees, attendeeBusy{
+			email:    members[i],
+			required: required[members[i]],
+			busy:     blocked,
+		})
+	}
+
+	if !opts.Room.IsZero() && len(availability.Members) > 0 {
+		token, err := m.GetUserToken(availability.Members[0])
+		if err != nil {
+			return nil, err
+		}
+		noRoom, err := m.noRoomTime(ctx, token, opts.Room, opts.From, opts.To)
+		if err != nil {
+			return nil, err
+		}
+		busy = append(busy, noRoom...)
+		for i := range attendees {
+			attendees[i].busy = append(attendees[i].busy, noRoom...)
+		}
+	}
+
+	// A required member whose calendar cannot be read can never be shown to be free
+	for _, unavailable := range availability.Unavailable {
+		if required[unavailable.Email] {
+			if !quorum.IsZero() {
+				availability.RankedSlots = []QuorumSlot{}
+			}
+			return availability, nil
+		}
+	}
+
+	if quorum.IsZero() {
+		switch {
+		case len(availability.Members) == 0:
+		case !distribution.IsZero():
+			availability.Slots = unionSlots(attendees, acrossSchedules(opts))
+		default:
+			availability.Slots = classifiedSlots(busy, nil, acrossSchedules(opts))
+		}
+		return availability, nil
+	}
+
+	minAttendees := quorum.MinAttendees
+	if minAttendees < len(required) {
+		minAttendees = len(required)
+	}
+	if minAttendees < 1 {
+		minAttendees = 1
+	}
+	availability.RankedSlots = quorumSlots(attendees, minAttendees, acrossSchedules(opts))
+
+	return availability, nil
+}
+
+// membersBusy reads the busy time of every member through the provider's free/busy
+// query. The first member token the provider accepts queries all calendars in a single
+// batched round-trip, which works whenever members can see each other's free/busy, as
+// they can within a Workspace domain. Calendars that token cannot read are retried
+// concurrently with their owner's token.
+func (m *Models) membersBusy(ctx context.Context, members []string, opts SlotOptions) []memberBusy {
+	results := make([]memberBusy, len(members))
+	tokens := make([]*oauth2.Token, len(members))
+	var pending []int
+	for i, email := range members {
+		token, err := m.GetUserToken(email)
+		switch {
+		case errors.Is(err, ErrTokenNotFound):
+			results[i].reason = "user has not authorized the app"
+		case err != nil:
+			results[i].err = err
+		default:
+			tokens[i] = token
+			pending = append(pending, i)
+		}
+	}
+
+	var retry []int
+	for len(pending) > 0 {
+		querier := pending[0]
+		ids := make([]string, len(pending))
+		for j, i := range pending {
+			ids[j] = members[i]
+		}
+
+		busy, _, err := m.Calendar.FreeBusy(ctx, tokens[querier], ids, opts.From, opts.To)
+		if errors.Is(err, ErrInvalidToken) {
+			results[querier].reason = "authorization expired or was revoked"
+			pending = pending[1:]
+			continue
+		}
+		if err != nil {
+			results[querier].err = err
+			return results
+		}
+
+		for _, i := range pending {
+			if periods, ok := busy[members[i]]; ok {
+				results[i].busy, _ = splitPeriods(periods)
+			} else {
+				retry = append(retry, i)
+			}
+		}
+		break
+	}
+
+	var wg sync.WaitGroup
+	for _, i := range retry {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			email := members[i]
+			busy, failed, err := m.Calendar.FreeBusy(ctx, tokens[i], []string{email}, opts.From, opts.To)
+			switch {
+			case errors.Is(err, ErrInvalidToken):
+				results[i].reason = "authorization expired or was revoked"
+			case err != nil:
+				results[i].err = err
+			case failed[email] != "":
+				results[i].reason = "calendar could not be read: " + failed[email]
+			default:
+				results[i].busy, _ = splitPeriods(busy[email])
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// attendeeBusy is the busy time of one attendee of a quorum search.
+type attendeeBusy struct {
+	email    string
+	required bool
+	busy     []interval
+}
+
+// quorumCandidate is a period and the attendees free for all of it.
+type quorumCandidate struct {
+	period    interval
+	available []bool
+}
+
+// quorumSlots finds the maximal periods, at least opts.MinDuration long and inside the
+// working windows, in which every required attendee and at least minAttendees attendees
+// in total are free. Periods that are contained in another one with a superset of free
+// attendees are dropped. The result is ordered by the number of free optional attendees,
+// then by start time.
+func quorumSlots(attendees []attendeeBusy, minAttendees int, opts SlotOptions) []QuorumSlot {
+	merged := make([][]interval, len(attendees))
+	for i, attendee := range attendees {
+		merged[i] = mergeIntervals(attendee.busy)
+	}
+
+	meets := func(available []bool) bool {
+		count := 0
+		for i, free := range available {
+			if free {
+				count++
+			} else if attendees[i].required {
+				return false
+			}
+		}
+		return count >= minAttendees
+	}
+
+	var candidates []quorumCandidate
+	for _, window := range freeIntervals(nil, opts) {
+		// Split the window at every busy boundary into segments with a fixed set of free attendees
+		points := []time.Time{window.start, window.end}
+		for _, busy := range merged {
+			for _, b := range busy {
+				if b.start.After(window.start) && b.start.Before(window.end) {
+					points = append(points, b.start)
+				}
+				if b.end.After(window.start) && b.end.Before(window.end) {
+					points = append(points, b.end)
+				}
+			}
+		}
+		sort.Slice(points, func(i, j int) bool { return points[i].Before(points[j]) })
+
+		var segments []quorumCandidate
+		for i := 0; i+1 < len(points); i++ {
+			if !points[i+1].After(points[i]) {
+				continue
+			}
+			segment := quorumCandidate{period: interval{start: points[i], end: points[i+1]}, available: make([]bool, len(attendees))}
+			for a := range attendees {
+				segment.available[a] = !overlapsAny(segment.period, merged[a])
+			}
+			segments = append(segments, segment)
+		}
+
+		// Grow a period from every segment, emitting it each time the set of free attendees shrinks
+		for i := range segments {
+			available := segments[i].available
+			if !meets(available) {
+				continue
+			}
+			for j := i; j < len(segments); j++ {
+				next := intersect(available, segments[j].available)
+				if !meets(next) {
+					candidates = appendCandidate(candidates, segments[i].period.start, segments[j-1].period.end, available, opts.MinDuration)
+					break
+				}
+				if !equalSets(next, available) {
+					candidates = appendCandidate(candidates, segments[i].period.start, segments[j-1].period.end, available, opts.MinDuration)
+					available = next
+				}
+				if j == len(segments)-1 {
+					candidates = appendCandidate(candidates, segments[i].period.start, segments[j].period.end, available, opts.MinDuration)
+				}
+			}
+		}
+	}
+
+	slots := []QuorumSlot{}
+	for i, candidate := range candidates {
+		if dominated(candidate, i, candidates) {
+			continue
+		}
+
+		slot := QuorumSlot{
+			Slot:      NewTimeSlot(candidate.period.start, candidate.period.end, SlotFree),
+			Attendees: []string{},
+			Missing:   []string{},
+		}
+		for a, free := range candidate.available {
+			if !free {
+				slot.Missing = append(slot.Missing, attendees[a].email)
+				continue
+			}
+			slot.Attendees = append(slot.Attendees, attendees[a].email)
+			if !attendees[a].required {
+				slot.AvailableOptional++
+			}
+		}
+		slots = append(slots, slot)
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool {
+		if slots[i].AvailableOptional != slots[j].AvailableOptional {
+			return slots[i].AvailableOptional > slots[j].AvailableOptional
+		}
+		return slots[i].Slot.Start.Before(slots[j].Slot.Start)
+	})
+
+	return slots
+}
+
+// appendCandidate appends the period from start to end if it lasts at least minDuration.
+func appendCandidate(candidates []quorumCandidate, start, end time.Time, available []bool, minDuration time.Duration) []quorumCandidate {
+	if end.Sub(start) < minDuration {
+		return candidates
+	}
+	return append(candidates, quorumCandidate{period: interval{start: start, end: end}, available: available})
+}
+
+// dominated reports whether candidates[index] lies within another candidate whose free
+// attendees include all of its own. Of two identical candidates only the first is kept.
+func dominated(candidate quorumCandidate, index int, candidates []quorumCandidate) bool {
+	for i, other := range candidates {
+		if i == index {
+			continue
+		}
+		if other.period.start.After(candidate.period.start) || other.period.end.Before(candidate.period.end) {
+			continue
+		}
+		if !equalSets(intersect(other.available, candidate.available), candidate.available) {
+			continue
+		}
+		identical := other.period.start.Equal(candidate.period.start) && other.period.end.Equal(candidate.period.end) && equalSets(other.available, candidate.available)
+		if !identical || i < index {
+			return true
+		}
+	}
+	return false
+}
+
+func intersect(a, b []bool) []bool {
+	out := make([]bool, len(a))
+	for i := range a {
+		out[i] = a[i] && b[i]
+	}
+	return out
+}
+
+func equalSets(a, b []bool) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
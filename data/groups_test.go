@@ -0,0 +1,123 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestQuorumSlots(t *testing.T) {
+	opts := SlotOptions{
+		From:        mustTime(t, "2024-05-06T09:00:00Z"),
+		To:          mustTime(t, "2024-05-06T13:00:00Z"),
+		MinDuration: time.Hour,
+		Location:    time.UTC,
+	}
+	attendees := []attendeeBusy{
+		{email: "lead@example.com", required: true, busy: []interval{
+			{start: mustTime(t, "2024-05-06T09:00:00Z"), end: mustTime(t, "2024-05-06T10:00:00Z")},
+		}},
+		{email: "anna@example.com", busy: []interval{
+			{start: mustTime(t, "2024-05-06T12:00:00Z"), end: mustTime(t, "2024-05-06T13:00:00Z")},
+		}},
+		{email: "bob@example.com", busy: []interval{
+			{start: mustTime(t, "2024-05-06T10:00:00Z"), end: mustTime(t, "2024-05-06T11:00:00Z")},
+		}},
+	}
+
+	slots := quorumSlots(attendees, 2, opts)
+
+	expected := []struct {
+		start, end string
+		optional   int
+		missing    []string
+	}{
+		{"2024-05-06T11:00:00Z", "2024-05-06T12:00:00Z", 2, nil},
+		{"2024-05-06T10:00:00Z", "2024-05-06T12:00:00Z", 1, []string{"bob@example.com"}},
+		{"2024-05-06T11:00:00Z", "2024-05-06T13:00:00Z", 1, []string{"anna@example.com"}},
+	}
+	if len(slots) != len(expected) {
+		t.Fatalf("expected %d slots, got %d: %+v", len(expected), len(slots), slots)
+	}
+	for i, e := range expected {
+		slot := slots[i]
+		if !slot.Slot.Start.Equal(mustTime(t, e.start)) || !slot.Slot.End.Equal(mustTime(t, e.end)) {
+			t.Errorf("slot %d: expected %s to %s, got %s to %s", i, e.start, e.end, slot.Slot.Start, slot.Slot.End)
+		}
+		if slot.AvailableOptional != e.optional {
+			t.Errorf("slot %d: expected %d optional attendees, got %d", i, e.optional, slot.AvailableOptional)
+		}
+		if len(slot.Missing) != len(e.missing) {
+			t.Errorf("slot %d: expected missing %v, got %v", i, e.missing, slot.Missing)
+			continue
+		}
+		for j := range e.missing {
+			if slot.Missing[j] != e.missing[j] {
+				t.Errorf("slot %d: expected missing %v, got %v", i, e.missing, slot.Missing)
+			}
+		}
+	}
+}
+
+func TestGetGroupFreeSlots(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	fake.AddBusy("anna@example.com", BusyPeriod{Start: day.Add(9 * time.Hour), End: day.Add(12 * time.Hour), Status: SlotBusy})
+	fake.AddBusy("bob@example.com", BusyPeriod{Start: day.Add(15 * time.Hour), End: day.Add(17 * time.Hour), Status: SlotTentative})
+	fake.Hide("bob@example.com", "bob-token")
+	fake.Revoke("aaron-token")
+
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WithArgs("design").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT user_email FROM user_groups WHERE group_name =`).
+		WithArgs("design").
+		WillReturnRows(sqlmock.NewRows([]string{"user_email"}).
+			AddRow("aaron@example.com").
+			AddRow("anna@example.com").
+			AddRow("bob@example.com").
+			AddRow("carol@example.com"))
+	expectDistribution(mock, "design", "")
+	expectToken(mock, "aaron@example.com", "aaron-token")
+	expectToken(mock, "anna@example.com", "anna-token")
+	expectToken(mock, "bob@example.com", "bob-token")
+	mock.ExpectQuery(`SELECT access_token, refresh_token, expiry FROM user_tokens WHERE email =`).
+		WithArgs("carol@example.com").
+		WillReturnError(sql.ErrNoRows)
+	expectHolds(mock)
+	expectPreferences(mock, "anna@example.com")
+	expectPreferences(mock, "bob@example.com")
+
+	availability, err := models.GetGroupFreeSlots(context.Background(), "design", SlotOptions{
+		From:        day,
+		To:          day.Add(24 * time.Hour),
+		MinDuration: 30 * time.Minute,
+		Location:    time.UTC,
+	}, Quorum{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(availability.Members) != 2 || availability.Members[0] != "anna@example.com" || availability.Members[1] != "bob@example.com" {
+		t.Errorf("unexpected members: %v", availability.Members)
+	}
+	if len(availability.Unavailable) != 2 || availability.Unavailable[0].Email != "aaron@example.com" || availability.Unavailable[1].Email != "carol@example.com" {
+		t.Errorf("unexpected unavailable members: %v", availability.Unavailable)
+	}
+	if len(availability.Slots) != 1 || !availability.Slots[0].Start.Equal(day.Add(12*time.Hour)) || !availability.Slots[0].End.Equal(day.Add(15*time.Hour)) {
+		t.Errorf("unexpected slots: %v", availability.Slots)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
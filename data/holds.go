@@ -0,0 +1,333 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrHoldNotFound is returned when no hold has the given ID.
+	ErrHoldNotFound = errors.New("hold not found")
+	// ErrHoldInactive is returned when confirming a hold that expired, was released
+	// or was already confirmed.
+	ErrHoldInactive = errors.New("hold is no longer active")
+	// ErrSlotHeld is returned when someone in a new hold is already held at that time.
+	ErrSlotHeld = errors.New("slot overlaps an active hold")
+)
+
+// Hold statuses recorded in the holds table.
+const (
+	HoldActive    = "active"
+	HoldConfirmed = "confirmed"
+	HoldExpired   = "expired"
+)
+
+// Hold is a short-lived reservation of a proposed slot. While it is active the slot
+// counts as busy for the organizer and the attendees in every availability search.
+type Hold struct {
+	ID          string    `json:"id" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	Organizer   string    `json:"organizer" example:"anna@example.com"`
+	Attendees   []string  `json:"attendees"`
+	Groups      []string  `json:"groups"`
+	Title       string    `json:"title" example:"Design sync"`
+	Description string    `json:"description,omitempty"`
+	Start       time.Time `json:"start" format:"date-time" example:"2024-05-06T10:00:00+02:00"`
+	End         time.Time `json:"end" format:"date-time" example:"2024-05-06T10:30:00+02:00"`
+	Status      string    `json:"status" enums:"active,confirmed,expired" example:"active"`
+	ExpiresAt   time.Time `json:"expires_at" format:"date-time" example:"2024-05-03T12:15:00Z"`
+	// EventID is the Google Calendar event the hold was confirmed into.
+	EventID string `json:"event_id,omitempty"`
+	// Host is the member held to host the meeting for a group that assigns hosts.
+	Host string `json:"host,omitempty" example:"bob@example.com"`
+	// assignment is the ID of the group assignment that chose Host.
+	assignment int
+}
+
+// CreateHold reserves the slot of req for ttl. The organizer needs write access, as
+// confirming the hold books the meeting on their calendar. Group names are expanded
+// into their members, except for a group with a distribution mode, whose host is
+// picked now and held alone. ErrSlotHeld is returned when the organizer or an
+// attendee already has an active hold overlapping the slot.
+func (m *Models) CreateHold(ctx context.Context, req MeetingRequest, ttl time.Duration) (*Hold, error) {
+	canWrite, err := m.HasScope(req.Organizer, writeScopes...)
+	if err != nil {
+		return nil, err
+	}
+	if !canWrite {
+		return nil, ErrWriteAccessRequired
+	}
+
+	groups, hostGroup, distribution, err := m.hostGroup(req.Groups)
+	if err != nil {
+		return nil, err
+	}
+	if hostGroup == "" {
+		return m.saveHold(req, req.Attendees, groups, ttl)
+	}
+
+	req.host, req.assignment, err = m.pickHost(ctx, hostGroup, distribution, interval{start: req.Start, end: req.End})
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err := m.saveHold(req, append(append([]string{}, req.Attendees...), req.host), groups, ttl)
+	if err != nil {
+		releaseErr := m.releaseHost(req.assignment)
+		if releaseErr != nil {
+			return nil, errors.Join(err, releaseErr)
+		}
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// saveHold records the hold of req for emails and the members of groups, unless
+// someone in it is already held at that time.
+func (m *Models) saveHold(req MeetingRequest, emails, groups []string, ttl time.Duration) (*Hold, error) {
+	attendees, err := m.resolveAttendees(req.Organizer, emails, groups)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+
+	hold := &Hold{
+		ID:          id,
+		Organizer:   req.Organizer,
+		Attendees:   attendees,
+		Groups:      append([]string{}, req.Groups...),
+		Host:        req.host,
+		assignment:  req.assignment,
+		Title:       req.Title,
+		Description: req.Description,
+		Start:       req.Start,
+		End:         req.End,
+		Status:      HoldActive,
+		ExpiresAt:   time.Now().Add(ttl).UTC(),
+	}
+	people := append([]string{hold.Organizer}, hold.Attendees...)
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queryHold := `
+		INSERT INTO holds (id, organizer, title, description, start_time, end_time, group_names, host, host_assignment, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err = tx.Exec(queryHold, hold.ID, hold.Organizer, hold.Title, hold.Description, hold.Start, hold.End,
+		strings.Join(hold.Groups, ","), hold.Host, hold.assignment, hold.Status, hold.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save hold: %w", err)
+	}
+
+	// Row locks cannot stop two holds for a free slot, as neither sees a row to lock, so
+	// holds for the same person serialize on an advisory lock held until commit. The
+	// locks are taken in order so that holds sharing several people cannot deadlock.
+	locked := append([]string{}, people...)
+	sort.Strings(locked)
+	for _, email := range locked {
+		_, err = tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock holds of %s: %w", email, err)
+		}
+	}
+
+	queryOverlap := `
+		SELECT a.email FROM hold_attendees a
+		JOIN holds h ON h.id = a.hold_id
+		WHERE a.email = $1 AND h.status = $2 AND h.expires_at > NOW()
+			AND h.start_time < $3 AND h.end_time > $4
+		LIMIT 1
+	`
+	queryAttendee := `INSERT INTO hold_attendees (hold_id, email) VALUES ($1, $2)`
+	for _, email := range people {
+		var held string
+		err = tx.QueryRow(queryOverlap, email, HoldActive, hold.End, hold.Start).Scan(&held)
+		if err == nil {
+			return nil, fmt.Errorf("%w: %s", ErrSlotHeld, held)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to check existing holds: %w", err)
+		}
+
+		_, err = tx.Exec(queryAttendee, hold.ID, email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save hold attendee: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return hold, nil
+}
+
+// GetHold returns the hold with id.
+func (m *Models) GetHold(id string) (*Hold, error) {
+	query := `
+		SELECT organizer, title, description, start_time, end_time, group_names, host, host_assignment, status, expires_at, COALESCE(event_id, '')
+		FROM holds WHERE id = $1
+	`
+
+	hold := &Hold{ID: id, Attendees: []string{}, Groups: []string{}}
+	var groups string
+	err := m.DB.QueryRow(query, id).Scan(&hold.Organizer, &hold.Title, &hold.Description, &hold.Start, &hold.End,
+		&groups, &hold.Host, &hold.assignment, &hold.Status, &hold.ExpiresAt, &hold.EventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+	if groups != "" {
+		hold.Groups = strings.Split(groups, ",")
+	}
+	if hold.Status == HoldActive && !hold.ExpiresAt.After(time.Now()) {
+		hold.Status = HoldExpired
+	}
+
+	rows, err := m.DB.Query(`SELECT email FROM hold_attendees WHERE hold_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hold attendees: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var email string
+		err := rows.Scan(&email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan hold attendee: %w", err)
+		}
+		if email != hold.Organizer {
+			hold.Attendees = append(hold.Attendees, email)
+		}
+	}
+
+	return hold, nil
+}
+
+// ConfirmHold turns the active hold with id into a meeting on behalf of actor, who
+// must be its organizer, and returns the meeting.
+func (m *Models) ConfirmHold(ctx context.Context, id, actor string) (*Meeting, error) {
+	hold, err := m.GetHold(id)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(actor, hold.Organizer) {
+		return nil, ErrNotAllowed
+	}
+	if hold.Status != HoldActive {
+		return nil, ErrHoldInactive
+	}
+
+	// Claim the hold before booking so that a second confirmation cannot book it again
+	var claimed string
+	queryClaim := `
+		UPDATE holds SET status = $1
+		WHERE id = $2 AND status = $3 AND expires_at > NOW()
+		RETURNING id
+	`
+	err = m.DB.QueryRow(queryClaim, HoldConfirmed, id, HoldActive).Scan(&claimed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrHoldInactive
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim hold: %w", err)
+	}
+
+	meeting, err := m.CreateMeeting(ctx, MeetingRequest{
+		Organizer:   hold.Organizer,
+		Attendees:   hold.Attendees,
+		Groups:      hold.Groups,
+		Title:       hold.Title,
+		Description: hold.Description,
+		Start:       hold.Start,
+		End:         hold.End,
+		host:        hold.Host,
+		assignment:  hold.assignment,
+	})
+	if err != nil {
+		// Hand the hold back so that the organizer can try again before it expires
+		_, releaseErr := m.DB.Exec(`UPDATE holds SET status = $1 WHERE id = $2`, HoldActive, id)
+		if releaseErr != nil {
+			return nil, errors.Join(err, fmt.Errorf("failed to release hold: %w", releaseErr))
+		}
+		return nil, err
+	}
+
+	_, err = m.DB.Exec(`UPDATE holds SET event_id = $1 WHERE id = $2`, meeting.EventID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update hold: %w", err)
+	}
+
+	return meeting, nil
+}
+
+// ReleaseExpiredHolds marks every active hold past its expiry as expired, handing the
+// hosts picked for them back to their groups, and returns how many were released.
+func (m *Models) ReleaseExpiredHolds() (int64, error) {
+	// Hosts picked for holds that were never confirmed go back to their group
+	queryHosts := `
+		DELETE FROM group_assignments WHERE id IN (
+			SELECT host_assignment FROM holds WHERE status = $1 AND expires_at <= NOW()
+		)
+	`
+	_, err := m.DB.Exec(queryHosts, HoldActive)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release hosts of expired holds: %w", err)
+	}
+
+	query := `UPDATE holds SET status = $1 WHERE status = $2 AND expires_at <= NOW()`
+	result, err := m.DB.Exec(query, HoldExpired, HoldActive)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release expired holds: %w", err)
+	}
+
+	released, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count released holds: %w", err)
+	}
+
+	return released, nil
+}
+
+// heldIntervals returns, per person, the slots of active holds overlapping from..to.
+func (m *Models) heldIntervals(from, to time.Time) (map[string][]interval, error) {
+	query := `
+		SELECT a.email, h.start_time, h.end_time FROM holds h
+		JOIN hold_attendees a ON a.hold_id = h.id
+		WHERE h.status = $1 AND h.expires_at > NOW() AND h.start_time < $2 AND h.end_time > $3
+	`
+	rows, err := m.DB.Query(query, HoldActive, to, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query holds: %w", err)
+	}
+	defer rows.Close()
+
+	held := map[string][]interval{}
+	for rows.Next() {
+		var email string
+		var start, end time.Time
+		err := rows.Scan(&email, &start, &end)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan hold: %w", err)
+		}
+		held[email] = append(held[email], interval{start: start, end: end})
+	}
+
+	return held, nil
+}
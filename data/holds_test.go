@@ -0,0 +1,313 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// heldSlot is a row of the active holds query.
+type heldSlot struct {
+	email      string
+	start, end time.Time
+}
+
+func expectHolds(mock sqlmock.Sqlmock, held ...heldSlot) {
+	rows := sqlmock.NewRows([]string{"email", "start_time", "end_time"})
+	for _, h := range held {
+		rows.AddRow(h.email, h.start, h.end)
+	}
+	mock.ExpectQuery(`SELECT a.email, h.start_time, h.end_time FROM holds`).
+		WithArgs(HoldActive, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(rows)
+}
+
+func expectHold(mock sqlmock.Sqlmock, id, organizer string, start, end, expiresAt time.Time, attendees []string) {
+	mock.ExpectQuery(`SELECT organizer, title, description, start_time, end_time, group_names, host, host_assignment, status, expires_at`).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"organizer", "title", "description", "start_time", "end_time", "group_names", "host", "host_assignment", "status", "expires_at", "event_id"}).
+			AddRow(organizer, "Design sync", "", start, end, "", "", 0, HoldActive, expiresAt, ""))
+
+	rows := sqlmock.NewRows([]string{"email"}).AddRow(organizer)
+	for _, email := range attendees {
+		rows.AddRow(email)
+	}
+	mock.ExpectQuery(`SELECT email FROM hold_attendees WHERE hold_id =`).WithArgs(id).WillReturnRows(rows)
+}
+
+func expectHoldLock(mock sqlmock.Sqlmock, email string) {
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(hashtext\(\$1\)\)`).WithArgs(email).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectHoldClaim(mock sqlmock.Sqlmock, id string, claimed bool) {
+	rows := sqlmock.NewRows([]string{"id"})
+	if claimed {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery(`UPDATE holds SET status =`).WithArgs(HoldConfirmed, id, HoldActive).WillReturnRows(rows)
+}
+
+func TestCreateHold(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+
+	organizer := "anna@example.com"
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	end := start.Add(30 * time.Minute)
+	noHold := sqlmock.NewRows([]string{"email"})
+
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO holds`).WillReturnResult(sqlmock.NewResult(1, 1))
+	expectHoldLock(mock, organizer)
+	expectHoldLock(mock, "bob@example.com")
+	mock.ExpectQuery(`SELECT a.email FROM hold_attendees`).WithArgs(organizer, HoldActive, end, start).WillReturnRows(noHold)
+	mock.ExpectExec(`INSERT INTO hold_attendees`).WithArgs(sqlmock.AnyArg(), organizer).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT a.email FROM hold_attendees`).WithArgs("bob@example.com", HoldActive, end, start).WillReturnRows(noHold)
+	mock.ExpectExec(`INSERT INTO hold_attendees`).WithArgs(sqlmock.AnyArg(), "bob@example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	hold, err := models.CreateHold(context.Background(), MeetingRequest{
+		Organizer: organizer,
+		Attendees: []string{"bob@example.com", organizer},
+		Title:     "Design sync",
+		Start:     start,
+		End:       end,
+	}, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if hold.ID == "" || hold.Status != HoldActive {
+		t.Errorf("expected an active hold with an id, got %+v", hold)
+	}
+	if len(hold.Attendees) != 1 || hold.Attendees[0] != "bob@example.com" {
+		t.Errorf("unexpected attendees: %v", hold.Attendees)
+	}
+	if until := time.Until(hold.ExpiresAt); until <= 14*time.Minute || until > 15*time.Minute {
+		t.Errorf("expected the hold to expire in 15 minutes, got %s", until)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCreateHoldRejectsOverlappingHold(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+
+	organizer := "anna@example.com"
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar")
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO holds`).WillReturnResult(sqlmock.NewResult(1, 1))
+	expectHoldLock(mock, organizer)
+	mock.ExpectQuery(`SELECT a.email FROM hold_attendees`).
+		WithArgs(organizer, HoldActive, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow(organizer))
+	mock.ExpectRollback()
+
+	_, err := models.CreateHold(context.Background(), MeetingRequest{
+		Organizer: organizer,
+		Title:     "Design sync",
+		Start:     start,
+		End:       start.Add(time.Hour),
+	}, time.Hour)
+	if !errors.Is(err, ErrSlotHeld) {
+		t.Fatalf("expected ErrSlotHeld, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestHoldDistributedGroup(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	organizer := "anna@example.com"
+	start := time.Date(2024, 5, 8, 10, 0, 0, 0, time.UTC)
+	end := start.Add(30 * time.Minute)
+	noHold := sqlmock.NewRows([]string{"email"})
+
+	// Only the next host of the round-robin group is held
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectDistribution(mock, "support", DistributeRoundRobin)
+	expectHostCandidates(mock, "support", "ann@example.com", "bob@example.com")
+	expectAssignment(mock, "support", historyRows().AddRow("ann@example.com", 1, start.Add(-time.Hour)), "bob@example.com")
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO holds`).
+		WithArgs(sqlmock.AnyArg(), organizer, "Support call", "", start, end, "support", "bob@example.com", 1, HoldActive, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectHoldLock(mock, organizer)
+	expectHoldLock(mock, "bob@example.com")
+	mock.ExpectQuery(`SELECT a.email FROM hold_attendees`).WithArgs(organizer, HoldActive, end, start).WillReturnRows(noHold)
+	mock.ExpectExec(`INSERT INTO hold_attendees`).WithArgs(sqlmock.AnyArg(), organizer).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT a.email FROM hold_attendees`).WithArgs("bob@example.com", HoldActive, end, start).WillReturnRows(noHold)
+	mock.ExpectExec(`INSERT INTO hold_attendees`).WithArgs(sqlmock.AnyArg(), "bob@example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	hold, err := models.CreateHold(context.Background(), MeetingRequest{
+		Organizer: organizer,
+		Groups:    []string{"support"},
+		Title:     "Support call",
+		Start:     start,
+		End:       end,
+	}, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hold.Host != "bob@example.com" || len(hold.Attendees) != 1 || hold.Attendees[0] != "bob@example.com" {
+		t.Errorf("expected only bob to be held, got host %q and %v", hold.Host, hold.Attendees)
+	}
+
+	// Confirming invites the held host without picking another one
+	mock.ExpectQuery(`SELECT organizer, title, description, start_time, end_time, group_names, host, host_assignment, status, expires_at`).
+		WithArgs(hold.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"organizer", "title", "description", "start_time", "end_time", "group_names", "host", "host_assignment", "status", "expires_at", "event_id"}).
+			AddRow(organizer, "Support call", "", start, end, "support", "bob@example.com", 1, HoldActive, hold.ExpiresAt, ""))
+	mock.ExpectQuery(`SELECT email FROM hold_attendees WHERE hold_id =`).
+		WithArgs(hold.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow(organizer).AddRow("bob@example.com"))
+	expectHoldClaim(mock, hold.ID, true)
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "anna-token")
+	expectDistribution(mock, "support", DistributeRoundRobin)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO meetings`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_attendees`).WithArgs("fake-event-1", "bob@example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_groups`).WithArgs("fake-event-1", "support").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE group_assignments SET event_id`).WithArgs("fake-event-1", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`UPDATE holds SET event_id =`).WithArgs("fake-event-1", hold.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	meeting, err := models.ConfirmHold(context.Background(), hold.ID, organizer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meeting.Host != "bob@example.com" || len(meeting.Attendees) != 1 || meeting.Attendees[0] != "bob@example.com" {
+		t.Errorf("expected only bob to be invited, got host %q and %v", meeting.Host, meeting.Attendees)
+	}
+	if events := fake.Events(organizer); len(events) != 1 || len(events[0].Attendees) != 1 {
+		t.Errorf("expected one event inviting bob, got %+v", events)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetFreeSlotsTreatsHoldsAsBusy(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	models.Calendar = NewFakeCalendar()
+
+	email := "anna@example.com"
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	expectToken(mock, email, "anna-token")
+	expectPreferences(mock, email)
+	expectHolds(mock,
+		heldSlot{email: email, start: day.Add(11 * time.Hour), end: day.Add(12 * time.Hour)},
+		heldSlot{email: "bob@example.com", start: day.Add(13 * time.Hour), end: day.Add(14 * time.Hour)},
+	)
+
+	slots, err := models.GetFreeSlots(context.Background(), email, SlotOptions{
+		From:        day,
+		To:          day.Add(24 * time.Hour),
+		MinDuration: 30 * time.Minute,
+		Location:    time.UTC,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(slots) != 2 || !slots[0].End.Equal(day.Add(11*time.Hour)) || !slots[1].Start.Equal(day.Add(12*time.Hour)) {
+		t.Errorf("expected the held hour to be busy, got %v", slots)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestConfirmHold(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	organizer := "anna@example.com"
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	end := start.Add(30 * time.Minute)
+
+	expectHold(mock, "hold-1", organizer, start, end, time.Now().Add(10*time.Minute), []string{"bob@example.com"})
+	expectHoldClaim(mock, "hold-1", true)
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "anna-token")
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO meetings`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_attendees`).WithArgs("fake-event-1", "bob@example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`UPDATE holds SET event_id =`).WithArgs("fake-event-1", "hold-1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	meeting, err := models.ConfirmHold(context.Background(), "hold-1", organizer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meeting.EventID != "fake-event-1" || !meeting.Start.Equal(start) {
+		t.Errorf("unexpected meeting: %+v", meeting)
+	}
+
+	// Only the organizer may confirm, and only while the hold is active
+	expectHold(mock, "hold-2", organizer, start, end, time.Now().Add(10*time.Minute), nil)
+	_, err = models.ConfirmHold(context.Background(), "hold-2", "bob@example.com")
+	if !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("expected ErrNotAllowed, got %v", err)
+	}
+
+	expectHold(mock, "hold-3", organizer, start, end, time.Now().Add(-time.Minute), nil)
+	_, err = models.ConfirmHold(context.Background(), "hold-3", organizer)
+	if !errors.Is(err, ErrHoldInactive) {
+		t.Errorf("expected ErrHoldInactive, got %v", err)
+	}
+
+	// A hold confirmed concurrently is only booked once
+	expectHold(mock, "hold-4", organizer, start, end, time.Now().Add(10*time.Minute), nil)
+	expectHoldClaim(mock, "hold-4", false)
+	_, err = models.ConfirmHold(context.Background(), "hold-4", organizer)
+	if !errors.Is(err, ErrHoldInactive) {
+		t.Errorf("expected ErrHoldInactive, got %v", err)
+	}
+	if events := fake.Events(organizer); len(events) != 1 {
+		t.Errorf("expected no second event, got %d events", len(events))
+	}
+
+	mock.ExpectQuery(`SELECT organizer, title`).WithArgs("missing").WillReturnError(sql.ErrNoRows)
+	_, err = models.ConfirmHold(context.Background(), "missing", organizer)
+	if !errors.Is(err, ErrHoldNotFound) {
+		t.Errorf("expected ErrHoldNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,662 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+)
+
+var (
+	// ErrWriteAccessRequired is returned when a user has only granted the service read
+	// access to their calendar and has to authorize it again to let it create events.
+	ErrWriteAccessRequired = errors.New("calendar write access has not been granted")
+	// ErrMeetingNotFound is returned when no meeting booked by the service has the event ID.
+	ErrMeetingNotFound = errors.New("meeting not found")
+	// ErrMeetingCancelled is returned when changing a meeting that was cancelled.
+	ErrMeetingCancelled = errors.New("meeting has been cancelled")
+	// ErrNotAllowed is returned when someone other than the organizer or an admin of
+	// one of the meeting's groups tries to change it.
+	ErrNotAllowed = errors.New("only the organizer or an admin of the meeting's groups may change it")
+	// ErrSeriesMove is returned when moving a recurring meeting, whose occurrences
+	// would each have to be checked again.
+	ErrSeriesMove = errors.New("a recurring meeting cannot be moved; cancel it and book the series again")
+)
+
+// Meeting statuses recorded in the meetings table.
+const (
+	MeetingScheduled   = "scheduled"
+	MeetingRescheduled = "rescheduled"
+	MeetingCancelled   = "cancelled"
+)
+
+// ConflictError is returned when attendees, or the meeting's room, are busy at the time
+// a meeting is moved to. Emails holds the room's calendar ID in that case.
+type ConflictError struct {
+	Emails []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("attendees are busy at the new time: %s", strings.Join(e.Emails, ", "))
+}
+
+// SeriesConflictError is returned when attendees are busy at some occurrences of a
+// recurring meeting.
+type SeriesConflictError struct {
+	Conflicts []OccurrenceConflict
+}
+
+func (e *SeriesConflictError) Error() string {
+	return fmt.Sprintf("attendees are busy at %d occurrences of the series", len(e.Conflicts))
+}
+
+// writeScopes are the OAuth scopes that let the service create events.
+var writeScopes = []string{calendar.CalendarEventsScope, calendar.CalendarScope}
+
+// MeetingRequest describes a meeting to put on the organizer's calendar.
+type MeetingRequest struct {
+	Organizer   string
+	Attendees   []string
+	Groups      []string
+	Title       string
+	Description string
+	Start       time.Time
+	End         time.Time
+	// Recurrence makes the meeting a series starting at Start, in the organizer's time zone.
+	Recurrence *Recurrence
+	// Force books a series even if some attendees are busy at some of its occurrences.
+	Force bool
+	// Room asks for a free room meeting the requirement to be booked with the meeting.
+	Room RoomRequirement
+	// host and assignment are the host already picked for the meeting by a hold, and
+	// the ID of their group assignment.
+	host       string
+	assignment int
+}
+
+// Meeting is a meeting on the organizer's calendar.
+type Meeting struct {
+	EventID     string    `json:"event_id" example:"5q8s0m7h2kq1m3b0f9g6v4c2pl"`
+	Organizer   string    `json:"organizer" example:"anna@example.com"`
+	Title       string    `json:"title" example:"Design sync"`
+	Description string    `json:"description,omitempty" example:"Weekly review of open design questions"`
+	Start       time.Time `json:"start" format:"date-time" example:"2024-05-06T10:00:00+02:00"`
+	End         time.Time `json:"end" format:"date-time" example:"2024-05-06T10:30:00+02:00"`
+	Attendees   []string  `json:"attendees"`
+	Groups      []string  `json:"groups"`
+	HTMLLink    string    `json:"html_link" example:"https://www.google.com/calendar/event?eid=NXE4czBt"`
+	MeetURL     string    `json:"meet_url" example:"https://meet.google.com/abc-defg-hij"`
+	Status      string    `json:"status" enums:"scheduled,rescheduled,cancelled" example:"scheduled"`
+	Recurrence  string    `json:"recurrence,omitempty" example:"RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=10"`
+	// Room is the calendar ID of the room booked for the meeting.
+	Room string `json:"room,omitempty" example:"c_1888abc@resource.calendar.google.com"`
+	// Host is the member chosen to host the meeting for a group that assigns hosts.
+	Host string `json:"host,omitempty" example:"bob@example.com"`
+	// assignment is the ID of the group assignment that chose Host.
+	assignment int
+	// PreviousStart and PreviousEnd are the times before the last reschedule.
+	PreviousStart *time.Time `json:"previous_start,omitempty" format:"date-time"`
+	PreviousEnd   *time.Time `json:"previous_end,omitempty" format:"date-time"`
+}
+
+// MeetingChange describes a change to a booked meeting. Zero fields are left as they are.
+type MeetingChange struct {
+	Title       string
+	Description string
+	Start       time.Time
+	End         time.Time
+	// Force moves the meeting even if some attendees or its room are busy at the new time.
+	Force bool
+}
+
+// CreateMeeting creates an event with a Google Meet conference on the organizer's
+// calendar and invites the attendees, including every member of the listed groups.
+// It returns ErrWriteAccessRequired when the organizer has not granted write access.
+// A recurring meeting is created as a single recurring event in the organizer's time
+// zone, after checking every occurrence: a *SeriesConflictError lists the occurrences
+// at which attendees are busy or outside their working hours, unless req.Force is set.
+// With a room requirement the smallest matching room that is free for the meeting, or
+// for every occurrence of a series, is added as a resource, or ErrNoRoomAvailable is
+// returned. Groups with a distribution mode send one host instead of every member.
+func (m *Models) CreateMeeting(ctx context.Context, req MeetingRequest) (*Meeting, error) {
+	token, err := m.writeToken(req.Organizer)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, hostGroup, distribution, err := m.hostGroup(req.Groups)
+	if err != nil {
+		return nil, err
+	}
+	if hostGroup == "" {
+		return m.bookMeeting(ctx, token, req, req.Attendees, groups, "", 0)
+	}
+
+	// The host of a confirmed hold was picked with the hold, which keeps the
+	// assignment if the meeting cannot be booked
+	if req.assignment != 0 {
+		emails := append(append([]string{}, req.Attendees...), req.host)
+		return m.bookMeeting(ctx, token, req, emails, groups, req.host, req.assignment)
+	}
+
+	host, assignment, err := m.pickHost(ctx, hostGroup, distribution, interval{start: req.Start, end: req.End})
+	if err != nil {
+		return nil, err
+	}
+
+	meeting, err := m.bookMeeting(ctx, token, req, append(append([]string{}, req.Attendees...), host), groups, host, assignment)
+	if err != nil {
+		// The host was reserved for this meeting only
+		releaseErr := m.releaseHost(assignment)
+		if releaseErr != nil {
+			return nil, errors.Join(err, releaseErr)
+		}
+		return nil, err
+	}
+
+	return meeting, nil
+}
+
+// hostGroup splits groups into those whose members are all invited and the one group,
+// if any, with a distribution mode, which sends a single host.
+func (m *Models) hostGroup(groups []string) ([]string, string, *Distribution, error) {
+	invited := []string{}
+	var hostGroup string
+	var hostDistribution *Distribution
+	for _, group := range groups {
+		distribution, err := m.GroupDistribution(group)
+		if errors.Is(err, ErrGroupNotFound) {
+			return nil, "", nil, fmt.Errorf("%w: %s", ErrGroupNotFound, group)
+		}
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if distribution.IsZero() {
+			invited = append(invited, group)
+			continue
+		}
+		if hostGroup != "" {
+			return nil, "", nil, fmt.Errorf("%w: only one group of a meeting can assign a host", ErrInvalidDistribution)
+		}
+		hostGroup, hostDistribution = group, distribution
+	}
+
+	return invited, hostGroup, hostDistribution, nil
+}
+
+// bookMeeting creates the event of req for emails and the members of groups, once
+// CreateMeeting has chosen its host, and records the meeting.
+func (m *Models) bookMeeting(ctx context.Context, token *oauth2.Token, req MeetingRequest, emails, groups []string, host string, assignment int) (*Meeting, error) {
+	attendees, err := m.resolveAttendees(req.Organizer, emails, groups)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &Event{
+		Title:       req.Title,
+		Description: req.Description,
+		Start:       req.Start,
+		End:         req.End,
+		Attendees:   attendees,
+		Conference:  true,
+	}
+	slots := []interval{{start: req.Start, end: req.End}}
+
+	if req.Recurrence != nil {
+		prefs, err := m.GetPreferences(req.Organizer)
+		if err != nil {
+			return nil, err
+		}
+		loc, err := time.LoadLocation(prefs.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load time zone of %s: %w", req.Organizer, err)
+		}
+
+		occurrences, err := req.Recurrence.Occurrences(req.Start.In(loc))
+		if err != nil {
+			return nil, err
+		}
+
+		if !req.Force {
+			people := append([]string{req.Organizer}, attendees...)
+			conflicts, err := m.seriesConflicts(ctx, people, occurrences, req.End.Sub(req.Start), loc)
+			if err != nil {
+				return nil, err
+			}
+			if len(conflicts) > 0 {
+				return nil, &SeriesConflictError{Conflicts: conflicts}
+			}
+		}
+
+		event.Recurrence = []string{req.Recurrence.String()}
+		event.TimeZone = prefs.TimeZone
+
+		slots = slots[:0]
+		for _, start := range occurrences {
+			slots = append(slots, interval{start: start, end: start.Add(req.End.Sub(req.Start))})
+		}
+	}
+
+	if !req.Room.IsZero() {
+		room, err := m.pickRoom(ctx, token, req.Room, slots)
+		if err != nil {
+			return nil, err
+		}
+		event.Resources = []string{room.CalendarID}
+	}
+
+	event, err = m.Calendar.CreateEvent(ctx, token, req.Organizer, event)
+	if err != nil {
+		return nil, err
+	}
+
+	meeting := meetingFromEvent(req.Organizer, event)
+	meeting.Groups = append([]string{}, req.Groups...)
+	meeting.Status = MeetingScheduled
+	if req.Recurrence != nil {
+		meeting.Recurrence = req.Recurrence.String()
+	}
+	if len(event.Resources) > 0 {
+		meeting.Room = event.Resources[0]
+	}
+	meeting.Host, meeting.assignment = host, assignment
+
+	err = m.saveMeeting(meeting)
+	if err != nil {
+		// Do not leave an event behind that the service cannot manage
+		m.Calendar.DeleteEvent(ctx, token, req.Organizer, event.ID)
+		return nil, err
+	}
+
+	return meeting, nil
+}
+
+// saveMeeting records a newly booked meeting with its attendees and groups.
+func (m *Models) saveMeeting(meeting *Meeting) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queryMeeting := `
+		INSERT INTO meetings (event_id, organizer, title, description, start_time, end_time, status, html_link, meet_url, recurrence, room, host)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	_, err = tx.Exec(queryMeeting, meeting.EventID, meeting.Organizer, meeting.Title, meeting.Description,
+		meeting.Start, meeting.End, meeting.Status, meeting.HTMLLink, meeting.MeetURL, meeting.Recurrence, meeting.Room, meeting.Host)
+	if err != nil {
+		return fmt.Errorf("failed to save meeting: %w", err)
+	}
+
+	queryAttendee := `INSERT INTO meeting_attendees (event_id, email) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	for _, email := range meeting.Attendees {
+		_, err = tx.Exec(queryAttendee, meeting.EventID, email)
+		if err != nil {
+			return fmt.Errorf("failed to save meeting attendee: %w", err)
+		}
+	}
+
+	queryGroup := `INSERT INTO meeting_groups (event_id, group_name) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	for _, group := range meeting.Groups {
+		_, err = tx.Exec(queryGroup, meeting.EventID, group)
+		if err != nil {
+			return fmt.Errorf("failed to save meeting group: %w", err)
+		}
+	}
+
+	if meeting.assignment != 0 {
+		queryAssignment := `UPDATE group_assignments SET event_id = $1 WHERE id = $2`
+		_, err = tx.Exec(queryAssignment, meeting.EventID, meeting.assignment)
+		if err != nil {
+			return fmt.Errorf("failed to save host assignment: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetMeeting returns the meeting booked by the service with the Google event ID eventID.
+func (m *Models) GetMeeting(eventID string) (*Meeting, error) {
+	query := `
+		SELECT organizer, title, description, start_time, end_time, status, previous_start, previous_end, html_link, meet_url, recurrence, room, host
+		FROM meetings WHERE event_id = $1
+	`
+
+	meeting := &Meeting{EventID: eventID, Attendees: []string{}, Groups: []string{}}
+	var previousStart, previousEnd sql.NullTime
+	err := m.DB.QueryRow(query, eventID).Scan(&meeting.Organizer, &meeting.Title, &meeting.Description,
+		&meeting.Start, &meeting.End, &meeting.Status, &previousStart, &previousEnd, &meeting.HTMLLink, &meeting.MeetURL, &meeting.Recurrence, &meeting.Room, &meeting.Host)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMeetingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get meeting: %w", err)
+	}
+	if previousStart.Valid && previousEnd.Valid {
+		meeting.PreviousStart, meeting.PreviousEnd = &previousStart.Time, &previousEnd.Time
+	}
+
+	meeting.Attendees, err = m.meetingList(`SELECT email FROM meeting_attendees WHERE event_id = $1 ORDER BY id`, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get meeting attendees: %w", err)
+	}
+
+	meeting.Groups, err = m.meetingList(`SELECT group_name FROM meeting_groups WHERE event_id = $1 ORDER BY id`, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get meeting groups: %w", err)
+	}
+
+	return meeting, nil
+}
+
+// meetingList runs a query returning one string column for eventID.
+func (m *Models) meetingList(query, eventID string) ([]string, error) {
+	rows, err := m.DB.Query(query, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		err := rows.Scan(&value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// authorizeMeetingChange returns ErrNotAllowed unless actor organized meeting or is an
+// admin of one of the groups it was booked for, comparing emails regardless of case as
+// requireActor does when meetings are booked. actor is verified for session tokens
+// but only asserted by API keys, which are trusted to act for any user.
+func (m *Models) authorizeMeetingChange(meeting *Meeting, actor string) error {
+	if strings.EqualFold(actor, meeting.Organizer) {
+		return nil
+	}
+
+	admin, err := m.IsGroupAdmin(actor, meeting.Groups)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return ErrNotAllowed
+	}
+
+	return nil
+}
+
+// RescheduleMeeting applies change to the meeting with eventID on behalf of actor and
+// notifies the attendees. When the meeting moves, the attendees' availability at the
+// new time is checked first and a *ConflictError is returned if some are busy, or if
+// the meeting's room is taken, unless change.Force is set. The previous times are kept
+// in the meeting's record. A recurring meeting can be renamed or described but not
+// moved, which returns ErrSeriesMove.
+func (m *Models) RescheduleMeeting(ctx context.Context, eventID, actor string, change MeetingChange) (*Meeting, error) {
+	meeting, err := m.GetMeeting(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if meeting.Status == MeetingCancelled {
+		return nil, ErrMeetingCancelled
+	}
+
+	err = m.authorizeMeetingChange(meeting, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := m.writeToken(meeting.Organizer)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := meeting.Start, meeting.End
+	if !change.Start.IsZero() {
+		start = change.Start
+	}
+	if !change.End.IsZero() {
+		end = change.End
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+	moved := !start.Equal(meeting.Start) || !end.Equal(meeting.End)
+	if moved && meeting.Recurrence != "" {
+		return nil, ErrSeriesMove
+	}
+
+	update := &Event{
+		ID:          eventID,
+		Title:       change.Title,
+		Description: change.Description,
+	}
+	if moved {
+		current, next := interval{start: meeting.Start, end: meeting.End}, interval{start: start, end: end}
+		if !change.Force {
+			people := append([]string{meeting.Organizer}, meeting.Attendees...)
+			conflicts, err := m.attendeeConflicts(ctx, people, current, next)
+			if err != nil {
+				return nil, err
+			}
+			if meeting.Room != "" {
+				taken, err := m.roomTaken(ctx, token, meeting.Room, current, next)
+				if err != nil {
+					return nil, err
+				}
+				if taken {
+					conflicts = append(conflicts, meeting.Room)
+				}
+			}
+			if len(conflicts) > 0 {
+				return nil, &ConflictError{Emails: conflicts}
+			}
+		}
+		update.Start, update.End = start, end
+	}
+
+	event, err := m.Calendar.UpdateEvent(ctx, token, meeting.Organizer, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if moved {
+		previousStart, previousEnd := meeting.Start, meeting.End
+		meeting.PreviousStart, meeting.PreviousEnd = &previousStart, &previousEnd
+		meeting.Status = MeetingRescheduled
+	}
+	meeting.Title, meeting.Description = event.Title, event.Description
+	meeting.Start, meeting.End = start, end
+
+	query := `
+		UPDATE meetings
+		SET title = $1, description = $2, start_time = $3, end_time = $4, status = $5,
+			previous_start = $6, previous_end = $7, updated_at = NOW()
+		WHERE event_id = $8
+	`
+	_, err = m.DB.Exec(query, meeting.Title, meeting.Description, meeting.Start, meeting.End, meeting.Status,
+		meeting.PreviousStart, meeting.PreviousEnd, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update meeting: %w", err)
+	}
+
+	return meeting, nil
+}
+
+// CancelMeeting deletes the meeting with eventID from the organizer's calendar on behalf
+// of actor, notifying the attendees, and marks it as cancelled.
+func (m *Models) CancelMeeting(ctx context.Context, eventID, actor string) (*Meeting, error) {
+	meeting, err := m.GetMeeting(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if meeting.Status == MeetingCancelled {
+		return nil, ErrMeetingCancelled
+	}
+
+	err = m.authorizeMeetingChange(meeting, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := m.writeToken(meeting.Organizer)
+	if err != nil {
+		return nil, err
+	}
+
+	// An event already removed in Google Calendar only needs its record updated
+	err = m.Calendar.DeleteEvent(ctx, token, meeting.Organizer, eventID)
+	if err != nil && !errors.Is(err, ErrEventNotFound) {
+		return nil, err
+	}
+
+	meeting.Status = MeetingCancelled
+	query := `UPDATE meetings SET status = $1, updated_at = NOW() WHERE event_id = $2`
+	_, err = m.DB.Exec(query, meeting.Status, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update meeting: %w", err)
+	}
+
+	return meeting, nil
+}
+
+// attendeeConflicts returns the people who are busy during next. Busy time within
+// current, the meeting's own slot, is ignored so that a meeting can be shifted into a
+// time that overlaps its old one. People whose calendars cannot be read are skipped.
+func (m *Models) attendeeConflicts(ctx context.Context, people []string, current, next interval) ([]string, error) {
+	results := m.membersBusy(ctx, people, SlotOptions{From: next.start, To: next.end})
+
+	var conflicts []string
+	for i, result := range results {
+		if result.err != nil {
+			return nil, fmt.Errorf("failed to read calendar of %s: %w", people[i], result.err)
+		}
+		for _, busy := range result.busy {
+			if overlapsAny(next, subtractInterval(busy, current)) {
+				conflicts = append(conflicts, people[i])
+				break
+			}
+		}
+	}
+
+	return conflicts, nil
+}
+
+// roomTaken reports whether room is booked during next, apart from current, the
+// meeting's own slot. A room whose calendar cannot be read is treated as free.
+func (m *Models) roomTaken(ctx context.Context, token *oauth2.Token, room string, current, next interval) (bool, error) {
+	busy, err := m.roomsBusy(ctx, token, []Resource{{CalendarID: room}}, next.start, next.end)
+	if err != nil {
+		return false, err
+	}
+	for _, period := range busy[room] {
+		if overlapsAny(next, subtractInterval(period, current)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// subtractInterval returns the parts of period that lie outside cut.
+func subtractInterval(period, cut interval) []interval {
+	var parts []interval
+	if period.start.Before(cut.start) {
+		parts = append(parts, interval{start: period.start, end: minTime(period.end, cut.start)})
+	}
+	if period.end.After(cut.end) {
+		parts = append(parts, interval{start: maxTime(period.start, cut.end), end: period.end})
+	}
+	return parts
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// writeToken returns the token of email after checking that it may create events.
+func (m *Models) writeToken(email string) (*oauth2.Token, error) {
+	canWrite, err := m.HasScope(email, writeScopes...)
+	if err != nil {
+		return nil, err
+	}
+	if !canWrite {
+		return nil, ErrWriteAccessRequired
+	}
+
+	return m.GetUserToken(email)
+}
+
+// resolveAttendees expands groups into their members and merges them with emails,
+// dropping duplicates and the organizer, who owns the event.
+func (m *Models) resolveAttendees(organizer string, emails, groups []string) ([]string, error) {
+	attendees := []string{}
+	add := func(email string) {
+		if email != organizer && !contains(attendees, email) {
+			attendees = append(attendees, email)
+		}
+	}
+
+	for _, email := range emails {
+		add(email)
+	}
+	for _, group := range groups {
+		members, err := m.GroupMembers(group)
+		if errors.Is(err, ErrGroupNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, group)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, email := range members {
+			add(email)
+		}
+	}
+
+	return attendees, nil
+}
+
+// meetingFromEvent describes the event organized by organizer as a Meeting.
+func meetingFromEvent(organizer string, event *Event) *Meeting {
+	attendees := event.Attendees
+	if attendees == nil {
+		attendees = []string{}
+	}
+
+	return &Meeting{
+		Groups:      []string{},
+		EventID:     event.ID,
+		Organizer:   organizer,
+		Title:       event.Title,
+		Description: event.Description,
+		Start:       event.Start,
+		End:         event.End,
+		Attendees:   attendees,
+		HTMLLink:    event.HTMLLink,
+		MeetURL:     event.MeetURL,
+	}
+}
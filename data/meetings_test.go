@@ -0,0 +1,387 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"golang.org/x/oauth2"
+)
+
+func expectScopes(mock sqlmock.Sqlmock, email, scopes string) {
+	mock.ExpectQuery(`SELECT scopes FROM user_tokens WHERE email =`).
+		WithArgs(email).
+		WillReturnRows(sqlmock.NewRows([]string{"scopes"}).AddRow(scopes))
+}
+
+func TestCreateMeeting(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	organizer := "anna@example.com"
+	expectScopes(mock, organizer, "openid email https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "anna-token")
+	expectDistribution(mock, "design", "")
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WithArgs("design").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT user_email FROM user_groups WHERE group_name =`).
+		WithArgs("design").
+		WillReturnRows(sqlmock.NewRows([]string{"user_email"}).
+			AddRow("anna@example.com").
+			AddRow("bob@example.com").
+			AddRow("carol@example.com"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO meetings`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_attendees`).WithArgs("fake-event-1", "bob@example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_attendees`).WithArgs("fake-event-1", "partner@example.org").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_attendees`).WithArgs("fake-event-1", "carol@example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_groups`).WithArgs("fake-event-1", "design").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	meeting, err := models.CreateMeeting(context.Background(), MeetingRequest{
+		Organizer: organizer,
+		Attendees: []string{"bob@example.com", "partner@example.org"},
+		Groups:    []string{"design"},
+		Title:     "Design sync",
+		Start:     start,
+		End:       start.Add(30 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if meeting.EventID == "" || meeting.MeetURL == "" || meeting.HTMLLink == "" {
+		t.Errorf("expected event id and links, got %+v", meeting)
+	}
+	expected := []string{"bob@example.com", "partner@example.org", "carol@example.com"}
+	if len(meeting.Attendees) != len(expected) {
+		t.Fatalf("expected attendees %v, got %v", expected, meeting.Attendees)
+	}
+	for i := range expected {
+		if meeting.Attendees[i] != expected[i] {
+			t.Errorf("expected attendees %v, got %v", expected, meeting.Attendees)
+		}
+	}
+	if events := fake.Events(organizer); len(events) != 1 || !events[0].Start.Equal(start) {
+		t.Errorf("expected the event on the organizer's calendar, got %v", events)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCreateMeetingRequiresWriteAccess(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	models.Calendar = NewFakeCalendar()
+
+	expectScopes(mock, "anna@example.com", "openid email https://www.googleapis.com/auth/calendar.readonly")
+
+	_, err := models.CreateMeeting(context.Background(), MeetingRequest{Organizer: "anna@example.com"})
+	if !errors.Is(err, ErrWriteAccessRequired) {
+		t.Fatalf("expected ErrWriteAccessRequired, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCreateRecurringMeetingReportsConflicts(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	organizer, attendee := "anna@example.com", "bob@example.com"
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	// Bob is busy during the second occurrence
+	fake.AddBusy(attendee, BusyPeriod{Start: start.AddDate(0, 0, 7), End: start.AddDate(0, 0, 7).Add(time.Hour), Status: SlotBusy})
+
+	rule, err := ParseRecurrence("RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req := MeetingRequest{
+		Organizer:  organizer,
+		Attendees:  []string{attendee},
+		Title:      "Design sync",
+		Start:      start,
+		End:        start.Add(30 * time.Minute),
+		Recurrence: rule,
+	}
+
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "anna-token")
+	expectPreferences(mock, organizer)
+	expectToken(mock, organizer, "anna-token")
+	expectToken(mock, attendee, "bob-token")
+	expectPreferences(mock, organizer)
+	expectPreferences(mock, attendee)
+
+	_, err = models.CreateMeeting(context.Background(), req)
+	var conflict *SeriesConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a SeriesConflictError, got %v", err)
+	}
+	if len(conflict.Conflicts) != 1 {
+		t.Fatalf("expected one conflicting occurrence, got %+v", conflict.Conflicts)
+	}
+	occurrence := conflict.Conflicts[0]
+	if !occurrence.Start.Equal(start.AddDate(0, 0, 7)) || len(occurrence.Busy) != 1 || occurrence.Busy[0] != attendee {
+		t.Errorf("expected bob to be busy on the second occurrence, got %+v", occurrence)
+	}
+	// The closest free times on the same day, within working hours
+	expected := []time.Time{
+		time.Date(2024, 5, 13, 9, 30, 0, 0, time.UTC),
+		time.Date(2024, 5, 13, 9, 15, 0, 0, time.UTC),
+		time.Date(2024, 5, 13, 9, 0, 0, 0, time.UTC),
+	}
+	if len(occurrence.Alternatives) != len(expected) {
+		t.Fatalf("expected alternatives at %v, got %+v", expected, occurrence.Alternatives)
+	}
+	for i := range expected {
+		if !occurrence.Alternatives[i].Start.Equal(expected[i]) {
+			t.Errorf("expected alternatives at %v, got %+v", expected, occurrence.Alternatives)
+		}
+	}
+	if events := fake.Events(organizer); len(events) != 0 {
+		t.Errorf("expected no event before the conflicts are resolved, got %v", events)
+	}
+
+	// Forcing the series creates a single recurring event
+	req.Force = true
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "anna-token")
+	expectPreferences(mock, organizer)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO meetings`).
+		WithArgs("fake-event-1", organizer, "Design sync", "", start, start.Add(30*time.Minute), MeetingScheduled,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), "RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=3", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_attendees`).WithArgs("fake-event-1", attendee).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	meeting, err := models.CreateMeeting(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meeting.Recurrence != "RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=3" {
+		t.Errorf("expected the recurrence on the meeting, got %q", meeting.Recurrence)
+	}
+	events := fake.Events(organizer)
+	if len(events) != 1 || len(events[0].Recurrence) != 1 || events[0].TimeZone != "UTC" {
+		t.Fatalf("expected one recurring event, got %+v", events)
+	}
+	if busy := fake.periods(organizer, start.AddDate(0, 0, 14), start.AddDate(0, 0, 15)); len(busy) != 1 {
+		t.Errorf("expected the third occurrence on the organizer's calendar, got %v", busy)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func expectMeeting(mock sqlmock.Sqlmock, eventID, organizer string, start, end time.Time, attendees, groups []string) {
+	mock.ExpectQuery(`SELECT organizer, title, description, start_time, end_time, status`).
+		WithArgs(eventID).
+		WillReturnRows(sqlmock.NewRows([]string{"organizer", "title", "description", "start_time", "end_time", "status", "previous_start", "previous_end", "html_link", "meet_url", "recurrence", "room", "host"}).
+			AddRow(organizer, "Design sync", "", start, end, MeetingScheduled, nil, nil, "", "", "", "", ""))
+	attendeeRows := sqlmock.NewRows([]string{"email"})
+	for _, email := range attendees {
+		attendeeRows.AddRow(email)
+	}
+	mock.ExpectQuery(`SELECT email FROM meeting_attendees`).WithArgs(eventID).WillReturnRows(attendeeRows)
+	groupRows := sqlmock.NewRows([]string{"group_name"})
+	for _, group := range groups {
+		groupRows.AddRow(group)
+	}
+	mock.ExpectQuery(`SELECT group_name FROM meeting_groups`).WithArgs(eventID).WillReturnRows(groupRows)
+}
+
+func TestRescheduleMeetingReportsConflicts(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	organizer := "anna@example.com"
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	event, _ := fake.CreateEvent(context.Background(), &oauth2.Token{AccessToken: "anna-token"}, organizer, &Event{
+		Title: "Design sync", Start: start, End: start.Add(time.Hour), Attendees: []string{"bob@example.com"},
+	})
+	fake.AddBusy("bob@example.com", BusyPeriod{Start: start.Add(time.Hour), End: start.Add(2 * time.Hour), Status: SlotBusy})
+
+	expectMeeting(mock, event.ID, organizer, start, start.Add(time.Hour), []string{"bob@example.com"}, []string{"design"})
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "anna-token")
+	expectToken(mock, organizer, "anna-token")
+	expectToken(mock, "bob@example.com", "bob-token")
+
+	// Moving by half an hour overlaps the meeting's own slot, which is fine, and Bob's next meeting, which is not
+	_, err := models.RescheduleMeeting(context.Background(), event.ID, organizer, MeetingChange{
+		Start: start.Add(30 * time.Minute),
+		End:   start.Add(90 * time.Minute),
+	})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a ConflictError, got %v", err)
+	}
+	if len(conflict.Emails) != 1 || conflict.Emails[0] != "bob@example.com" {
+		t.Errorf("expected bob to conflict, got %v", conflict.Emails)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+// expectStoredMeeting expects a meeting without attendees or groups to be read with
+// its recurrence and room.
+func expectStoredMeeting(mock sqlmock.Sqlmock, eventID, organizer string, start, end time.Time, recurrence, room string) {
+	mock.ExpectQuery(`SELECT organizer, title, description, start_time, end_time, status`).
+		WithArgs(eventID).
+		WillReturnRows(sqlmock.NewRows([]string{"organizer", "title", "description", "start_time", "end_time", "status", "previous_start", "previous_end", "html_link", "meet_url", "recurrence", "room", "host"}).
+			AddRow(organizer, "Design sync", "", start, end, MeetingScheduled, nil, nil, "", "", recurrence, room, ""))
+	mock.ExpectQuery(`SELECT email FROM meeting_attendees`).WithArgs(eventID).WillReturnRows(sqlmock.NewRows([]string{"email"}))
+	mock.ExpectQuery(`SELECT group_name FROM meeting_groups`).WithArgs(eventID).WillReturnRows(sqlmock.NewRows([]string{"group_name"}))
+}
+
+func TestRescheduleMeetingReportsTakenRoom(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	organizer := "anna@example.com"
+	room := "c_1888abc@resource.calendar.google.com"
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	event, _ := fake.CreateEvent(context.Background(), &oauth2.Token{AccessToken: "anna-token"}, organizer, &Event{
+		Title: "Design sync", Start: start, End: start.Add(time.Hour), Resources: []string{room},
+	})
+	fake.AddBusy(room, BusyPeriod{Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour), Status: SlotBusy})
+
+	expectStoredMeeting(mock, event.ID, organizer, start, start.Add(time.Hour), "", room)
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "anna-token")
+	expectToken(mock, organizer, "anna-token")
+
+	// The room's own booking of the meeting does not count, but the next booking does
+	_, err := models.RescheduleMeeting(context.Background(), event.ID, organizer, MeetingChange{
+		Start: start.Add(90 * time.Minute),
+		End:   start.Add(150 * time.Minute),
+	})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a ConflictError, got %v", err)
+	}
+	if len(conflict.Emails) != 1 || conflict.Emails[0] != room {
+		t.Errorf("expected the room to conflict, got %v", conflict.Emails)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRescheduleMeetingSeries(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	organizer := "anna@example.com"
+	rule := "RRULE:FREQ=WEEKLY;COUNT=4"
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	event, _ := fake.CreateEvent(context.Background(), &oauth2.Token{AccessToken: "anna-token"}, organizer, &Event{
+		Title: "Design sync", Start: start, End: start.Add(time.Hour), Recurrence: []string{rule}, TimeZone: "Europe/Warsaw",
+	})
+
+	// A series is not moved, as only its first occurrence would be checked
+	expectStoredMeeting(mock, event.ID, organizer, start, start.Add(time.Hour), rule, "")
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "anna-token")
+
+	_, err := models.RescheduleMeeting(context.Background(), event.ID, organizer, MeetingChange{
+		Start: start.Add(time.Hour),
+		End:   start.Add(2 * time.Hour),
+	})
+	if !errors.Is(err, ErrSeriesMove) {
+		t.Fatalf("expected ErrSeriesMove, got %v", err)
+	}
+
+	// Renaming it leaves the times and the rule alone
+	expectStoredMeeting(mock, event.ID, organizer, start, start.Add(time.Hour), rule, "")
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "anna-token")
+	mock.ExpectExec(`UPDATE meetings`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	meeting, err := models.RescheduleMeeting(context.Background(), event.ID, organizer, MeetingChange{Title: "Design review"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meeting.Title != "Design review" || meeting.Status != MeetingScheduled {
+		t.Errorf("unexpected meeting: %+v", meeting)
+	}
+	stored := fake.Events(organizer)[0]
+	if !stored.Start.Equal(start) || len(stored.Recurrence) != 1 || stored.TimeZone != "Europe/Warsaw" {
+		t.Errorf("expected the series to keep its times, got %+v", stored)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCancelMeetingRequiresOrganizerOrAdmin(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	models.Calendar = NewFakeCalendar()
+
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	expectMeeting(mock, "event-1", "anna@example.com", start, start.Add(time.Hour), []string{"bob@example.com"}, []string{"design"})
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM user_groups`).
+		WithArgs("bob@example.com", "design", RoleAdmin).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	_, err := models.CancelMeeting(context.Background(), "event-1", "bob@example.com")
+	if !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("expected ErrNotAllowed, got %v", err)
+	}
+
+	// The organizer is recognized whatever the case of their email
+	expectMeeting(mock, "event-1", "anna@example.com", start, start.Add(time.Hour), []string{"bob@example.com"}, []string{"design"})
+	expectScopes(mock, "anna@example.com", "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, "anna@example.com", "anna-token")
+	mock.ExpectExec(`UPDATE meetings SET status`).WithArgs(MeetingCancelled, "event-1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = models.CancelMeeting(context.Background(), "event-1", "Anna@Example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
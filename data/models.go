@@ -3,38 +3,98 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/calendar/v3"
-	"google.golang.org/api/option"
 )
 
-var oauthConfig = &oauth2.Config{
-	ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-	ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-	RedirectURL:  "http://localhost:80/oauth2callback",
-	Scopes:       []string{calendar.CalendarReadonlyScope},
-	Endpoint:     google.Endpoint,
-}
+var (
+	// ErrTokenNotFound is returned when no calendar token is stored for a user.
+	ErrTokenNotFound = errors.New("no token stored for user")
+	// ErrGroupNotFound is returned when a group does not exist.
+	ErrGroupNotFound = errors.New("group not found")
+)
 
 type Models struct {
-	DB *sql.DB
+	DB       *sql.DB
+	Calendar CalendarProvider
+}
+
+// SlotOptions describes the time window and constraints of a free-slot search.
+type SlotOptions struct {
+	From        time.Time
+	To          time.Time
+	MinDuration time.Duration
+	Location    *time.Location
+	// IgnoreAllDay stops all-day events from blocking their days, for calendars
+	// where they are used for birthdays or reminders rather than absences.
+	IgnoreAllDay bool
+	// Schedule holds the working hours slots must fall in. Without one every day is
+	// worked from nine to five in Location.
+	Schedule *Schedule
+	// Overrides replace the booking constraints from the preferences of the people
+	// searched for.
+	Overrides ConstraintOverrides
+	// Room makes group searches also require a free room meeting it.
+	Room RoomRequirement
+	// Periods, when set, limit slots to these parts of the window, such as the
+	// afternoons of "this week after 3pm".
+	Periods []Period
 }
 
+// Period is a stretch of time between Start and End.
+type Period struct {
+	Start time.Time `json:"start" format:"date-time" example:"2024-05-14T12:00:00+02:00"`
+	End   time.Time `json:"end" format:"date-time" example:"2024-05-14T17:00:00+02:00"`
+}
+
+// NewModels returns the models backed by db and by Google Calendar.
 func NewModels(db *sql.DB) Models {
-	return Models{DB: db}
+	return Models{DB: db, Calendar: NewGoogleCalendar()}
 }
 
-func (m *Models) SaveUserToken(token *oauth2.Token) error {
-	query := `INSERT INTO user_tokens (access_token, refresh_token, expiry) VALUES ($1, $2, $3)`
-	_, err := m.DB.Exec(query, token.AccessToken, token.RefreshToken, token.Expiry)
+// SaveUserToken upserts the user identified by a verified email and stores token
+// as that user's calendar token, replacing any token saved for them earlier.
+// Google only returns a refresh token on the first consent, so an empty refresh
+// token never overwrites a stored one. The scopes granted with the token are kept
+// so write access can be checked before touching the user's calendar.
+func (m *Models) SaveUserToken(email string, token *oauth2.Token) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queryUser := `INSERT INTO users (email) VALUES ($1) ON CONFLICT (email) DO NOTHING`
+	_, err = tx.Exec(queryUser, email)
+	if err != nil {
+		return fmt.Errorf("failed to upsert user: %w", err)
+	}
+
+	scopes, _ := token.Extra("scope").(string)
+
+	queryToken := `
+		INSERT INTO user_tokens (email, access_token, refresh_token, expiry, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (email) DO UPDATE SET
+			access_token = EXCLUDED.access_token,
+			refresh_token = COALESCE(NULLIF(EXCLUDED.refresh_token, ''), user_tokens.refresh_token),
+			expiry = EXCLUDED.expiry,
+			scopes = COALESCE(NULLIF(EXCLUDED.scopes, ''), user_tokens.scopes)
+	`
+	_, err = tx.Exec(queryToken, email, token.AccessToken, token.RefreshToken, token.Expiry, scopes)
 	if err != nil {
 		return fmt.Errorf("failed to save token: %w", err)
 	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
@@ -45,6 +105,9 @@ func (m *Models) GetUserToken(email string) (*oauth2.Token, error) {
 	var accessToken, refreshToken string
 	var expiry time.Time
 	err := row.Scan(&accessToken, &refreshToken, &expiry)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTokenNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get token: %w", err)
 	}
@@ -56,89 +119,80 @@ func (m *Models) GetUserToken(email string) (*oauth2.Token, error) {
 	}, nil
 }
 
-// GetFreeSlots retrieves free time slots for the next week for the authenticated user.
-func (m *Models) GetFreeSlots(ctx context.Context, token *oauth2.Token) ([]string, error) {
-	// Create a custom HTTP client
-	client := oauthConfig.Client(ctx, token)
+// HasScope reports whether the token stored for email was granted any of scopes.
+func (m *Models) HasScope(email string, scopes ...string) (bool, error) {
+	query := `SELECT scopes FROM user_tokens WHERE email = $1`
 
-	// Create the Google Calendar service using the new recommended method
-	srv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	var granted string
+	err := m.DB.QueryRow(query, email).Scan(&granted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrTokenNotFound
+	}
 	if err != nil {
-		return nil, fmt.Errorf("unable to create calendar service: %w", err)
+		return false, fmt.Errorf("failed to get token scopes: %w", err)
 	}
 
-	// Get the current time and define the time range for the next week
-	now := time.Now()
-	startOfWeek := now.UTC().Format(time.RFC3339)                       // Start of today in UTC
-	endOfWeek := now.Add(7 * 24 * time.Hour).UTC().Format(time.RFC3339) // One week from now
-
-	// Fetch the events for the next week
-	events, err := srv.Events.List("primary").
-		TimeMin(startOfWeek).
-		TimeMax(endOfWeek).
-		SingleEvents(true).
-		OrderBy("startTime").
-		Do()
-	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve calendar events: %w", err)
+	for _, g := range strings.Fields(granted) {
+		for _, scope := range scopes {
+			if g == scope {
+				return true, nil
+			}
+		}
 	}
 
-	// Create a slice to store the free time slots
-	var freeSlots []string
+	return false, nil
+}
 
-	// Let's assume work hours are from 9:00 AM to 5:00 PM, adjust according to your needs.
-	workStart := 9
-	workEnd := 17
+// GetFreeSlots retrieves the available time slots between opts.From and opts.To in the
+// primary calendar of the user with email, classified as free or tentative. Slots fall
+// within the user's working hours and respect their booking constraints, as overridden
+// by opts.Overrides. Time reserved by active holds counts as meetings.
+func (m *Models) GetFreeSlots(ctx context.Context, email string, opts SlotOptions) ([]TimeSlot, error) {
+	token, err := m.GetUserToken(email)
+	if err != nil {
+		return nil, err
+	}
 
-	// Loop through the events and find gaps between them
-	var lastEndTime time.Time
-	for _, event := range events.Items {
-		// Parse the event start and end time
-		startTime, err := time.Parse(time.RFC3339, event.Start.DateTime)
-		if err != nil {
-			startTime, err = time.Parse(time.RFC3339, event.Start.Date) // handle full-day events
-			if err != nil {
-				return nil, fmt.Errorf("error parsing event start time: %w", err)
-			}
-		}
+	prefs, err := m.GetPreferences(email)
+	if err != nil {
+		return nil, err
+	}
+	opts.Schedule, err = prefs.Schedule()
+	if err != nil {
+		return nil, err
+	}
+	constraints := opts.Overrides.apply(prefs.Constraints())
 
-		// If this is the first event, check if there's a gap before it
-		if lastEndTime.IsZero() {
-			if startTime.Hour() > workStart {
-				// Found a free slot before the first event
-				freeSlot := fmt.Sprintf("%s to %s", fmt.Sprintf("%02d:00", workStart), startTime.Format("15:04"))
-				freeSlots = append(freeSlots, freeSlot)
-			}
-		} else {
-			// Check for a gap between the last event and the current one
-			if startTime.Sub(lastEndTime) > time.Minute*30 { // Allow a 30 min gap
-				freeSlot := fmt.Sprintf("%s to %s", lastEndTime.Format("15:04"), startTime.Format("15:04"))
-				freeSlots = append(freeSlots, freeSlot)
-			}
-		}
+	// Meetings just outside the window still count towards buffers and caps
+	fetch := opts
+	fetch.From = opts.From.Add(-constraints.lookaround())
+	fetch.To = opts.To.Add(constraints.lookaround())
 
-		// Update the last end time
-		endTime, err := time.Parse(time.RFC3339, event.End.DateTime)
-		if err != nil {
-			endTime, err = time.Parse(time.RFC3339, event.End.Date) // handle full-day events
-			if err != nil {
-				return nil, fmt.Errorf("error parsing event end time: %w", err)
-			}
-		}
-		lastEndTime = endTime
+	periods, err := m.Calendar.BusyPeriods(ctx, token, email, fetch)
+	if err != nil {
+		return nil, err
 	}
 
-	// Check if there are free slots after the last event for the rest of the day
-	if !lastEndTime.IsZero() && lastEndTime.Hour() < workEnd {
-		freeSlot := fmt.Sprintf("%s to %s", lastEndTime.Format("15:04"), fmt.Sprintf("%02d:00", workEnd))
-		freeSlots = append(freeSlots, freeSlot)
+	held, err := m.heldIntervals(fetch.From, fetch.To)
+	if err != nil {
+		return nil, err
 	}
 
-	// Return the list of free slots
-	return freeSlots, nil
+	busy, tentative := splitPeriods(periods)
+	meetings := append(busy, held[email]...)
+	busy = append(meetings, constraints.blocked(meetings, opts.From, time.Now(), opts.Schedule.Location)...)
+	return classifiedSlots(busy, tentative, opts), nil
 }
 
-func (m *Models) AddUserToGroup(userEmail, groupName string) error {
+// Roles a user can have in a group. Admins may change meetings booked for the group.
+const (
+	RoleMember = "member"
+	RoleAdmin  = "admin"
+)
+
+// AddUserToGroup adds the user to the group with role, creating the group if needed.
+// Adding a user who is already a member updates their role.
+func (m *Models) AddUserToGroup(userEmail, groupName, role string) error {
 	tx, err := m.DB.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -154,11 +208,11 @@ func (m *Models) AddUserToGroup(userEmail, groupName string) error {
 
 	// Link user to group
 	queryLink := `
-		INSERT INTO user_groups (user_email, group_name) 
-		VALUES ($1, $2) 
-		ON CONFLICT DO NOTHING
+		INSERT INTO user_groups (user_email, group_name, role) 
+		VALUES ($1, $2, $3) 
+		ON CONFLICT (user_email, group_name) DO UPDATE SET role = EXCLUDED.role
 	`
-	_, err = tx.Exec(queryLink, userEmail, groupName)
+	_, err = tx.Exec(queryLink, userEmail, groupName, role)
 	if err != nil {
 		return fmt.Errorf("failed to link user to group: %w", err)
 	}
@@ -212,8 +266,80 @@ func (m *Models) ListGroups() ([]string, error) {
 
 	return groups, nil
 }
+
+// IsGroupAdmin reports whether email is an admin of any of groups.
+func (m *Models) IsGroupAdmin(email string, groups []string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM user_groups WHERE LOWER(user_email) = LOWER($1) AND group_name = $2 AND role = $3)`
+	for _, group := range groups {
+		var admin bool
+		err := m.DB.QueryRow(query, email, group, RoleAdmin).Scan(&admin)
+		if err != nil {
+			return false, fmt.Errorf("failed to look up group role: %w", err)
+		}
+		if admin {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GroupMembers returns the emails of the members of groupName in alphabetical order.
+func (m *Models) GroupMembers(groupName string) ([]string, error) {
+	var exists bool
+	queryGroup := `SELECT EXISTS (SELECT 1 FROM groups WHERE name = $1)`
+	err := m.DB.QueryRow(queryGroup, groupName).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up group: %w", err)
+	}
+	if !exists {
+		return nil, ErrGroupNotFound
+	}
+
+	query := `SELECT user_email FROM user_groups WHERE group_name = $1 ORDER BY user_email`
+	rows, err := m.DB.Query(query, groupName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query group members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []string
+	for rows.Next() {
+		var email string
+		err := rows.Scan(&email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan member email: %w", err)
+		}
+		members = append(members, email)
+	}
+
+	return members, nil
+}
+
 func (m *Models) InitializeDatabase() error {
 	queries := []string{
+		`CREATE TABLE IF NOT EXISTS users (
+			id SERIAL PRIMARY KEY,
+			email VARCHAR(255) UNIQUE NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS user_tokens (
+			id SERIAL PRIMARY KEY,
+			email VARCHAR(255) UNIQUE NOT NULL,
+			access_token TEXT NOT NULL,
+			refresh_token TEXT NOT NULL DEFAULT '',
+			expiry TIMESTAMPTZ,
+			FOREIGN KEY (email) REFERENCES users(email) ON DELETE CASCADE
+		);`,
+		// Tables created before tokens were stored by email have no email column, and
+		// their rows cannot be attributed to anyone; those users sign in again
+		`ALTER TABLE user_tokens ADD COLUMN IF NOT EXISTS email VARCHAR(255);`,
+		`DELETE FROM user_tokens WHERE email IS NULL;`,
+		`DELETE FROM user_tokens a USING user_tokens b WHERE a.email = b.email AND a.ctid < b.ctid;`,
+		`INSERT INTO users (email) SELECT email FROM user_tokens ON CONFLICT (email) DO NOTHING;`,
+		`ALTER TABLE user_tokens ALTER COLUMN email SET NOT NULL;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS user_tokens_email_idx ON user_tokens (email);`,
+		`ALTER TABLE user_tokens ADD COLUMN IF NOT EXISTS scopes TEXT NOT NULL DEFAULT '';`,
 		`CREATE TABLE IF NOT EXISTS groups (
 			id SERIAL PRIMARY KEY,
 			name VARCHAR(255) UNIQUE NOT NULL
@@ -226,6 +352,192 @@ func (m *Models) InitializeDatabase() error {
 			FOREIGN KEY (group_name) REFERENCES groups(name),
 			UNIQUE (user_email, group_name)
 		);`,
+		`ALTER TABLE user_groups ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'member';`,
+		`CREATE TABLE IF NOT EXISTS meetings (
+			id SERIAL PRIMARY KEY,
+			event_id VARCHAR(1024) UNIQUE NOT NULL,
+			organizer VARCHAR(255) NOT NULL,
+			title TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			start_time TIMESTAMPTZ NOT NULL,
+			end_time TIMESTAMPTZ NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+			previous_start TIMESTAMPTZ,
+			previous_end TIMESTAMPTZ,
+			html_link TEXT NOT NULL DEFAULT '',
+			meet_url TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			FOREIGN KEY (organizer) REFERENCES users(email)
+		);`,
+		`ALTER TABLE meetings ADD COLUMN IF NOT EXISTS recurrence TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE meetings ADD COLUMN IF NOT EXISTS room VARCHAR(1024) NOT NULL DEFAULT '';`,
+		`CREATE TABLE IF NOT EXISTS meeting_attendees (
+			id SERIAL PRIMARY KEY,
+			event_id VARCHAR(1024) NOT NULL,
+			email VARCHAR(255) NOT NULL,
+			FOREIGN KEY (event_id) REFERENCES meetings(event_id) ON DELETE CASCADE,
+			UNIQUE (event_id, email)
+		);`,
+		`CREATE TABLE IF NOT EXISTS meeting_groups (
+			id SERIAL PRIMARY KEY,
+			event_id VARCHAR(1024) NOT NULL,
+			group_name VARCHAR(255) NOT NULL,
+			FOREIGN KEY (event_id) REFERENCES meetings(event_id) ON DELETE CASCADE,
+			FOREIGN KEY (group_name) REFERENCES groups(name),
+			UNIQUE (event_id, group_name)
+		);`,
+		`CREATE TABLE IF NOT EXISTS holds (
+			id VARCHAR(64) PRIMARY KEY,
+			organizer VARCHAR(255) NOT NULL,
+			title TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			start_time TIMESTAMPTZ NOT NULL,
+			end_time TIMESTAMPTZ NOT NULL,
+			group_names TEXT NOT NULL DEFAULT '',
+			status VARCHAR(20) NOT NULL DEFAULT 'active',
+			expires_at TIMESTAMPTZ NOT NULL,
+			event_id VARCHAR(1024),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			FOREIGN KEY (organizer) REFERENCES users(email)
+		);`,
+		`CREATE TABLE IF NOT EXISTS hold_attendees (
+			id SERIAL PRIMARY KEY,
+			hold_id VARCHAR(64) NOT NULL,
+			email VARCHAR(255) NOT NULL,
+			FOREIGN KEY (hold_id) REFERENCES holds(id) ON DELETE CASCADE,
+			UNIQUE (hold_id, email)
+		);`,
+		`CREATE TABLE IF NOT EXISTS user_preferences (
+			email VARCHAR(255) PRIMARY KEY,
+			timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+			working_hours JSONB NOT NULL DEFAULT '{}',
+			min_notice_minutes INTEGER NOT NULL DEFAULT 0,
+			max_meetings_per_day INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			FOREIGN KEY (email) REFERENCES users(email) ON DELETE CASCADE
+		);`,
+		`ALTER TABLE user_preferences
+			ADD COLUMN IF NOT EXISTS max_meetings_per_week INTEGER NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS buffer_before_minutes INTEGER NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS buffer_after_minutes INTEGER NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS max_continuous_minutes INTEGER NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS break_minutes INTEGER NOT NULL DEFAULT 0;`,
+		`CREATE TABLE IF NOT EXISTS resources (
+			id SERIAL PRIMARY KEY,
+			calendar_id VARCHAR(1024) UNIQUE NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			capacity INTEGER NOT NULL,
+			building VARCHAR(255) NOT NULL DEFAULT '',
+			features TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS booking_pages (
+			id SERIAL PRIMARY KEY,
+			slug VARCHAR(64) UNIQUE NOT NULL,
+			owner VARCHAR(255) NOT NULL,
+			group_name VARCHAR(255) NOT NULL DEFAULT '',
+			title TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			duration_minutes INTEGER NOT NULL,
+			questions JSONB NOT NULL DEFAULT '[]',
+			rules JSONB NOT NULL DEFAULT '{}',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			FOREIGN KEY (owner) REFERENCES users(email)
+		);`,
+		`CREATE TABLE IF NOT EXISTS page_bookings (
+			id SERIAL PRIMARY KEY,
+			slug VARCHAR(64) NOT NULL,
+			guest_email VARCHAR(255) NOT NULL,
+			guest_name VARCHAR(255) NOT NULL DEFAULT '',
+			event_id VARCHAR(1024) NOT NULL,
+			answers JSONB NOT NULL DEFAULT '{}',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			FOREIGN KEY (slug) REFERENCES booking_pages(slug) ON DELETE CASCADE
+		);`,
+		`CREATE INDEX IF NOT EXISTS page_bookings_guest_idx ON page_bookings (slug, guest_email);`,
+		`ALTER TABLE groups ADD COLUMN IF NOT EXISTS distribution VARCHAR(20) NOT NULL DEFAULT '';`,
+		`ALTER TABLE user_groups ADD COLUMN IF NOT EXISTS weight INTEGER NOT NULL DEFAULT 1;`,
+		`ALTER TABLE meetings ADD COLUMN IF NOT EXISTS host VARCHAR(255) NOT NULL DEFAULT '';`,
+		`CREATE TABLE IF NOT EXISTS group_assignments (
+			id SERIAL PRIMARY KEY,
+			group_name VARCHAR(255) NOT NULL,
+			host VARCHAR(255) NOT NULL,
+			event_id VARCHAR(1024) NOT NULL,
+			assigned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			FOREIGN KEY (group_name) REFERENCES groups(name) ON DELETE CASCADE
+		);`,
+		`CREATE INDEX IF NOT EXISTS group_assignments_group_idx ON group_assignments (group_name, assigned_at);`,
+		// Hosts are reserved before their meeting's event exists
+		`ALTER TABLE group_assignments ALTER COLUMN event_id DROP NOT NULL;`,
+		`ALTER TABLE holds ADD COLUMN IF NOT EXISTS host VARCHAR(255) NOT NULL DEFAULT '';`,
+		`ALTER TABLE holds ADD COLUMN IF NOT EXISTS host_assignment INTEGER NOT NULL DEFAULT 0;`,
+		`CREATE TABLE IF NOT EXISTS polls (
+			id VARCHAR(64) PRIMARY KEY,
+			organizer VARCHAR(255) NOT NULL,
+			title TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			status VARCHAR(20) NOT NULL DEFAULT 'open',
+			winning_slot INT,
+			event_id VARCHAR(1024),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			closed_at TIMESTAMPTZ,
+			FOREIGN KEY (organizer) REFERENCES users(email)
+		);`,
+		`CREATE TABLE IF NOT EXISTS poll_slots (
+			poll_id VARCHAR(64) NOT NULL,
+			slot_index INT NOT NULL,
+			start_time TIMESTAMPTZ NOT NULL,
+			end_time TIMESTAMPTZ NOT NULL,
+			PRIMARY KEY (poll_id, slot_index),
+			FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS poll_participants (
+			poll_id VARCHAR(64) NOT NULL,
+			email VARCHAR(255) NOT NULL,
+			token VARCHAR(64) NOT NULL UNIQUE,
+			PRIMARY KEY (poll_id, email),
+			FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS poll_votes (
+			poll_id VARCHAR(64) NOT NULL,
+			email VARCHAR(255) NOT NULL,
+			slot_index INT NOT NULL,
+			answer VARCHAR(20) NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (poll_id, email, slot_index),
+			FOREIGN KEY (poll_id, email) REFERENCES poll_participants(poll_id, email) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS sessions (
+			id VARCHAR(64) PRIMARY KEY,
+			organizer VARCHAR(255) NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'active',
+			constraints JSONB NOT NULL,
+			candidates JSONB NOT NULL DEFAULT '[]',
+			unavailable JSONB NOT NULL DEFAULT '[]',
+			event_id VARCHAR(1024),
+			expires_at TIMESTAMPTZ NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			FOREIGN KEY (organizer) REFERENCES users(email)
+		);`,
+		`CREATE TABLE IF NOT EXISTS session_turns (
+			id SERIAL PRIMARY KEY,
+			session_id VARCHAR(64) NOT NULL,
+			action VARCHAR(32) NOT NULL,
+			payload JSONB NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS api_keys (
+			id VARCHAR(64) PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			key_hash CHAR(64) NOT NULL UNIQUE,
+			scopes TEXT NOT NULL DEFAULT '',
+			expires_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
 	}
 
 	for _, query := range queries {
@@ -0,0 +1,358 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"golang.org/x/oauth2"
+)
+
+func TestNewModels(t *testing.T) {
+	db, _, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	if models.DB != db {
+		t.Fatalf("expected db to be set")
+	}
+}
+
+func TestSaveUserToken(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	token := &oauth2.Token{
+		AccessToken:  "access-token",
+		RefreshToken: "refresh-token",
+		Expiry:       time.Now(),
+	}
+
+	email := "test@example.com"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO users`).
+		WithArgs(email).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO user_tokens`).
+		WithArgs(email, token.AccessToken, token.RefreshToken, token.Expiry, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := models.SaveUserToken(email, token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetUserToken(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	email := "test@example.com"
+	token := &oauth2.Token{
+		AccessToken:  "access-token",
+		RefreshToken: "refresh-token",
+		Expiry:       time.Now(),
+	}
+
+	mock.ExpectQuery(`SELECT access_token, refresh_token, expiry FROM user_tokens WHERE email =`).
+		WithArgs(email).
+		WillReturnRows(sqlmock.NewRows([]string{"access_token", "refresh_token", "expiry"}).
+			AddRow(token.AccessToken, token.RefreshToken, token.Expiry))
+
+	result, err := models.GetUserToken(email)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.AccessToken != token.AccessToken || result.RefreshToken != token.RefreshToken || !result.Expiry.Equal(token.Expiry) {
+		t.Fatalf("unexpected token result: %v", result)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func expectToken(mock sqlmock.Sqlmock, email, accessToken string) {
+	mock.ExpectQuery(`SELECT access_token, refresh_token, expiry FROM user_tokens WHERE email =`).
+		WithArgs(email).
+		WillReturnRows(sqlmock.NewRows([]string{"access_token", "refresh_token", "expiry"}).
+			AddRow(accessToken, "refresh-token", time.Now().Add(time.Hour)))
+}
+
+func TestGetFreeSlots(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	email := "anna@example.com"
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	fake.AddBusy(email, BusyPeriod{Start: day.Add(9 * time.Hour), End: day.Add(12 * time.Hour), Status: SlotBusy})
+	fake.AddBusy(email, BusyPeriod{Start: day.Add(14 * time.Hour), End: day.Add(15 * time.Hour), Status: SlotTentative})
+	expectToken(mock, email, "anna-token")
+	expectPreferences(mock, email)
+	expectHolds(mock)
+
+	slots, err := models.GetFreeSlots(context.Background(), email, SlotOptions{
+		From:        day,
+		To:          day.Add(24 * time.Hour),
+		MinDuration: 30 * time.Minute,
+		Location:    time.UTC,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(slots) != 3 {
+		t.Fatalf("expected 3 slots, got %d: %v", len(slots), slots)
+	}
+	if slots[0].Status != SlotFree || !slots[0].Start.Equal(day.Add(12*time.Hour)) || !slots[0].End.Equal(day.Add(14*time.Hour)) {
+		t.Errorf("unexpected first slot: %v", slots[0])
+	}
+	if slots[1].Status != SlotTentative || !slots[1].End.Equal(day.Add(17*time.Hour)) {
+		t.Errorf("unexpected tentative slot: %v", slots[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestAddUserToGroup(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	userEmail := "test@example.com"
+	groupName := "test-group"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO groups`).
+		WithArgs(groupName).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO user_groups`).
+		WithArgs(userEmail, groupName, RoleAdmin).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := models.AddUserToGroup(userEmail, groupName, RoleAdmin)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	users := []string{"user1@example.com", "user2@example.com"}
+
+	mock.ExpectQuery(`SELECT email FROM users`).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).
+			AddRow(users[0]).
+			AddRow(users[1]))
+
+	result, err := models.ListUsers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result) != len(users) {
+		t.Fatalf("expected %d users, got %d", len(users), len(result))
+	}
+
+	for i, email := range result {
+		if email != users[i] {
+			t.Errorf("expected %s, got %s", users[i], email)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestListGroups(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	groups := []string{"group1", "group2"}
+
+	mock.ExpectQuery(`SELECT name FROM groups`).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(groups[0]).
+			AddRow(groups[1]))
+
+	result, err := models.ListGroups()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result) != len(groups) {
+		t.Fatalf("expected %d groups, got %d", len(groups), len(result))
+	}
+
+	for i, name := range result {
+		if name != groups[i] {
+			t.Errorf("expected %s, got %s", groups[i], name)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGroupMembers(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	groupName := "design"
+
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WithArgs(groupName).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT user_email FROM user_groups WHERE group_name =`).
+		WithArgs(groupName).
+		WillReturnRows(sqlmock.NewRows([]string{"user_email"}).
+			AddRow("anna@example.com").
+			AddRow("bob@example.com"))
+
+	members, err := models.GroupMembers(groupName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(members) != 2 || members[0] != "anna@example.com" || members[1] != "bob@example.com" {
+		t.Fatalf("unexpected members: %v", members)
+	}
+
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	_, err = models.GroupMembers("missing")
+	if !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("expected ErrGroupNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestInitializeDatabase(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS users`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS user_tokens`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER TABLE user_tokens ADD COLUMN IF NOT EXISTS email`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM user_tokens WHERE email IS NULL`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM user_tokens a USING user_tokens b`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO users \(email\) SELECT email FROM user_tokens`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER TABLE user_tokens ALTER COLUMN email SET NOT NULL`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE UNIQUE INDEX IF NOT EXISTS user_tokens_email_idx`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER TABLE user_tokens ADD COLUMN IF NOT EXISTS scopes`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS groups`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS user_groups`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER TABLE user_groups ADD COLUMN IF NOT EXISTS role`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS meetings`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER TABLE meetings ADD COLUMN IF NOT EXISTS recurrence`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER TABLE meetings ADD COLUMN IF NOT EXISTS room`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS meeting_attendees`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS meeting_groups`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS holds`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS hold_attendees`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS user_preferences`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER TABLE user_preferences`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS resources`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS booking_pages`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS page_bookings`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE INDEX IF NOT EXISTS page_bookings_guest_idx`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER TABLE groups ADD COLUMN IF NOT EXISTS distribution`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER TABLE user_groups ADD COLUMN IF NOT EXISTS weight`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER TABLE meetings ADD COLUMN IF NOT EXISTS host`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS group_assignments`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE INDEX IF NOT EXISTS group_assignments_group_idx`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER TABLE group_assignments ALTER COLUMN event_id DROP NOT NULL`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER TABLE holds ADD COLUMN IF NOT EXISTS host `).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER TABLE holds ADD COLUMN IF NOT EXISTS host_assignment`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS polls`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS poll_slots`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS poll_participants`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS poll_votes`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS sessions`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS session_turns`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS api_keys`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := models.InitializeDatabase()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
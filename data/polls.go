@@ -0,0 +1,471 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrPollNotFound is returned when no poll has the given ID.
+	ErrPollNotFound = errors.New("poll not found")
+	// ErrPollClosed is returned when voting on or closing a poll that is already closed.
+	ErrPollClosed = errors.New("poll is closed")
+	// ErrInvalidPoll is returned for polls that cannot be created or closed as asked.
+	ErrInvalidPoll = errors.New("invalid poll")
+	// ErrInvalidVote is returned for votes that do not answer every slot of the poll.
+	ErrInvalidVote = errors.New("invalid vote")
+	// ErrVoteLinkInvalid is returned when a vote token does not belong to the poll.
+	ErrVoteLinkInvalid = errors.New("vote link is not valid")
+)
+
+// Poll statuses recorded in the polls table.
+const (
+	PollOpen   = "open"
+	PollClosed = "closed"
+)
+
+// Answers participants give to each slot of a poll.
+const (
+	VoteYes      = "yes"
+	VoteIfNeeded = "if_needed"
+	VoteNo       = "no"
+)
+
+const (
+	// maxPollSlots is how many candidate slots a poll may offer.
+	maxPollSlots = 20
+	// maxPollParticipants is how many people a poll may invite.
+	maxPollParticipants = 50
+)
+
+// PollRequest is a poll to create: candidate slots for a meeting and the people
+// asked to vote on them.
+type PollRequest struct {
+	Organizer   string `json:"organizer" example:"anna@example.com"`
+	Title       string `json:"title" example:"Partner kickoff"`
+	Description string `json:"description,omitempty" example:"Kickoff of the integration project"`
+	// Slots are the candidate times, each with a start and an end.
+	Slots        []PollSlot `json:"slots"`
+	Participants []string   `json:"participants" example:"jan@partner.example"`
+	// OnlyFree drops the candidate slots in which the organizer is not free.
+	OnlyFree bool `json:"only_free" example:"true"`
+}
+
+// Validate checks that req can be stored.
+func (req PollRequest) Validate() error {
+	switch {
+	case req.Organizer == "":
+		return fmt.Errorf("%w: organizer is required", ErrInvalidPoll)
+	case req.Title == "":
+		return fmt.Errorf("%w: title is required", ErrInvalidPoll)
+	case len(req.Slots) == 0 || len(req.Slots) > maxPollSlots:
+		return fmt.Errorf("%w: between 1 and %d slots are required", ErrInvalidPoll, maxPollSlots)
+	case len(req.Participants) == 0 || len(req.Participants) > maxPollParticipants:
+		return fmt.Errorf("%w: between 1 and %d participants are required", ErrInvalidPoll, maxPollParticipants)
+	}
+
+	for _, slot := range req.Slots {
+		if slot.Start.IsZero() || !slot.End.After(slot.Start) {
+			return fmt.Errorf("%w: every slot needs a start before its end", ErrInvalidPoll)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, email := range req.Participants {
+		if !strings.Contains(email, "@") {
+			return fmt.Errorf("%w: %q is not an email address", ErrInvalidPoll, email)
+		}
+		if seen[email] {
+			return fmt.Errorf("%w: %s is invited twice", ErrInvalidPoll, email)
+		}
+		seen[email] = true
+	}
+	return nil
+}
+
+// PollSlot is a candidate time of a poll with the participants' answers.
+type PollSlot struct {
+	Start time.Time `json:"start" format:"date-time" example:"2024-05-06T10:00:00+02:00"`
+	End   time.Time `json:"end" format:"date-time" example:"2024-05-06T11:00:00+02:00"`
+	// Yes, IfNeeded and No list the participants who gave each answer.
+	Yes      []string `json:"yes"`
+	IfNeeded []string `json:"if_needed"`
+	No       []string `json:"no"`
+}
+
+// PollParticipant is a person invited to vote on a poll.
+type PollParticipant struct {
+	Email string `json:"email" example:"jan@partner.example"`
+	// Token identifies the participant's vote link. It is only returned when the poll
+	// is created.
+	Token string `json:"token,omitempty" example:"3f1d0c9e7b2a4c6d8e0f1a2b3c4d5e6f"`
+	Voted bool   `json:"voted" example:"false"`
+}
+
+// Poll asks participants which of several times suit them for a meeting. Closing it
+// books the winning slot on the organizer's calendar.
+type Poll struct {
+	ID           string            `json:"id" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	Organizer    string            `json:"organizer" example:"anna@example.com"`
+	Title        string            `json:"title" example:"Partner kickoff"`
+	Description  string            `json:"description,omitempty"`
+	Status       string            `json:"status" enums:"open,closed" example:"open"`
+	Slots        []PollSlot        `json:"slots"`
+	Participants []PollParticipant `json:"participants"`
+	// WinningSlot is the index of the slot the poll was closed with.
+	WinningSlot *int `json:"winning_slot,omitempty" example:"1"`
+	// EventID is the Google Calendar event booked when the poll was closed.
+	EventID string `json:"event_id,omitempty"`
+}
+
+// BestSlot returns the index of the slot the most participants can attend, preferring
+// more yes answers over if-needed ones and then the earlier slot.
+func (p *Poll) BestSlot() int {
+	best := 0
+	for i, slot := range p.Slots {
+		current := p.Slots[best]
+		able, bestAble := len(slot.Yes)+len(slot.IfNeeded), len(current.Yes)+len(current.IfNeeded)
+		switch {
+		case able != bestAble:
+			if able > bestAble {
+				best = i
+			}
+		case len(slot.Yes) != len(current.Yes):
+			if len(slot.Yes) > len(current.Yes) {
+				best = i
+			}
+		case slot.Start.Before(current.Start):
+			best = i
+		}
+	}
+	return best
+}
+
+// CreatePoll stores a poll for req and returns it with a vote token per participant.
+// The organizer needs write access, as closing the poll books the meeting on their
+// calendar. With req.OnlyFree the slots in which the organizer is busy or outside
+// their working hours are dropped, and ErrInvalidPoll is returned if none is left.
+func (m *Models) CreatePoll(ctx context.Context, req PollRequest) (*Poll, error) {
+	canWrite, err := m.HasScope(req.Organizer, writeScopes...)
+	if err != nil {
+		return nil, err
+	}
+	if !canWrite {
+		return nil, ErrWriteAccessRequired
+	}
+
+	slots := req.Slots
+	if req.OnlyFree {
+		slots, err = m.organizerFreeSlots(ctx, req.Organizer, req.Slots)
+		if err != nil {
+			return nil, err
+		}
+		if len(slots) == 0 {
+			return nil, fmt.Errorf("%w: the organizer is not free in any of the slots", ErrInvalidPoll)
+		}
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+
+	poll := &Poll{
+		ID:           id,
+		Organizer:    req.Organizer,
+		Title:        req.Title,
+		Description:  req.Description,
+		Status:       PollOpen,
+		Slots:        make([]PollSlot, len(slots)),
+		Participants: make([]PollParticipant, len(req.Participants)),
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queryPoll := `INSERT INTO polls (id, organizer, title, description, status) VALUES ($1, $2, $3, $4, $5)`
+	_, err = tx.Exec(queryPoll, poll.ID, poll.Organizer, poll.Title, poll.Description, poll.Status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save poll: %w", err)
+	}
+
+	querySlot := `INSERT INTO poll_slots (poll_id, slot_index, start_time, end_time) VALUES ($1, $2, $3, $4)`
+	for i, slot := range slots {
+		_, err = tx.Exec(querySlot, poll.ID, i, slot.Start, slot.End)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save poll slot: %w", err)
+		}
+		poll.Slots[i] = PollSlot{Start: slot.Start, End: slot.End, Yes: []string{}, IfNeeded: []string{}, No: []string{}}
+	}
+
+	queryParticipant := `INSERT INTO poll_participants (poll_id, email, token) VALUES ($1, $2, $3)`
+	for i, email := range req.Participants {
+		token, err := randomID()
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(queryParticipant, poll.ID, email, token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save poll participant: %w", err)
+		}
+		poll.Participants[i] = PollParticipant{Email: email, Token: token}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return poll, nil
+}
+
+// organizerFreeSlots returns the candidate slots that lie within the organizer's free time.
+func (m *Models) organizerFreeSlots(ctx context.Context, organizer string, candidates []PollSlot) ([]PollSlot, error) {
+	from, to := candidates[0].Start, candidates[0].End
+	for _, slot := range candidates[1:] {
+		from, to = minTime(from, slot.Start), maxTime(to, slot.End)
+	}
+
+	free, err := m.GetFreeSlots(ctx, organizer, SlotOptions{From: from, To: to, Location: time.UTC})
+	if err != nil {
+		return nil, err
+	}
+
+	var slots []PollSlot
+	for _, slot := range candidates {
+		for _, period := range free {
+			if !slot.Start.Before(period.Start) && !slot.End.After(period.End) {
+				slots = append(slots, slot)
+				break
+			}
+		}
+	}
+	return slots, nil
+}
+
+// GetPoll returns the poll with id and the participants' answers. Vote tokens are
+// left out.
+func (m *Models) GetPoll(id string) (*Poll, error) {
+	poll := &Poll{ID: id, Slots: []PollSlot{}, Participants: []PollParticipant{}}
+	var winningSlot sql.NullInt64
+	query := `SELECT organizer, title, description, status, winning_slot, COALESCE(event_id, '') FROM polls WHERE id = $1`
+	err := m.DB.QueryRow(query, id).Scan(&poll.Organizer, &poll.Title, &poll.Description, &poll.Status, &winningSlot, &poll.EventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPollNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get poll: %w", err)
+	}
+	if winningSlot.Valid {
+		index := int(winningSlot.Int64)
+		poll.WinningSlot = &index
+	}
+
+	rows, err := m.DB.Query(`SELECT start_time, end_time FROM poll_slots WHERE poll_id = $1 ORDER BY slot_index`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query poll slots: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		slot := PollSlot{Yes: []string{}, IfNeeded: []string{}, No: []string{}}
+		err := rows.Scan(&slot.Start, &slot.End)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan poll slot: %w", err)
+		}
+		poll.Slots = append(poll.Slots, slot)
+	}
+
+	rows, err = m.DB.Query(`SELECT email FROM poll_participants WHERE poll_id = $1 ORDER BY email`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query poll participants: %w", err)
+	}
+	defer rows.Close()
+
+	participants := map[string]int{}
+	for rows.Next() {
+		var participant PollParticipant
+		err := rows.Scan(&participant.Email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan poll participant: %w", err)
+		}
+		participants[participant.Email] = len(poll.Participants)
+		poll.Participants = append(poll.Participants, participant)
+	}
+
+	rows, err = m.DB.Query(`SELECT email, slot_index, answer FROM poll_votes WHERE poll_id = $1 ORDER BY email`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query poll votes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var email, answer string
+		var index int
+		err := rows.Scan(&email, &index, &answer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan poll vote: %w", err)
+		}
+		if index < 0 || index >= len(poll.Slots) {
+			continue
+		}
+		if i, ok := participants[email]; ok {
+			poll.Participants[i].Voted = true
+		}
+
+		slot := &poll.Slots[index]
+		switch answer {
+		case VoteYes:
+			slot.Yes = append(slot.Yes, email)
+		case VoteIfNeeded:
+			slot.IfNeeded = append(slot.IfNeeded, email)
+		case VoteNo:
+			slot.No = append(slot.No, email)
+		}
+	}
+
+	return poll, nil
+}
+
+// Vote records the answers of the participant holding token to the slots of the poll
+// with id, one answer per slot in order. Answers can be changed until the poll closes.
+func (m *Models) Vote(id, token string, answers []string) error {
+	poll, err := m.GetPoll(id)
+	if err != nil {
+		return err
+	}
+	if poll.Status != PollOpen {
+		return ErrPollClosed
+	}
+	if len(answers) != len(poll.Slots) {
+		return fmt.Errorf("%w: expected %d answers, one per slot", ErrInvalidVote, len(poll.Slots))
+	}
+	for _, answer := range answers {
+		if answer != VoteYes && answer != VoteIfNeeded && answer != VoteNo {
+			return fmt.Errorf("%w: answer %q must be yes, if_needed or no", ErrInvalidVote, answer)
+		}
+	}
+
+	var email string
+	query := `SELECT email FROM poll_participants WHERE poll_id = $1 AND token = $2`
+	err = m.DB.QueryRow(query, id, token).Scan(&email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrVoteLinkInvalid
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up participant: %w", err)
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Votes are only written while the poll is open; the shared lock on its row makes
+	// a concurrent close wait for them, or leaves them unwritten once it has closed
+	queryVote := `
+		INSERT INTO poll_votes (poll_id, email, slot_index, answer)
+		SELECT $1, $2, $3, $4 FROM polls WHERE id = $1 AND status = $5 FOR SHARE
+		ON CONFLICT (poll_id, email, slot_index)
+		DO UPDATE SET answer = EXCLUDED.answer, updated_at = NOW()
+	`
+	for i, answer := range answers {
+		result, err := tx.Exec(queryVote, id, email, i, answer, PollOpen)
+		if err != nil {
+			return fmt.Errorf("failed to save vote: %w", err)
+		}
+		saved, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to count saved votes: %w", err)
+		}
+		if saved == 0 {
+			return ErrPollClosed
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ClosePoll closes the open poll with id on behalf of actor, who must be its organizer,
+// and books the meeting with every participant. The slot with the given index is
+// booked, or the best slot when slot is nil.
+func (m *Models) ClosePoll(ctx context.Context, id, actor string, slot *int) (*Poll, *Meeting, error) {
+	poll, err := m.GetPoll(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !strings.EqualFold(actor, poll.Organizer) {
+		return nil, nil, ErrNotAllowed
+	}
+	if poll.Status != PollOpen {
+		return nil, nil, ErrPollClosed
+	}
+
+	winner := poll.BestSlot()
+	if slot != nil {
+		if *slot < 0 || *slot >= len(poll.Slots) {
+			return nil, nil, fmt.Errorf("%w: slot must be between 0 and %d", ErrInvalidPoll, len(poll.Slots)-1)
+		}
+		winner = *slot
+	}
+
+	attendees := make([]string, len(poll.Participants))
+	for i, participant := range poll.Participants {
+		attendees[i] = participant.Email
+	}
+
+	// Claim the poll before booking so that a second close cannot book it again
+	var claimed string
+	queryClaim := `
+		UPDATE polls SET status = $1, winning_slot = $2, closed_at = NOW()
+		WHERE id = $3 AND status = $4
+		RETURNING id
+	`
+	err = m.DB.QueryRow(queryClaim, PollClosed, winner, id, PollOpen).Scan(&claimed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrPollClosed
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to close poll: %w", err)
+	}
+
+	meeting, err := m.CreateMeeting(ctx, MeetingRequest{
+		Organizer:   poll.Organizer,
+		Attendees:   attendees,
+		Title:       poll.Title,
+		Description: poll.Description,
+		Start:       poll.Slots[winner].Start,
+		End:         poll.Slots[winner].End,
+	})
+	if err != nil {
+		// Open the poll again so that the organizer can retry or pick another slot
+		_, reopenErr := m.DB.Exec(`UPDATE polls SET status = $1, winning_slot = NULL, closed_at = NULL WHERE id = $2`, PollOpen, id)
+		if reopenErr != nil {
+			return nil, nil, errors.Join(err, fmt.Errorf("failed to reopen poll: %w", reopenErr))
+		}
+		return nil, nil, err
+	}
+
+	_, err = m.DB.Exec(`UPDATE polls SET event_id = $1 WHERE id = $2`, meeting.EventID, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to update poll: %w", err)
+	}
+
+	poll.Status = PollClosed
+	poll.WinningSlot = &winner
+	poll.EventID = meeting.EventID
+	return poll, meeting, nil
+}
@@ -0,0 +1,244 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// pollVote is a row of the poll votes query.
+type pollVote struct {
+	email  string
+	slot   int
+	answer string
+}
+
+func expectPoll(mock sqlmock.Sqlmock, id, organizer, status string, slots []interval, participants []string, votes ...pollVote) {
+	mock.ExpectQuery(`SELECT organizer, title, description, status, winning_slot`).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"organizer", "title", "description", "status", "winning_slot", "event_id"}).
+			AddRow(organizer, "Partner kickoff", "", status, nil, ""))
+
+	slotRows := sqlmock.NewRows([]string{"start_time", "end_time"})
+	for _, slot := range slots {
+		slotRows.AddRow(slot.start, slot.end)
+	}
+	mock.ExpectQuery(`SELECT start_time, end_time FROM poll_slots`).WithArgs(id).WillReturnRows(slotRows)
+
+	participantRows := sqlmock.NewRows([]string{"email"})
+	for _, email := range participants {
+		participantRows.AddRow(email)
+	}
+	mock.ExpectQuery(`SELECT email FROM poll_participants WHERE poll_id =`).WithArgs(id).WillReturnRows(participantRows)
+
+	voteRows := sqlmock.NewRows([]string{"email", "slot_index", "answer"})
+	for _, vote := range votes {
+		voteRows.AddRow(vote.email, vote.slot, vote.answer)
+	}
+	mock.ExpectQuery(`SELECT email, slot_index, answer FROM poll_votes`).WithArgs(id).WillReturnRows(voteRows)
+}
+
+func TestPollBestSlot(t *testing.T) {
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	slot := func(hour int, yes, ifNeeded []string) PollSlot {
+		return PollSlot{Start: day.Add(time.Duration(hour) * time.Hour), End: day.Add(time.Duration(hour+1) * time.Hour), Yes: yes, IfNeeded: ifNeeded}
+	}
+
+	tests := []struct {
+		name     string
+		slots    []PollSlot
+		expected int
+	}{
+		{
+			name:     "most people able to attend",
+			slots:    []PollSlot{slot(9, []string{"a"}, nil), slot(10, []string{"a"}, []string{"b", "c"})},
+			expected: 1,
+		},
+		{
+			name:     "more yes answers break ties",
+			slots:    []PollSlot{slot(9, []string{"a"}, []string{"b"}), slot(10, []string{"a", "b"}, nil)},
+			expected: 1,
+		},
+		{
+			name:     "earlier slot breaks remaining ties",
+			slots:    []PollSlot{slot(11, []string{"a"}, nil), slot(9, []string{"b"}, nil)},
+			expected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poll := &Poll{Slots: tt.slots}
+			if got := poll.BestSlot(); got != tt.expected {
+				t.Errorf("expected slot %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCreatePollOnlyFree(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	organizer := "anna@example.com"
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	fake.AddBusy(organizer, BusyPeriod{Start: day.Add(10 * time.Hour), End: day.Add(11 * time.Hour), Status: SlotBusy})
+
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "anna-token")
+	expectPreferences(mock, organizer)
+	expectHolds(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO polls`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO poll_slots`).WithArgs(sqlmock.AnyArg(), 0, day.Add(9*time.Hour), day.Add(10*time.Hour)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO poll_slots`).WithArgs(sqlmock.AnyArg(), 1, day.Add(14*time.Hour), day.Add(15*time.Hour)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO poll_participants`).WithArgs(sqlmock.AnyArg(), "jan@partner.example", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	poll, err := models.CreatePoll(context.Background(), PollRequest{
+		Organizer: organizer,
+		Title:     "Partner kickoff",
+		Slots: []PollSlot{
+			{Start: day.Add(9 * time.Hour), End: day.Add(10 * time.Hour)},
+			{Start: day.Add(10 * time.Hour), End: day.Add(11 * time.Hour)},
+			{Start: day.Add(14 * time.Hour), End: day.Add(15 * time.Hour)},
+		},
+		Participants: []string{"jan@partner.example"},
+		OnlyFree:     true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(poll.Slots) != 2 {
+		t.Errorf("expected the busy slot to be dropped, got %v", poll.Slots)
+	}
+	if poll.Participants[0].Token == "" {
+		t.Errorf("expected a vote token for the participant")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestVote(t *testing.T) {
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	slots := []interval{{start: start, end: start.Add(time.Hour)}, {start: start.Add(2 * time.Hour), end: start.Add(3 * time.Hour)}}
+	participants := []string{"jan@partner.example"}
+
+	tests := []struct {
+		name     string
+		status   string
+		answers  []string
+		closing  bool
+		expected error
+	}{
+		{name: "answers every slot", status: PollOpen, answers: []string{VoteYes, VoteIfNeeded}},
+		{name: "missing answer", status: PollOpen, answers: []string{VoteYes}, expected: ErrInvalidVote},
+		{name: "unknown answer", status: PollOpen, answers: []string{VoteYes, "maybe"}, expected: ErrInvalidVote},
+		{name: "closed poll", status: PollClosed, answers: []string{VoteYes, VoteNo}, expected: ErrPollClosed},
+		{name: "closed while voting", status: PollOpen, answers: []string{VoteYes, VoteNo}, closing: true, expected: ErrPollClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer db.Close()
+
+			models := NewModels(db)
+
+			expectPoll(mock, "poll-1", "anna@example.com", tt.status, slots, participants)
+			if tt.expected == nil || tt.closing {
+				mock.ExpectQuery(`SELECT email FROM poll_participants WHERE poll_id = \$1 AND token = \$2`).
+					WithArgs("poll-1", "token-1").
+					WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("jan@partner.example"))
+				mock.ExpectBegin()
+			}
+			switch {
+			case tt.closing:
+				// The poll closed after it was read, so no vote is written
+				mock.ExpectExec(`INSERT INTO poll_votes .* FROM polls WHERE id = \$1 AND status = \$5`).
+					WithArgs("poll-1", "jan@partner.example", 0, tt.answers[0], PollOpen).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			case tt.expected == nil:
+				for i, answer := range tt.answers {
+					mock.ExpectExec(`INSERT INTO poll_votes`).WithArgs("poll-1", "jan@partner.example", i, answer, PollOpen).WillReturnResult(sqlmock.NewResult(1, 1))
+				}
+				mock.ExpectCommit()
+			}
+
+			err := models.Vote("poll-1", "token-1", tt.answers)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected error %v, got %v", tt.expected, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatalf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestClosePoll(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	organizer := "anna@example.com"
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	slots := []interval{{start: start, end: start.Add(time.Hour)}, {start: start.Add(2 * time.Hour), end: start.Add(3 * time.Hour)}}
+	participants := []string{"eva@partner.example", "jan@partner.example"}
+
+	expectPoll(mock, "poll-1", organizer, PollOpen, slots, participants,
+		pollVote{"eva@partner.example", 0, VoteNo}, pollVote{"eva@partner.example", 1, VoteYes},
+		pollVote{"jan@partner.example", 0, VoteYes}, pollVote{"jan@partner.example", 1, VoteIfNeeded})
+	mock.ExpectQuery(`UPDATE polls SET status`).WithArgs(PollClosed, 1, "poll-1", PollOpen).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("poll-1"))
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "anna-token")
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO meetings`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_attendees`).WithArgs("fake-event-1", "eva@partner.example").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_attendees`).WithArgs("fake-event-1", "jan@partner.example").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`UPDATE polls SET event_id`).WithArgs("fake-event-1", "poll-1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	poll, meeting, err := models.ClosePoll(context.Background(), "poll-1", organizer, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !meeting.Start.Equal(slots[1].start) {
+		t.Errorf("expected the meeting at %v, got %v", slots[1].start, meeting.Start)
+	}
+	if poll.Status != PollClosed || poll.WinningSlot == nil || *poll.WinningSlot != 1 {
+		t.Errorf("expected the poll closed with slot 1, got %+v", poll)
+	}
+
+	// A close racing the first one finds the poll claimed and books nothing
+	expectPoll(mock, "poll-1", organizer, PollOpen, slots, participants)
+	mock.ExpectQuery(`UPDATE polls SET status`).WithArgs(PollClosed, 0, "poll-1", PollOpen).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, _, err = models.ClosePoll(context.Background(), "poll-1", organizer, nil)
+	if !errors.Is(err, ErrPollClosed) {
+		t.Errorf("expected ErrPollClosed, got %v", err)
+	}
+	if events := fake.Events(organizer); len(events) != 1 {
+		t.Errorf("expected no second event, got %d events", len(events))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,370 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrUserNotFound is returned when no user has the given email.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrInvalidPreferences is returned when preferences fail validation.
+	ErrInvalidPreferences = errors.New("invalid preferences")
+)
+
+// HourRange is a daily range of wall-clock time, as offsets from midnight. It
+// serializes as {"start": "09:00", "end": "17:00"}; an end of "24:00" is midnight.
+type HourRange struct {
+	Start time.Duration `json:"start" swaggertype:"string" example:"09:00"`
+	End   time.Duration `json:"end" swaggertype:"string" example:"17:00"`
+}
+
+// contains reports whether start..end falls on a single day inside the range, in loc.
+func (h HourRange) contains(start, end time.Time, loc *time.Location) bool {
+	start = start.In(loc)
+	return !start.Before(atClock(start, h.Start, loc)) && !end.After(atClock(start, h.End, loc))
+}
+
+func (h HourRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Start string `json:"start"`
+		End   string `json:"end"`
+	}{formatClock(h.Start), formatClock(h.End)})
+}
+
+func (h *HourRange) UnmarshalJSON(b []byte) error {
+	var clock struct {
+		Start string `json:"start"`
+		End   string `json:"end"`
+	}
+	err := json.Unmarshal(b, &clock)
+	if err != nil {
+		return err
+	}
+
+	h.Start, err = ParseClock(clock.Start)
+	if err != nil {
+		return err
+	}
+	h.End, err = ParseClock(clock.End)
+	return err
+}
+
+// ParseClock parses a "15:04" wall-clock time, or "24:00", into an offset from midnight.
+func ParseClock(value string) (time.Duration, error) {
+	if value == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q: must be in HH:MM format", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func formatClock(offset time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(offset/time.Hour), int(offset%time.Hour/time.Minute))
+}
+
+// WorkingHours maps lowercase weekday names to the hours worked on those days. A day
+// can have several ranges for split shifts; days missing from the map are days off.
+type WorkingHours map[string][]HourRange
+
+// Preferences are a user's scheduling preferences. Working hours are wall-clock times
+// in TimeZone.
+type Preferences struct {
+	Email        string       `json:"email" example:"anna@example.com"`
+	TimeZone     string       `json:"timezone" example:"Europe/Warsaw"`
+	WorkingHours WorkingHours `json:"working_hours"`
+	// MinNoticeMinutes is how far ahead meetings have to be booked.
+	MinNoticeMinutes int `json:"min_notice_minutes" example:"120"`
+	// MaxMeetingsPerDay caps the meetings booked on a day; zero means no cap.
+	MaxMeetingsPerDay int `json:"max_meetings_per_day" example:"5"`
+	// MaxMeetingsPerWeek caps the meetings booked from Monday to Sunday; zero means no cap.
+	MaxMeetingsPerWeek int `json:"max_meetings_per_week" example:"20"`
+	// BufferBeforeMinutes and BufferAfterMinutes are kept free around every meeting.
+	BufferBeforeMinutes int `json:"buffer_before_minutes" example:"5"`
+	BufferAfterMinutes  int `json:"buffer_after_minutes" example:"10"`
+	// MaxContinuousMinutes is the longest run of meetings allowed without a break of at
+	// least BreakMinutes; zero means no limit.
+	MaxContinuousMinutes int `json:"max_continuous_minutes" example:"120"`
+	BreakMinutes         int `json:"break_minutes" example:"15"`
+}
+
+// DefaultPreferences returns the preferences of a user who has not set any: nine to
+// five on weekdays in timeZone.
+func DefaultPreferences(email, timeZone string) Preferences {
+	hours := WorkingHours{}
+	for day := time.Monday; day <= time.Friday; day++ {
+		hours[weekdayName(day)] = []HourRange{{Start: defaultWorkStart, End: defaultWorkEnd}}
+	}
+	return Preferences{Email: email, TimeZone: timeZone, WorkingHours: hours}
+}
+
+func weekdayName(day time.Weekday) string {
+	return strings.ToLower(day.String())
+}
+
+// Validate checks that the time zone exists and that every day's working hours are
+// ordered, non-overlapping ranges within the day.
+func (p Preferences) Validate() error {
+	if _, err := time.LoadLocation(p.TimeZone); err != nil || p.TimeZone == "" {
+		return fmt.Errorf("%w: timezone %q is not an IANA time zone name", ErrInvalidPreferences, p.TimeZone)
+	}
+
+	days := map[string]bool{}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		days[weekdayName(day)] = true
+	}
+	for day, ranges := range p.WorkingHours {
+		if !days[day] {
+			return fmt.Errorf("%w: %q is not a weekday name", ErrInvalidPreferences, day)
+		}
+		var previousEnd time.Duration
+		for _, r := range ranges {
+			if r.Start < previousEnd || r.End <= r.Start || r.End > 24*time.Hour {
+				return fmt.Errorf("%w: working hours on %s must be ordered, non-overlapping ranges within the day", ErrInvalidPreferences, day)
+			}
+			previousEnd = r.End
+		}
+	}
+
+	for _, value := range []int{p.MinNoticeMinutes, p.MaxMeetingsPerDay, p.MaxMeetingsPerWeek, p.BufferBeforeMinutes,
+		p.BufferAfterMinutes, p.MaxContinuousMinutes, p.BreakMinutes} {
+		if value < 0 {
+			return fmt.Errorf("%w: limits, buffers and breaks must not be negative", ErrInvalidPreferences)
+		}
+	}
+
+	return nil
+}
+
+// Schedule returns the working hours of p in its time zone.
+func (p Preferences) Schedule() (*Schedule, error) {
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: timezone %q is not an IANA time zone name", ErrInvalidPreferences, p.TimeZone)
+	}
+
+	schedule := &Schedule{Location: loc}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		schedule.Days[day] = p.WorkingHours[weekdayName(day)]
+	}
+	return schedule, nil
+}
+
+// Schedule is a set of weekly working hours in a time zone.
+type Schedule struct {
+	Location *time.Location
+	// Days holds the working hours of each day, indexed by time.Weekday.
+	Days [7][]HourRange
+}
+
+// everyDay returns a schedule working hours on every day in loc.
+func everyDay(loc *time.Location, hours HourRange) *Schedule {
+	schedule := &Schedule{Location: loc}
+	for day := range schedule.Days {
+		schedule.Days[day] = []HourRange{hours}
+	}
+	return schedule
+}
+
+// windows returns the working time of the schedule between from and to, clipped to
+// them, merged and in chronological order.
+func (s *Schedule) windows(from, to time.Time) []interval {
+	loc := s.Location
+	var windows []interval
+	for day := atClock(from.In(loc), 0, loc); day.Before(to); day = atClock(day.AddDate(0, 0, 1), 0, loc) {
+		for _, hours := range s.Days[day.Weekday()] {
+			window := interval{start: maxTime(atClock(day, hours.Start, loc), from), end: minTime(atClock(day, hours.End, loc), to)}
+			if window.end.After(window.start) {
+				windows = append(windows, window)
+			}
+		}
+	}
+	return mergeIntervals(windows)
+}
+
+// offHours returns the time between from and to outside the schedule's working hours.
+func (s *Schedule) offHours(from, to time.Time) []interval {
+	var off []interval
+	cursor := from
+	for _, window := range s.windows(from, to) {
+		if window.start.After(cursor) {
+			off = append(off, interval{start: cursor, end: window.start})
+		}
+		cursor = window.end
+	}
+	if cursor.Before(to) {
+		off = append(off, interval{start: cursor, end: to})
+	}
+	return off
+}
+
+// GetPreferences returns the preferences of the user with email, or the defaults in
+// UTC if they have not set any.
+func (m *Models) GetPreferences(email string) (Preferences, error) {
+	query := `
+		SELECT p.timezone, p.working_hours, p.min_notice_minutes, p.max_meetings_per_day, p.max_meetings_per_week,
+			p.buffer_before_minutes, p.buffer_after_minutes, p.max_continuous_minutes, p.break_minutes
+		FROM users u LEFT JOIN user_preferences p ON p.email = u.email
+		WHERE u.email = $1
+	`
+
+	var timeZone, workingHours sql.NullString
+	var minNotice, maxPerDay, maxPerWeek, bufferBefore, bufferAfter, maxContinuous, breakLength sql.NullInt64
+	err := m.DB.QueryRow(query, email).Scan(&timeZone, &workingHours, &minNotice, &maxPerDay, &maxPerWeek,
+		&bufferBefore, &bufferAfter, &maxContinuous, &breakLength)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Preferences{}, ErrUserNotFound
+	}
+	if err != nil {
+		return Preferences{}, fmt.Errorf("failed to get preferences: %w", err)
+	}
+	if !timeZone.Valid {
+		return DefaultPreferences(email, "UTC"), nil
+	}
+
+	prefs := Preferences{
+		Email:                email,
+		TimeZone:             timeZone.String,
+		WorkingHours:         WorkingHours{},
+		MinNoticeMinutes:     int(minNotice.Int64),
+		MaxMeetingsPerDay:    int(maxPerDay.Int64),
+		MaxMeetingsPerWeek:   int(maxPerWeek.Int64),
+		BufferBeforeMinutes:  int(bufferBefore.Int64),
+		BufferAfterMinutes:   int(bufferAfter.Int64),
+		MaxContinuousMinutes: int(maxContinuous.Int64),
+		BreakMinutes:         int(breakLength.Int64),
+	}
+	err = json.Unmarshal([]byte(workingHours.String), &prefs.WorkingHours)
+	if err != nil {
+		return Preferences{}, fmt.Errorf("failed to decode working hours: %w", err)
+	}
+
+	return prefs, nil
+}
+
+// SavePreferences validates prefs and stores them, replacing the user's earlier
+// preferences.
+func (m *Models) SavePreferences(prefs Preferences) error {
+	err := prefs.Validate()
+	if err != nil {
+		return err
+	}
+
+	workingHours, err := json.Marshal(prefs.WorkingHours)
+	if err != nil {
+		return fmt.Errorf("failed to encode working hours: %w", err)
+	}
+
+	query := `
+		INSERT INTO user_preferences (email, timezone, working_hours, min_notice_minutes, max_meetings_per_day,
+			max_meetings_per_week, buffer_before_minutes, buffer_after_minutes, max_continuous_minutes, break_minutes)
+		SELECT $1, $2::text, $3::jsonb, $4::int, $5::int, $6::int, $7::int, $8::int, $9::int, $10::int
+		WHERE EXISTS (SELECT 1 FROM users WHERE email = $1)
+		ON CONFLICT (email) DO UPDATE SET
+			timezone = EXCLUDED.timezone,
+			working_hours = EXCLUDED.working_hours,
+			min_notice_minutes = EXCLUDED.min_notice_minutes,
+			max_meetings_per_day = EXCLUDED.max_meetings_per_day,
+			max_meetings_per_week = EXCLUDED.max_meetings_per_week,
+			buffer_before_minutes = EXCLUDED.buffer_before_minutes,
+			buffer_after_minutes = EXCLUDED.buffer_after_minutes,
+			max_continuous_minutes = EXCLUDED.max_continuous_minutes,
+			break_minutes = EXCLUDED.break_minutes,
+			updated_at = NOW()
+	`
+	result, err := m.DB.Exec(query, prefs.Email, prefs.TimeZone, string(workingHours), prefs.MinNoticeMinutes,
+		prefs.MaxMeetingsPerDay, prefs.MaxMeetingsPerWeek, prefs.BufferBeforeMinutes, prefs.BufferAfterMinutes,
+		prefs.MaxContinuousMinutes, prefs.BreakMinutes)
+	if err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+
+	saved, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+	if saved == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// InitPreferences stores default preferences for a user who has none yet, in the time
+// zone of their Google Calendar settings.
+func (m *Models) InitPreferences(ctx context.Context, email string, token *oauth2.Token) error {
+	var exists bool
+	queryExists := `SELECT EXISTS (SELECT 1 FROM user_preferences WHERE email = $1)`
+	err := m.DB.QueryRow(queryExists, email).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to look up preferences: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	timeZone, err := m.Calendar.TimeZone(ctx, token, email)
+	if err != nil {
+		return err
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		timeZone = "UTC"
+	}
+
+	prefs := DefaultPreferences(email, timeZone)
+	workingHours, err := json.Marshal(prefs.WorkingHours)
+	if err != nil {
+		return fmt.Errorf("failed to encode working hours: %w", err)
+	}
+
+	query := `
+		INSERT INTO user_preferences (email, timezone, working_hours)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (email) DO NOTHING
+	`
+	_, err = m.DB.Exec(query, email, prefs.TimeZone, string(workingHours))
+	if err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+
+	return nil
+}
+
+// unavailableTime returns the time between opts.From and opts.To in which the user
+// with email cannot take a new meeting given their meetings: the time outside their
+// working hours and the time kept free by their booking constraints.
+func (m *Models) unavailableTime(email string, meetings []interval, opts SlotOptions) ([]interval, error) {
+	prefs, err := m.GetPreferences(email)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := prefs.Schedule()
+	if err != nil {
+		return nil, err
+	}
+
+	constraints := opts.Overrides.apply(prefs.Constraints())
+	unavailable := schedule.offHours(opts.From, opts.To)
+	return append(unavailable, constraints.blocked(meetings, opts.From, time.Now(), schedule.Location)...), nil
+}
+
+// acrossSchedules returns opts searching whole days, for searches across people whose
+// time outside working hours is already part of their busy time.
+func acrossSchedules(opts SlotOptions) SlotOptions {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	opts.Schedule = everyDay(loc, HourRange{Start: 0, End: 24 * time.Hour})
+	return opts
+}
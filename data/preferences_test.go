@@ -0,0 +1,206 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"golang.org/x/oauth2"
+)
+
+// expectPreferences expects the preferences of a user who has not set any.
+func expectPreferences(mock sqlmock.Sqlmock, email string) {
+	mock.ExpectQuery(`SELECT p.timezone, p.working_hours`).
+		WithArgs(email).
+		WillReturnRows(preferenceRows().AddRow(nil, nil, nil, nil, nil, nil, nil, nil, nil))
+}
+
+func preferenceRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"timezone", "working_hours", "min_notice_minutes", "max_meetings_per_day",
+		"max_meetings_per_week", "buffer_before_minutes", "buffer_after_minutes", "max_continuous_minutes", "break_minutes"})
+}
+
+func TestHourRangeJSON(t *testing.T) {
+	var hours WorkingHours
+	err := json.Unmarshal([]byte(`{"monday": [{"start": "08:30", "end": "12:00"}, {"start": "20:00", "end": "24:00"}]}`), &hours)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	monday := hours["monday"]
+	if len(monday) != 2 || monday[0].Start != 8*time.Hour+30*time.Minute || monday[1].End != 24*time.Hour {
+		t.Fatalf("unexpected working hours: %v", monday)
+	}
+
+	b, err := json.Marshal(monday[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(b) != `{"start":"08:30","end":"12:00"}` {
+		t.Errorf("unexpected JSON: %s", b)
+	}
+
+	err = json.Unmarshal([]byte(`{"start": "9am", "end": "17:00"}`), &HourRange{})
+	if err == nil {
+		t.Errorf("expected an error for an invalid time")
+	}
+}
+
+func TestPreferencesValidate(t *testing.T) {
+	valid := DefaultPreferences("anna@example.com", "Asia/Kolkata")
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected defaults to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(p *Preferences)
+	}{
+		{"unknown time zone", func(p *Preferences) { p.TimeZone = "Mars/Olympus" }},
+		{"unknown day", func(p *Preferences) { p.WorkingHours["someday"] = nil }},
+		{"overlapping shifts", func(p *Preferences) {
+			p.WorkingHours["monday"] = []HourRange{{Start: 9 * time.Hour, End: 13 * time.Hour}, {Start: 12 * time.Hour, End: 17 * time.Hour}}
+		}},
+		{"empty shift", func(p *Preferences) {
+			p.WorkingHours["friday"] = []HourRange{{Start: 9 * time.Hour, End: 9 * time.Hour}}
+		}},
+		{"negative notice", func(p *Preferences) { p.MinNoticeMinutes = -1 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := DefaultPreferences("anna@example.com", "UTC")
+			tt.change(&prefs)
+			if err := prefs.Validate(); !errors.Is(err, ErrInvalidPreferences) {
+				t.Errorf("expected ErrInvalidPreferences, got %v", err)
+			}
+		})
+	}
+}
+
+func TestScheduleWindows(t *testing.T) {
+	prefs := DefaultPreferences("anna@example.com", "America/New_York")
+	prefs.WorkingHours["saturday"] = []HourRange{{Start: 10 * time.Hour, End: 12 * time.Hour}, {Start: 13 * time.Hour, End: 15 * time.Hour}}
+	schedule, err := prefs.Schedule()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Friday 2024-05-10 to Sunday 2024-05-12 in UTC
+	from := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	windows := schedule.windows(from, from.Add(3*24*time.Hour))
+
+	expected := []interval{
+		{start: time.Date(2024, 5, 10, 13, 0, 0, 0, time.UTC), end: time.Date(2024, 5, 10, 21, 0, 0, 0, time.UTC)},
+		{start: time.Date(2024, 5, 11, 14, 0, 0, 0, time.UTC), end: time.Date(2024, 5, 11, 16, 0, 0, 0, time.UTC)},
+		{start: time.Date(2024, 5, 11, 17, 0, 0, 0, time.UTC), end: time.Date(2024, 5, 11, 19, 0, 0, 0, time.UTC)},
+	}
+	if len(windows) != len(expected) {
+		t.Fatalf("expected %d windows, got %v", len(expected), windows)
+	}
+	for i := range expected {
+		if !windows[i].start.Equal(expected[i].start) || !windows[i].end.Equal(expected[i].end) {
+			t.Errorf("window %d: expected %v, got %v", i, expected[i], windows[i])
+		}
+	}
+
+	off := schedule.offHours(from, from.Add(24*time.Hour))
+	if len(off) != 2 || !off[0].end.Equal(expected[0].start) || !off[1].start.Equal(expected[0].end) {
+		t.Errorf("unexpected off hours: %v", off)
+	}
+}
+
+func TestGetFreeSlotsUsesWorkingHours(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	models.Calendar = NewFakeCalendar()
+
+	email := "ravi@example.com"
+	expectToken(mock, email, "ravi-token")
+	mock.ExpectQuery(`SELECT p.timezone, p.working_hours`).
+		WithArgs(email).
+		WillReturnRows(preferenceRows().
+			AddRow("Asia/Kolkata", `{"monday": [{"start": "10:00", "end": "13:00"}, {"start": "14:00", "end": "18:30"}]}`, 0, 0, 0, 0, 0, 0, 0))
+	expectHolds(mock)
+
+	// Monday 2024-05-06 in UTC; Kolkata is UTC+5:30
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	slots, err := models.GetFreeSlots(context.Background(), email, SlotOptions{
+		From:        day,
+		To:          day.Add(24 * time.Hour),
+		MinDuration: 30 * time.Minute,
+		Location:    time.UTC,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(slots) != 2 ||
+		!slots[0].Start.Equal(day.Add(4*time.Hour+30*time.Minute)) || !slots[0].End.Equal(day.Add(7*time.Hour+30*time.Minute)) ||
+		!slots[1].Start.Equal(day.Add(8*time.Hour+30*time.Minute)) || !slots[1].End.Equal(day.Add(13*time.Hour)) {
+		t.Errorf("expected the split shift in UTC, got %v", slots)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestInitPreferences(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	fake.SetTimeZone("anna@example.com", "Europe/Warsaw")
+	models := NewModels(db)
+	models.Calendar = fake
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM user_preferences`).
+		WithArgs("anna@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`INSERT INTO user_preferences`).
+		WithArgs("anna@example.com", "Europe/Warsaw", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM user_preferences`).
+		WithArgs("anna@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	token := &oauth2.Token{AccessToken: "anna-token"}
+	if err := models.InitPreferences(context.Background(), "anna@example.com", token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Preferences the user already has are never replaced
+	if err := models.InitPreferences(context.Background(), "anna@example.com", token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSavePreferencesUnknownUser(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+
+	mock.ExpectExec(`INSERT INTO user_preferences`).
+		WithArgs("nobody@example.com", "UTC", sqlmock.AnyArg(), 0, 0, 0, 0, 0, 0, 0).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := models.SavePreferences(DefaultPreferences("nobody@example.com", "UTC"))
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
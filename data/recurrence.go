@@ -0,0 +1,412 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRecurrence is returned for recurrence rules outside the supported subset.
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+// maxRecurrenceSpan is how far after its start a series has to end, so that every
+// occurrence can be checked against the attendees' calendars.
+const maxRecurrenceSpan = 366 * 24 * time.Hour
+
+const (
+	// recurrenceCheckChunk is the longest window read from calendars at once when
+	// checking the occurrences of a series.
+	recurrenceCheckChunk = 31 * 24 * time.Hour
+	// maxAlternatives is the number of alternative times suggested for a conflicting occurrence.
+	maxAlternatives = 3
+)
+
+// Recurrence frequencies supported in RRULEs.
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+var rruleDays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// RecurrenceDay is a BYDAY entry: a weekday, with an ordinal within the month for
+// monthly rules such as 2TU (second Tuesday) or -1FR (last Friday).
+type RecurrenceDay struct {
+	Ordinal int
+	Day     time.Weekday
+}
+
+// Recurrence is a parsed RRULE limited to FREQ=DAILY, WEEKLY or MONTHLY with
+// INTERVAL, BYDAY and either COUNT or UNTIL.
+type Recurrence struct {
+	Freq     string
+	Interval int
+	Count    int
+	Until    time.Time
+	ByDay    []RecurrenceDay
+}
+
+// ParseRecurrence parses an RRULE such as "RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10".
+// The "RRULE:" prefix is optional. Series have to be bounded by COUNT or UNTIL.
+func ParseRecurrence(rule string) (*Recurrence, error) {
+	r := &Recurrence{Interval: 1}
+	body := strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if body == "" {
+		return nil, fmt.Errorf("%w: rule is empty", ErrInvalidRecurrence)
+	}
+
+	for _, part := range strings.Split(body, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not NAME=VALUE", ErrInvalidRecurrence, part)
+		}
+
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+			if r.Freq != FreqDaily && r.Freq != FreqWeekly && r.Freq != FreqMonthly {
+				return nil, fmt.Errorf("%w: FREQ must be DAILY, WEEKLY or MONTHLY", ErrInvalidRecurrence)
+			}
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err != nil || r.Interval < 1 {
+				return nil, fmt.Errorf("%w: INTERVAL must be a positive number", ErrInvalidRecurrence)
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+			if err != nil || r.Count < 1 {
+				return nil, fmt.Errorf("%w: COUNT must be a positive number", ErrInvalidRecurrence)
+			}
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+		case "BYDAY":
+			for _, entry := range strings.Split(value, ",") {
+				day, err := parseRecurrenceDay(entry)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s is not supported", ErrInvalidRecurrence, name)
+		}
+	}
+
+	switch {
+	case r.Freq == "":
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRecurrence)
+	case r.Count == 0 && r.Until.IsZero():
+		return nil, fmt.Errorf("%w: COUNT or UNTIL is required", ErrInvalidRecurrence)
+	case r.Count > 0 && !r.Until.IsZero():
+		return nil, fmt.Errorf("%w: COUNT and UNTIL cannot be combined", ErrInvalidRecurrence)
+	}
+	for _, day := range r.ByDay {
+		if day.Ordinal != 0 && r.Freq != FreqMonthly {
+			return nil, fmt.Errorf("%w: BYDAY ordinals are only supported with FREQ=MONTHLY", ErrInvalidRecurrence)
+		}
+	}
+
+	return r, nil
+}
+
+// parseUntil parses an UNTIL value, a UTC date-time or a date.
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if until, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			return until, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: UNTIL must be a date such as 20240630 or a UTC time such as 20240630T170000Z", ErrInvalidRecurrence)
+}
+
+// parseRecurrenceDay parses a BYDAY entry such as MO, 2TU or -1FR.
+func parseRecurrenceDay(entry string) (RecurrenceDay, error) {
+	entry = strings.ToUpper(strings.TrimSpace(entry))
+	if len(entry) < 2 {
+		return RecurrenceDay{}, fmt.Errorf("%w: %q is not a BYDAY value", ErrInvalidRecurrence, entry)
+	}
+
+	day, ok := rruleDays[entry[len(entry)-2:]]
+	if !ok {
+		return RecurrenceDay{}, fmt.Errorf("%w: %q is not a BYDAY value", ErrInvalidRecurrence, entry)
+	}
+
+	var ordinal int
+	if prefix := entry[:len(entry)-2]; prefix != "" {
+		var err error
+		ordinal, err = strconv.Atoi(prefix)
+		if err != nil || ordinal == 0 || ordinal < -5 || ordinal > 5 {
+			return RecurrenceDay{}, fmt.Errorf("%w: %q is not a BYDAY value", ErrInvalidRecurrence, entry)
+		}
+	}
+
+	return RecurrenceDay{Ordinal: ordinal, Day: day}, nil
+}
+
+// String formats r as an RRULE line for a calendar event.
+func (r *Recurrence) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, day := range r.ByDay {
+			name := strings.ToUpper(day.Day.String()[:2])
+			if day.Ordinal != 0 {
+				name = strconv.Itoa(day.Ordinal) + name
+			}
+			days = append(days, name)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return "RRULE:" + strings.Join(parts, ";")
+}
+
+// Occurrences returns the start times of the series starting at start, at the same
+// wall-clock time in start's location. start has to be the first occurrence, and the
+// series has to end within a year.
+func (r *Recurrence) Occurrences(start time.Time) ([]time.Time, error) {
+	limit := start.Add(maxRecurrenceSpan)
+
+	var occurrences []time.Time
+	done := func(t time.Time) bool {
+		return (r.Count > 0 && len(occurrences) == r.Count) || (!r.Until.IsZero() && t.After(r.Until))
+	}
+
+	for period := 0; ; period += r.Interval {
+		candidates, periodStart := r.candidates(start, period)
+		if periodStart.After(limit) {
+			return nil, fmt.Errorf("%w: the series has to end within a year", ErrInvalidRecurrence)
+		}
+
+		for _, t := range candidates {
+			if t.Before(start) {
+				continue
+			}
+			if done(t) {
+				if len(occurrences) == 0 || !occurrences[0].Equal(start) {
+					return nil, fmt.Errorf("%w: the start time has to be the first occurrence", ErrInvalidRecurrence)
+				}
+				return occurrences, nil
+			}
+			if t.After(limit) {
+				return nil, fmt.Errorf("%w: the series has to end within a year", ErrInvalidRecurrence)
+			}
+			occurrences = append(occurrences, t)
+		}
+	}
+}
+
+// candidates returns the occurrence times of the period-th day, week or month after
+// start's, in order, with the start of that period.
+func (r *Recurrence) candidates(start time.Time, period int) ([]time.Time, time.Time) {
+	loc := start.Location()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, start.Hour(), start.Minute(), start.Second(), 0, loc)
+	}
+	y, m, d := start.Date()
+
+	var times []time.Time
+	switch r.Freq {
+	case FreqDaily:
+		day := at(y, m, d+period)
+		if len(r.ByDay) == 0 || r.hasWeekday(day.Weekday()) {
+			times = append(times, day)
+		}
+		return times, day
+
+	case FreqWeekly:
+		monday := d - (int(start.Weekday())+6)%7 + 7*period
+		days := []time.Weekday{start.Weekday()}
+		if len(r.ByDay) > 0 {
+			days = nil
+			for _, day := range r.ByDay {
+				days = append(days, day.Day)
+			}
+		}
+		for _, day := range days {
+			times = append(times, at(y, m, monday+(int(day)+6)%7))
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+		return times, at(y, m, monday)
+
+	default:
+		first := at(y, m+time.Month(period), 1)
+		fy, fm, _ := first.Date()
+		daysInMonth := time.Date(fy, fm+1, 0, 0, 0, 0, 0, loc).Day()
+		if len(r.ByDay) == 0 {
+			if d <= daysInMonth {
+				times = append(times, at(fy, fm, d))
+			}
+			return times, first
+		}
+		for _, byDay := range r.ByDay {
+			var days []int
+			for day := 1; day <= daysInMonth; day++ {
+				if time.Date(fy, fm, day, 0, 0, 0, 0, loc).Weekday() == byDay.Day {
+					days = append(days, day)
+				}
+			}
+			switch {
+			case byDay.Ordinal == 0:
+				for _, day := range days {
+					times = append(times, at(fy, fm, day))
+				}
+			case byDay.Ordinal > 0 && byDay.Ordinal <= len(days):
+				times = append(times, at(fy, fm, days[byDay.Ordinal-1]))
+			case byDay.Ordinal < 0 && -byDay.Ordinal <= len(days):
+				times = append(times, at(fy, fm, days[len(days)+byDay.Ordinal]))
+			}
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+		return times, first
+	}
+}
+
+func (r *Recurrence) hasWeekday(day time.Weekday) bool {
+	for _, byDay := range r.ByDay {
+		if byDay.Day == day {
+			return true
+		}
+	}
+	return false
+}
+
+// OccurrenceConflict is an occurrence of a series at which some attendees cannot meet,
+// with other times on the same day at which everyone can.
+type OccurrenceConflict struct {
+	Start        time.Time  `json:"start" format:"date-time" example:"2024-05-13T10:00:00+02:00"`
+	End          time.Time  `json:"end" format:"date-time" example:"2024-05-13T10:30:00+02:00"`
+	Busy         []string   `json:"busy" example:"bob@example.com"`
+	Alternatives []TimeSlot `json:"alternatives"`
+}
+
+// seriesConflicts checks the occurrences of a series of meetings of the given length
+// against the calendars and working hours of people. Alternatives are the times on
+// the same day in loc closest to the occurrence at which everyone is free. People
+// whose calendars cannot be read are skipped.
+func (m *Models) seriesConflicts(ctx context.Context, people []string, occurrences []time.Time, length time.Duration, loc *time.Location) ([]OccurrenceConflict, error) {
+	from := atClock(occurrences[0].In(loc), 0, loc)
+	to := atClock(occurrences[len(occurrences)-1].In(loc), 24*time.Hour, loc)
+
+	busy := make([][]interval, len(people))
+	skipped := make([]bool, len(people))
+	for chunkFrom := from; chunkFrom.Before(to); {
+		chunkTo := minTime(chunkFrom.Add(recurrenceCheckChunk), to)
+		for i, result := range m.membersBusy(ctx, people, SlotOptions{From: chunkFrom, To: chunkTo}) {
+			if result.err != nil {
+				return nil, fmt.Errorf("failed to read calendar of %s: %w", people[i], result.err)
+			}
+			if result.reason != "" {
+				skipped[i] = true
+			}
+			busy[i] = append(busy[i], result.busy...)
+		}
+		chunkFrom = chunkTo
+	}
+
+	var all []interval
+	for i, email := range people {
+		if skipped[i] {
+			continue
+		}
+		prefs, err := m.GetPreferences(email)
+		if err != nil {
+			return nil, err
+		}
+		schedule, err := prefs.Schedule()
+		if err != nil {
+			return nil, err
+		}
+		busy[i] = mergeIntervals(append(busy[i], schedule.offHours(from, to)...))
+		all = append(all, busy[i]...)
+	}
+
+	var conflicts []OccurrenceConflict
+	for _, start := range occurrences {
+		slot := interval{start: start, end: start.Add(length)}
+
+		var unavailable []string
+		for i, email := range people {
+			if !skipped[i] && overlapsAny(slot, busy[i]) {
+				unavailable = append(unavailable, email)
+			}
+		}
+		if len(unavailable) == 0 {
+			continue
+		}
+
+		conflicts = append(conflicts, OccurrenceConflict{
+			Start:        slot.start,
+			End:          slot.end,
+			Busy:         unavailable,
+			Alternatives: alternativeTimes(all, slot, loc),
+		})
+	}
+
+	return conflicts, nil
+}
+
+// alternativeTimes returns up to maxAlternatives slots as long as slot that avoid busy,
+// on the same day as slot in loc, closest to slot first.
+func alternativeTimes(busy []interval, slot interval, loc *time.Location) []TimeSlot {
+	length := slot.end.Sub(slot.start)
+	day := acrossSchedules(SlotOptions{
+		From:        atClock(slot.start, 0, loc),
+		To:          atClock(slot.start, 24*time.Hour, loc),
+		MinDuration: length,
+		Location:    loc,
+	})
+
+	var starts []time.Time
+	for _, free := range freeIntervals(busy, day) {
+		start := free.start.Truncate(suggestionStep)
+		if start.Before(free.start) {
+			start = start.Add(suggestionStep)
+		}
+		for ; !start.Add(length).After(free.end); start = start.Add(suggestionStep) {
+			starts = append(starts, start)
+		}
+	}
+
+	distance := func(t time.Time) time.Duration {
+		if d := t.Sub(slot.start); d >= 0 {
+			return d
+		}
+		return slot.start.Sub(t)
+	}
+	sort.SliceStable(starts, func(i, j int) bool {
+		return distance(starts[i]) < distance(starts[j])
+	})
+
+	alternatives := []TimeSlot{}
+	for _, start := range starts {
+		if len(alternatives) == maxAlternatives {
+			break
+		}
+		alternatives = append(alternatives, NewTimeSlot(start.In(loc), start.Add(length).In(loc), SlotFree))
+	}
+	return alternatives
+}
@@ -0,0 +1,140 @@
+package data
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	valid := map[string]string{
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10":    "RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10",
+		"FREQ=daily;INTERVAL=2;UNTIL=20240630":      "RRULE:FREQ=DAILY;INTERVAL=2;UNTIL=20240630T235959Z",
+		"RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=6":     "RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=6",
+		"RRULE:FREQ=MONTHLY;UNTIL=20241231T170000Z": "RRULE:FREQ=MONTHLY;UNTIL=20241231T170000Z",
+	}
+	for rule, expected := range valid {
+		r, err := ParseRecurrence(rule)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", rule, err)
+			continue
+		}
+		if r.String() != expected {
+			t.Errorf("%s: expected %s, got %s", rule, expected, r.String())
+		}
+	}
+
+	invalid := []string{
+		"",
+		"RRULE:FREQ=YEARLY;COUNT=2",
+		"RRULE:FREQ=WEEKLY",
+		"RRULE:FREQ=WEEKLY;COUNT=2;UNTIL=20240630",
+		"RRULE:FREQ=WEEKLY;BYDAY=2MO;COUNT=2",
+		"RRULE:FREQ=WEEKLY;BYDAY=XX;COUNT=2",
+		"RRULE:FREQ=WEEKLY;BYMONTH=2;COUNT=2",
+		"RRULE:FREQ=DAILY;INTERVAL=0;COUNT=2",
+	}
+	for _, rule := range invalid {
+		if _, err := ParseRecurrence(rule); !errors.Is(err, ErrInvalidRecurrence) {
+			t.Errorf("%q: expected ErrInvalidRecurrence, got %v", rule, err)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+
+	tests := []struct {
+		name     string
+		rule     string
+		start    time.Time
+		expected []time.Time
+	}{
+		{
+			name:  "weekly on two days",
+			rule:  "RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+			start: time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC),
+				time.Date(2024, 5, 8, 10, 0, 0, 0, time.UTC),
+				time.Date(2024, 5, 13, 10, 0, 0, 0, time.UTC),
+				time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "daily on weekdays until a date",
+			rule:  "RRULE:FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;UNTIL=20240513",
+			start: time.Date(2024, 5, 9, 9, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 5, 9, 9, 0, 0, 0, time.UTC),
+				time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC),
+				time.Date(2024, 5, 13, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "last Friday of the month",
+			rule:  "RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			start: time.Date(2024, 5, 31, 15, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 5, 31, 15, 0, 0, 0, time.UTC),
+				time.Date(2024, 6, 28, 15, 0, 0, 0, time.UTC),
+				time.Date(2024, 7, 26, 15, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "monthly skips months without the day",
+			rule:  "RRULE:FREQ=MONTHLY;COUNT=3",
+			start: time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC),
+				time.Date(2024, 5, 31, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "keeps the wall clock time across daylight saving time",
+			rule:  "RRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=2",
+			start: time.Date(2024, 3, 25, 10, 0, 0, 0, warsaw).AddDate(0, 0, -14),
+			expected: []time.Time{
+				time.Date(2024, 3, 11, 10, 0, 0, 0, warsaw),
+				time.Date(2024, 3, 25, 10, 0, 0, 0, warsaw),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			occurrences, err := r.Occurrences(tt.start)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(occurrences) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, occurrences)
+			}
+			for i := range tt.expected {
+				if !occurrences[i].Equal(tt.expected[i]) {
+					t.Errorf("occurrence %d: expected %v, got %v", i, tt.expected[i], occurrences[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOccurrencesRejectsInvalidSeries(t *testing.T) {
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+
+	// The series starts on a Monday but only repeats on Tuesdays
+	r, _ := ParseRecurrence("RRULE:FREQ=WEEKLY;BYDAY=TU;COUNT=3")
+	if _, err := r.Occurrences(start); !errors.Is(err, ErrInvalidRecurrence) {
+		t.Errorf("expected ErrInvalidRecurrence for a start that is not an occurrence, got %v", err)
+	}
+
+	r, _ = ParseRecurrence("RRULE:FREQ=WEEKLY;COUNT=60")
+	if _, err := r.Occurrences(start); !errors.Is(err, ErrInvalidRecurrence) {
+		t.Errorf("expected ErrInvalidRecurrence for a series longer than a year, got %v", err)
+	}
+}
@@ -0,0 +1,295 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrResourceNotFound is returned when no resource has the calendar ID.
+	ErrResourceNotFound = errors.New("resource not found")
+	// ErrResourceExists is returned when registering a calendar that is already a resource.
+	ErrResourceExists = errors.New("resource already exists")
+	// ErrNoRoomAvailable is returned when no room matching a requirement is free.
+	ErrNoRoomAvailable = errors.New("no matching room is available")
+)
+
+// Resource is a bookable room, registered as a Google resource calendar.
+type Resource struct {
+	// CalendarID is the email address of the resource calendar.
+	CalendarID string   `json:"calendar_id" example:"c_1888abc@resource.calendar.google.com"`
+	Name       string   `json:"name" example:"Krakow-3-Wawel"`
+	Capacity   int      `json:"capacity" example:"8"`
+	Building   string   `json:"building" example:"Krakow HQ"`
+	Features   []string `json:"features" example:"video,whiteboard"`
+}
+
+// Validate checks that r can be stored.
+func (r Resource) Validate() error {
+	switch {
+	case r.CalendarID == "":
+		return errors.New("calendar_id is required")
+	case r.Name == "":
+		return errors.New("name is required")
+	case r.Capacity < 1:
+		return errors.New("capacity must be at least 1")
+	}
+	return nil
+}
+
+// RoomRequirement describes the room a meeting needs. The zero value needs no room.
+type RoomRequirement struct {
+	// Capacity is the least number of seats.
+	Capacity int    `json:"capacity" example:"6"`
+	Building string `json:"building,omitempty" example:"Krakow HQ"`
+	// Features lists what the room has to offer, such as video.
+	Features []string `json:"features,omitempty" example:"video"`
+}
+
+// IsZero reports whether req asks for no room.
+func (req RoomRequirement) IsZero() bool {
+	return req.Capacity == 0 && req.Building == "" && len(req.Features) == 0
+}
+
+// matches reports whether r meets req.
+func (req RoomRequirement) matches(r Resource) bool {
+	if r.Capacity < req.Capacity || (req.Building != "" && !strings.EqualFold(r.Building, req.Building)) {
+		return false
+	}
+	for _, feature := range req.Features {
+		found := false
+		for _, has := range r.Features {
+			if strings.EqualFold(has, feature) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateResource registers a resource calendar. It returns ErrResourceExists if the
+// calendar is already registered.
+func (m *Models) CreateResource(r Resource) error {
+	query := `
+		INSERT INTO resources (calendar_id, name, capacity, building, features)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (calendar_id) DO NOTHING
+	`
+	result, err := m.DB.Exec(query, r.CalendarID, r.Name, r.Capacity, r.Building, strings.Join(r.Features, ","))
+	if err != nil {
+		return fmt.Errorf("failed to save resource: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save resource: %w", err)
+	}
+	if rows == 0 {
+		return ErrResourceExists
+	}
+
+	return nil
+}
+
+// UpdateResource replaces the details of the resource with r.CalendarID.
+func (m *Models) UpdateResource(r Resource) error {
+	query := `
+		UPDATE resources SET name = $1, capacity = $2, building = $3, features = $4, updated_at = NOW()
+		WHERE calendar_id = $5
+	`
+	result, err := m.DB.Exec(query, r.Name, r.Capacity, r.Building, strings.Join(r.Features, ","), r.CalendarID)
+	if err != nil {
+		return fmt.Errorf("failed to update resource: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update resource: %w", err)
+	}
+	if rows == 0 {
+		return ErrResourceNotFound
+	}
+
+	return nil
+}
+
+// DeleteResource removes the resource with calendarID.
+func (m *Models) DeleteResource(calendarID string) error {
+	result, err := m.DB.Exec(`DELETE FROM resources WHERE calendar_id = $1`, calendarID)
+	if err != nil {
+		return fmt.Errorf("failed to delete resource: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete resource: %w", err)
+	}
+	if rows == 0 {
+		return ErrResourceNotFound
+	}
+
+	return nil
+}
+
+// ListResources returns the resources meeting req, smallest first so that rooms are
+// not taken away from larger meetings.
+func (m *Models) ListResources(req RoomRequirement) ([]Resource, error) {
+	query := `
+		SELECT calendar_id, name, capacity, building, features FROM resources
+		WHERE capacity >= $1
+		ORDER BY capacity, name
+	`
+	rows, err := m.DB.Query(query, req.Capacity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query resources: %w", err)
+	}
+	defer rows.Close()
+
+	resources := []Resource{}
+	for rows.Next() {
+		var r Resource
+		var features string
+		err := rows.Scan(&r.CalendarID, &r.Name, &r.Capacity, &r.Building, &features)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan resource: %w", err)
+		}
+		r.Features = splitList(features)
+		if req.matches(r) {
+			resources = append(resources, r)
+		}
+	}
+
+	return resources, nil
+}
+
+// splitList splits a comma-separated column, returning an empty slice for "".
+func splitList(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+// roomsBusy reads the busy time of rooms between from and to with token, in windows
+// of at most recurrenceCheckChunk. Rooms whose calendars cannot be read are left out
+// of the result.
+func (m *Models) roomsBusy(ctx context.Context, token *oauth2.Token, rooms []Resource, from, to time.Time) (map[string][]interval, error) {
+	ids := make([]string, len(rooms))
+	for i, room := range rooms {
+		ids[i] = room.CalendarID
+	}
+
+	busy := map[string][]interval{}
+	unreadable := map[string]bool{}
+	for chunkFrom := from; chunkFrom.Before(to); {
+		chunkTo := minTime(chunkFrom.Add(recurrenceCheckChunk), to)
+		periods, failed, err := m.Calendar.FreeBusy(ctx, token, ids, chunkFrom, chunkTo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read room calendars: %w", err)
+		}
+		for _, id := range ids {
+			if failed[id] != "" {
+				unreadable[id] = true
+				continue
+			}
+			roomBusy, _ := splitPeriods(periods[id])
+			busy[id] = append(busy[id], roomBusy...)
+		}
+		chunkFrom = chunkTo
+	}
+
+	for id := range unreadable {
+		delete(busy, id)
+	}
+	return busy, nil
+}
+
+// pickRoom returns the smallest room meeting req that is free during every slot, or
+// ErrNoRoomAvailable.
+func (m *Models) pickRoom(ctx context.Context, token *oauth2.Token, req RoomRequirement, slots []interval) (*Resource, error) {
+	rooms, err := m.ListResources(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(rooms) == 0 {
+		return nil, ErrNoRoomAvailable
+	}
+
+	busy, err := m.roomsBusy(ctx, token, rooms, slots[0].start, slots[len(slots)-1].end)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, room := range rooms {
+		roomBusy, ok := busy[room.CalendarID]
+		if !ok {
+			continue
+		}
+		free := true
+		for _, slot := range slots {
+			if overlapsAny(slot, roomBusy) {
+				free = false
+				break
+			}
+		}
+		if free {
+			picked := room
+			return &picked, nil
+		}
+	}
+
+	return nil, ErrNoRoomAvailable
+}
+
+// noRoomTime returns the periods between from and to in which none of the rooms
+// meeting req is free, which group searches treat as busy time. Every period is busy
+// when no room meets req.
+func (m *Models) noRoomTime(ctx context.Context, token *oauth2.Token, req RoomRequirement, from, to time.Time) ([]interval, error) {
+	rooms, err := m.ListResources(req)
+	if err != nil {
+		return nil, err
+	}
+
+	busy, err := m.roomsBusy(ctx, token, rooms, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start from the whole window and keep only the time every readable room is busy
+	blocked := []interval{{start: from, end: to}}
+	for _, room := range rooms {
+		roomBusy, ok := busy[room.CalendarID]
+		if !ok {
+			continue
+		}
+		blocked = intersectIntervals(blocked, mergeIntervals(roomBusy))
+	}
+	return blocked, nil
+}
+
+// intersectIntervals returns the periods covered by both a and b, which are sorted
+// and do not overlap.
+func intersectIntervals(a, b []interval) []interval {
+	var result []interval
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		start, end := maxTime(a[i].start, b[j].start), minTime(a[i].end, b[j].end)
+		if start.Before(end) {
+			result = append(result, interval{start: start, end: end})
+		}
+		if a[i].end.Before(b[j].end) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
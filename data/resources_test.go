@@ -0,0 +1,183 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func expectResources(mock sqlmock.Sqlmock, capacity int, resources ...Resource) {
+	rows := sqlmock.NewRows([]string{"calendar_id", "name", "capacity", "building", "features"})
+	for _, r := range resources {
+		rows.AddRow(r.CalendarID, r.Name, r.Capacity, r.Building, strings.Join(r.Features, ","))
+	}
+	mock.ExpectQuery(`SELECT calendar_id, name, capacity, building, features FROM resources`).
+		WithArgs(capacity).
+		WillReturnRows(rows)
+}
+
+var (
+	roomWawel   = Resource{CalendarID: "wawel@resource.example.com", Name: "Wawel", Capacity: 4, Building: "HQ", Features: []string{"video"}}
+	roomVistula = Resource{CalendarID: "vistula@resource.example.com", Name: "Vistula", Capacity: 10, Building: "HQ", Features: []string{"video", "whiteboard"}}
+)
+
+func TestListResourcesMatchesRequirement(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	attic := Resource{CalendarID: "attic@resource.example.com", Name: "Attic", Capacity: 6, Building: "Annex"}
+	expectResources(mock, 4, roomWawel, attic, roomVistula)
+
+	rooms, err := models.ListResources(RoomRequirement{Capacity: 4, Building: "hq", Features: []string{"Whiteboard"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rooms) != 1 || rooms[0].CalendarID != roomVistula.CalendarID {
+		t.Errorf("expected only the Vistula room, got %+v", rooms)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCreateResourceRejectsDuplicates(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	mock.ExpectExec(`INSERT INTO resources`).
+		WithArgs(roomVistula.CalendarID, "Vistula", 10, "HQ", "video,whiteboard").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := models.CreateResource(roomVistula); !errors.Is(err, ErrResourceExists) {
+		t.Errorf("expected ErrResourceExists, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetGroupFreeSlotsRequiresRoom(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	fake.AddBusy(roomWawel.CalendarID, BusyPeriod{Start: day.Add(9 * time.Hour), End: day.Add(12 * time.Hour), Status: SlotBusy})
+	fake.AddBusy(roomVistula.CalendarID, BusyPeriod{Start: day.Add(10 * time.Hour), End: day.Add(14 * time.Hour), Status: SlotBusy})
+
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WithArgs("design").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT user_email FROM user_groups WHERE group_name =`).
+		WithArgs("design").
+		WillReturnRows(sqlmock.NewRows([]string{"user_email"}).AddRow("anna@example.com"))
+	expectDistribution(mock, "design", "")
+	expectToken(mock, "anna@example.com", "anna-token")
+	expectHolds(mock)
+	expectPreferences(mock, "anna@example.com")
+	expectToken(mock, "anna@example.com", "anna-token")
+	expectResources(mock, 4, roomWawel, roomVistula)
+
+	availability, err := models.GetGroupFreeSlots(context.Background(), "design", SlotOptions{
+		From:        day,
+		To:          day.Add(24 * time.Hour),
+		MinDuration: 30 * time.Minute,
+		Location:    time.UTC,
+		Room:        RoomRequirement{Capacity: 4},
+	}, Quorum{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Both rooms are taken between 10 and 12
+	expected := []interval{
+		{start: day.Add(9 * time.Hour), end: day.Add(10 * time.Hour)},
+		{start: day.Add(12 * time.Hour), end: day.Add(17 * time.Hour)},
+	}
+	if len(availability.Slots) != len(expected) {
+		t.Fatalf("expected slots %v, got %v", expected, availability.Slots)
+	}
+	for i, slot := range availability.Slots {
+		if !slot.Start.Equal(expected[i].start) || !slot.End.Equal(expected[i].end) {
+			t.Errorf("expected slots %v, got %v", expected, availability.Slots)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCreateMeetingBooksFreeRoom(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	organizer := "anna@example.com"
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	// The smaller room is taken, so the larger one is booked
+	fake.AddBusy(roomWawel.CalendarID, BusyPeriod{Start: start, End: start.Add(time.Hour), Status: SlotBusy})
+
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "anna-token")
+	expectResources(mock, 3, roomWawel, roomVistula)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO meetings`).
+		WithArgs("fake-event-1", organizer, "Design sync", "", start, start.Add(30*time.Minute), MeetingScheduled,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), "", roomVistula.CalendarID, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_attendees`).WithArgs("fake-event-1", "bob@example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	meeting, err := models.CreateMeeting(context.Background(), MeetingRequest{
+		Organizer: organizer,
+		Attendees: []string{"bob@example.com"},
+		Title:     "Design sync",
+		Start:     start,
+		End:       start.Add(30 * time.Minute),
+		Room:      RoomRequirement{Capacity: 3, Features: []string{"video"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meeting.Room != roomVistula.CalendarID {
+		t.Errorf("expected the Vistula room, got %q", meeting.Room)
+	}
+	if busy := fake.periods(roomVistula.CalendarID, start, start.Add(time.Hour)); len(busy) != 1 {
+		t.Errorf("expected the room to be busy during the meeting, got %v", busy)
+	}
+
+	// Once both rooms are taken no room can be booked
+	expectScopes(mock, organizer, "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, organizer, "anna-token")
+	expectResources(mock, 3, roomWawel, roomVistula)
+
+	_, err = models.CreateMeeting(context.Background(), MeetingRequest{
+		Organizer: organizer,
+		Title:     "Another sync",
+		Start:     start,
+		End:       start.Add(30 * time.Minute),
+		Room:      RoomRequirement{Capacity: 3},
+	})
+	if !errors.Is(err, ErrNoRoomAvailable) {
+		t.Errorf("expected ErrNoRoomAvailable, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
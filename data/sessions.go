@@ -0,0 +1,585 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"calendar-extension/timeparse"
+)
+
+var (
+	// ErrSessionNotFound is returned when no session has the given ID.
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionExpired is returned for sessions that were not used within their TTL.
+	ErrSessionExpired = errors.New("session has expired")
+	// ErrSessionBooked is returned for turns on a session that has booked its meeting.
+	ErrSessionBooked = errors.New("session has already booked a meeting")
+	// ErrInvalidSession is returned for sessions that cannot be started.
+	ErrInvalidSession = errors.New("invalid session")
+	// ErrInvalidTurn is returned for turns that cannot be applied.
+	ErrInvalidTurn = errors.New("invalid turn")
+)
+
+// Session statuses recorded in the sessions table.
+const (
+	SessionActive = "active"
+	SessionBooked = "booked"
+)
+
+// Actions of the turns of a session.
+const (
+	TurnExcludeDays    = "exclude_days"
+	TurnChangeDuration = "change_duration"
+	TurnChangeWindow   = "change_window"
+	TurnAddAttendee    = "add_attendee"
+	TurnRemoveAttendee = "remove_attendee"
+	TurnPick           = "pick"
+)
+
+const (
+	defaultSessionDuration = 30
+	defaultSessionLimit    = 5
+	maxSessionLimit        = 20
+	// maxSessionWindow is the longest window a session searches.
+	maxSessionWindow = 31 * 24 * time.Hour
+)
+
+// SessionConstraints are what a scheduling session searches for. Turns refine them.
+type SessionConstraints struct {
+	Organizer string   `json:"organizer" example:"anna@example.com"`
+	Title     string   `json:"title" example:"Design review"`
+	Attendees []string `json:"attendees" example:"bob@example.com"`
+	Groups    []string `json:"groups" example:"design"`
+	// DurationMinutes is the length of the meeting, 30 by default.
+	DurationMinutes int `json:"duration_minutes" example:"30"`
+	// When describes the window in words, such as "next week", instead of From and To.
+	When string    `json:"when,omitempty" example:"next week"`
+	From time.Time `json:"from" format:"date-time" example:"2024-05-13T00:00:00+02:00"`
+	To   time.Time `json:"to" format:"date-time" example:"2024-05-20T00:00:00+02:00"`
+	// Periods are the parts of the window When describes.
+	Periods  []Period `json:"periods,omitempty"`
+	TimeZone string   `json:"tz" example:"Europe/Warsaw"`
+	// ExcludedDays are weekdays, such as monday, or dates in YYYY-MM-DD form.
+	ExcludedDays []string `json:"excluded_days" example:"monday"`
+	// Limit is how many candidate times are offered, 5 by default.
+	Limit int `json:"limit" example:"5"`
+}
+
+// location returns the time zone of the session.
+func (c *SessionConstraints) location() (*time.Location, error) {
+	if c.TimeZone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: tz %q is not an IANA time zone name", ErrInvalidSession, c.TimeZone)
+	}
+	return loc, nil
+}
+
+// setWindow sets the window of c from its When phrase, read at now.
+func (c *SessionConstraints) setWindow(now time.Time) error {
+	loc, err := c.location()
+	if err != nil {
+		return err
+	}
+	window, err := timeparse.Parse(c.When, now, loc)
+	if err != nil {
+		return fmt.Errorf("cannot interpret %q: %w", c.When, err)
+	}
+
+	c.From, c.To, c.Periods = window.Start, window.End, nil
+	for _, r := range window.Ranges {
+		c.Periods = append(c.Periods, Period{Start: r.Start, End: r.End})
+	}
+	if c.DurationMinutes == 0 {
+		c.DurationMinutes = int(window.Duration() / time.Minute)
+	}
+	return nil
+}
+
+// checkWindow checks that the window of c ends after it starts and is not too long.
+func (c *SessionConstraints) checkWindow() error {
+	if !c.To.After(c.From) || c.To.Sub(c.From) > maxSessionWindow {
+		return fmt.Errorf("the window must end after it starts and last at most %s", maxSessionWindow)
+	}
+	return nil
+}
+
+// normalize checks c and fills in its defaults.
+func (c *SessionConstraints) normalize(now time.Time) error {
+	switch {
+	case c.Organizer == "":
+		return fmt.Errorf("%w: organizer is required", ErrInvalidSession)
+	case len(c.Attendees) == 0 && len(c.Groups) == 0:
+		return fmt.Errorf("%w: attendees or groups are required", ErrInvalidSession)
+	case c.When != "" && (!c.From.IsZero() || !c.To.IsZero()):
+		return fmt.Errorf("%w: pass either when or from and to, not both", ErrInvalidSession)
+	case c.Limit < 0 || c.Limit > maxSessionLimit:
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidSession, maxSessionLimit)
+	}
+
+	if _, err := c.location(); err != nil {
+		return err
+	}
+	if c.When != "" {
+		err := c.setWindow(now)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSession, err)
+		}
+	}
+	if c.From.IsZero() {
+		c.From = now
+	}
+	if c.To.IsZero() {
+		c.To = c.From.Add(7 * 24 * time.Hour)
+	}
+	if err := c.checkWindow(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSession, err)
+	}
+
+	if c.DurationMinutes == 0 {
+		c.DurationMinutes = defaultSessionDuration
+	}
+	if c.DurationMinutes < 5 || c.DurationMinutes > 8*60 {
+		return fmt.Errorf("%w: duration_minutes must be between 5 and 480", ErrInvalidSession)
+	}
+	if c.Title == "" {
+		c.Title = "Meeting"
+	}
+	if c.Limit == 0 {
+		c.Limit = defaultSessionLimit
+	}
+	if c.Attendees == nil {
+		c.Attendees = []string{}
+	}
+	if c.Groups == nil {
+		c.Groups = []string{}
+	}
+	if c.ExcludedDays == nil {
+		c.ExcludedDays = []string{}
+	}
+	for _, day := range c.ExcludedDays {
+		if err := checkDay(day); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSession, err)
+		}
+	}
+	return nil
+}
+
+// checkDay checks that day names a weekday or a YYYY-MM-DD date.
+func checkDay(day string) error {
+	if _, err := time.Parse(time.DateOnly, day); err == nil {
+		return nil
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(day, weekday.String()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is neither a weekday nor a YYYY-MM-DD date", day)
+}
+
+// excluded reports whether the day starting at day is one of c's excluded days.
+func (c *SessionConstraints) excluded(day time.Time) bool {
+	for _, excluded := range c.ExcludedDays {
+		if strings.EqualFold(excluded, day.Weekday().String()) || excluded == day.Format(time.DateOnly) {
+			return true
+		}
+	}
+	return false
+}
+
+// periods returns the parts of the window meetings can be proposed in: the periods
+// of the When phrase, or the whole window, without the excluded days.
+func (c *SessionConstraints) periods(loc *time.Location) []interval {
+	base := []interval{{start: c.From, end: c.To}}
+	if len(c.Periods) > 0 {
+		base = base[:0]
+		for _, period := range c.Periods {
+			base = append(base, interval{start: period.Start, end: period.End})
+		}
+	}
+	if len(c.ExcludedDays) == 0 {
+		return mergeIntervals(base)
+	}
+
+	var days []interval
+	for day := atClock(c.From.In(loc), 0, loc); day.Before(c.To); day = atClock(day.AddDate(0, 0, 1), 0, loc) {
+		if !c.excluded(day) {
+			days = append(days, interval{start: maxTime(day, c.From), end: minTime(atClock(day.AddDate(0, 0, 1), 0, loc), c.To)})
+		}
+	}
+	return intersectIntervals(mergeIntervals(base), mergeIntervals(days))
+}
+
+// Turn is a refinement of a session, such as "not Monday" or "book the second one".
+type Turn struct {
+	Action string `json:"action" enums:"exclude_days,change_duration,change_window,add_attendee,remove_attendee,pick" example:"exclude_days"`
+	// Days are the weekdays or YYYY-MM-DD dates exclude_days removes.
+	Days []string `json:"days,omitempty" example:"monday"`
+	// DurationMinutes is the new length for change_duration.
+	DurationMinutes int `json:"duration_minutes,omitempty" example:"45"`
+	// When is the new window for change_window, such as "next week".
+	When string `json:"when,omitempty" example:"next week"`
+	// Attendee and Group are added or removed by add_attendee and remove_attendee.
+	Attendee string `json:"attendee,omitempty" example:"carol@example.com"`
+	Group    string `json:"group,omitempty" example:"design"`
+	// Option is the candidate, counted from 1, that pick books.
+	Option int `json:"option,omitempty" example:"2"`
+}
+
+// Session is a multi-turn search for a meeting time. It keeps its constraints and the
+// current candidate times until it books a meeting or expires.
+type Session struct {
+	ID          string             `json:"id" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	Status      string             `json:"status" enums:"active,booked" example:"active"`
+	Constraints SessionConstraints `json:"constraints"`
+	// Candidates are the times on offer, best first; pick refers to them by position.
+	Candidates  []Suggestion        `json:"candidates"`
+	Unavailable []UnavailableMember `json:"unavailable_attendees"`
+	// EventID is the Google Calendar event booked by pick.
+	EventID   string    `json:"event_id,omitempty"`
+	ExpiresAt time.Time `json:"expires_at" format:"date-time" example:"2024-05-08T10:30:00Z"`
+}
+
+// candidates searches the times matching the constraints of s, for the organizer and
+// every attendee.
+func (m *Models) candidates(ctx context.Context, s *Session) error {
+	c := &s.Constraints
+	loc, err := c.location()
+	if err != nil {
+		return err
+	}
+
+	s.Candidates, s.Unavailable = []Suggestion{}, []UnavailableMember{}
+	periods := c.periods(loc)
+	if len(periods) == 0 {
+		return nil
+	}
+
+	opts := SlotOptions{
+		From:        c.From,
+		To:          c.To,
+		MinDuration: time.Duration(c.DurationMinutes) * time.Minute,
+		Location:    loc,
+	}
+	for _, period := range periods {
+		opts.Periods = append(opts.Periods, Period{Start: period.start, End: period.end})
+	}
+
+	suggestions, err := m.SuggestTimes(ctx, SuggestionRequest{
+		Attendees: append([]string{c.Organizer}, c.Attendees...),
+		Groups:    c.Groups,
+		Opts:      opts,
+		Limit:     c.Limit,
+		Weights:   DefaultSuggestionWeights,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.Candidates, s.Unavailable = suggestions.Suggestions, suggestions.Unavailable
+	return nil
+}
+
+// StartSession starts a session searching for c and stores it for ttl.
+func (m *Models) StartSession(ctx context.Context, c SessionConstraints, ttl time.Duration) (*Session, error) {
+	err := c.normalize(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Session{ID: id, Status: SessionActive, Constraints: c, ExpiresAt: time.Now().Add(ttl).UTC()}
+	err = m.candidates(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	constraints, candidates, unavailable, err := s.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO sessions (id, organizer, status, constraints, candidates, unavailable, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err = m.DB.Exec(query, s.ID, c.Organizer, s.Status, constraints, candidates, unavailable, s.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return s, nil
+}
+
+// encode returns the JSON columns of s.
+func (s *Session) encode() (constraints, candidates, unavailable string, err error) {
+	columns := []struct {
+		value  any
+		target *string
+	}{
+		{s.Constraints, &constraints},
+		{s.Candidates, &candidates},
+		{s.Unavailable, &unavailable},
+	}
+	for _, column := range columns {
+		encoded, err := json.Marshal(column.value)
+		if err != nil {
+			return "", "", "", fmt.Errorf("failed to encode session: %w", err)
+		}
+		*column.target = string(encoded)
+	}
+	return constraints, candidates, unavailable, nil
+}
+
+// GetSession returns the session with id on behalf of actor, who must be its
+// organizer. ErrSessionExpired is returned for active sessions past their TTL.
+func (m *Models) GetSession(id, actor string) (*Session, error) {
+	query := `
+		SELECT organizer, status, constraints, candidates, unavailable, COALESCE(event_id, ''), expires_at
+		FROM sessions WHERE id = $1
+	`
+
+	s := &Session{ID: id}
+	var organizer, constraints, candidates, unavailable string
+	err := m.DB.QueryRow(query, id).Scan(&organizer, &s.Status, &constraints, &candidates, &unavailable, &s.EventID, &s.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if !strings.EqualFold(actor, organizer) {
+		return nil, ErrNotAllowed
+	}
+	if s.Status == SessionActive && !s.ExpiresAt.After(time.Now()) {
+		return nil, ErrSessionExpired
+	}
+
+	columns := []struct {
+		value  string
+		target any
+	}{
+		{constraints, &s.Constraints},
+		{candidates, &s.Candidates},
+		{unavailable, &s.Unavailable},
+	}
+	for _, column := range columns {
+		err := json.Unmarshal([]byte(column.value), column.target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode session: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// ApplyTurn refines the session with id on behalf of actor, its organizer, and keeps
+// it for another ttl. Refinements search the candidates again; pick books the chosen
+// candidate and returns the meeting.
+func (m *Models) ApplyTurn(ctx context.Context, id, actor string, turn Turn, ttl time.Duration) (*Session, *Meeting, error) {
+	s, err := m.GetSession(id, actor)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.Status != SessionActive {
+		return nil, nil, ErrSessionBooked
+	}
+
+	c := &s.Constraints
+	var meeting *Meeting
+	switch turn.Action {
+	case TurnExcludeDays:
+		if len(turn.Days) == 0 {
+			return nil, nil, fmt.Errorf("%w: days are required", ErrInvalidTurn)
+		}
+		for _, day := range turn.Days {
+			if err := checkDay(day); err != nil {
+				return nil, nil, fmt.Errorf("%w: %w", ErrInvalidTurn, err)
+			}
+			if !contains(c.ExcludedDays, strings.ToLower(day)) {
+				c.ExcludedDays = append(c.ExcludedDays, strings.ToLower(day))
+			}
+		}
+
+	case TurnChangeDuration:
+		if turn.DurationMinutes < 5 || turn.DurationMinutes > 8*60 {
+			return nil, nil, fmt.Errorf("%w: duration_minutes must be between 5 and 480", ErrInvalidTurn)
+		}
+		c.DurationMinutes = turn.DurationMinutes
+
+	case TurnChangeWindow:
+		if turn.When == "" {
+			return nil, nil, fmt.Errorf("%w: when is required", ErrInvalidTurn)
+		}
+		c.When = turn.When
+		err := c.setWindow(time.Now())
+		if err == nil {
+			err = c.checkWindow()
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidTurn, err)
+		}
+
+	case TurnAddAttendee:
+		switch {
+		case turn.Attendee != "" && !contains(c.Attendees, turn.Attendee):
+			c.Attendees = append(c.Attendees, turn.Attendee)
+		case turn.Group != "" && !contains(c.Groups, turn.Group):
+			c.Groups = append(c.Groups, turn.Group)
+		case turn.Attendee == "" && turn.Group == "":
+			return nil, nil, fmt.Errorf("%w: attendee or group is required", ErrInvalidTurn)
+		}
+
+	case TurnRemoveAttendee:
+		if !contains(c.Attendees, turn.Attendee) && !contains(c.Groups, turn.Group) {
+			return nil, nil, fmt.Errorf("%w: not an attendee or group of the session", ErrInvalidTurn)
+		}
+		c.Attendees, c.Groups = without(c.Attendees, turn.Attendee), without(c.Groups, turn.Group)
+		if len(c.Attendees) == 0 && len(c.Groups) == 0 {
+			return nil, nil, fmt.Errorf("%w: the session needs at least one attendee or group", ErrInvalidTurn)
+		}
+
+	case TurnPick:
+		if turn.Option < 1 || turn.Option > len(s.Candidates) {
+			return nil, nil, fmt.Errorf("%w: option must be between 1 and %d", ErrInvalidTurn, len(s.Candidates))
+		}
+		candidate := s.Candidates[turn.Option-1]
+
+		// Claim the session before booking so that a second pick cannot book it again
+		var claimed string
+		queryClaim := `UPDATE sessions SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3 RETURNING id`
+		err = m.DB.QueryRow(queryClaim, SessionBooked, s.ID, SessionActive).Scan(&claimed)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrSessionBooked
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to claim session: %w", err)
+		}
+
+		meeting, err = m.CreateMeeting(ctx, MeetingRequest{
+			Organizer: c.Organizer,
+			Attendees: c.Attendees,
+			Groups:    c.Groups,
+			Title:     c.Title,
+			Start:     candidate.Start,
+			End:       candidate.End,
+		})
+		if err != nil {
+			// Hand the session back so that the organizer can pick again
+			_, releaseErr := m.DB.Exec(`UPDATE sessions SET status = $1 WHERE id = $2`, SessionActive, s.ID)
+			if releaseErr != nil {
+				return nil, nil, errors.Join(err, fmt.Errorf("failed to release session: %w", releaseErr))
+			}
+			return nil, nil, err
+		}
+		s.Status, s.EventID = SessionBooked, meeting.EventID
+
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown action %q", ErrInvalidTurn, turn.Action)
+	}
+
+	if turn.Action != TurnPick {
+		err = m.candidates(ctx, s)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	s.ExpiresAt = time.Now().Add(ttl).UTC()
+
+	err = m.saveTurn(s, turn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s, meeting, nil
+}
+
+// saveTurn stores the state of s after turn, and turn itself. The stored session has
+// to have the status of s, which a pick claimed before booking, so that a turn racing
+// a pick cannot make a booked session active again; otherwise ErrSessionBooked is
+// returned.
+func (m *Models) saveTurn(s *Session, turn Turn) error {
+	constraints, candidates, unavailable, err := s.encode()
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(turn)
+	if err != nil {
+		return fmt.Errorf("failed to encode turn: %w", err)
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	querySession := `
+		UPDATE sessions SET status = $1, constraints = $2, candidates = $3, unavailable = $4, event_id = NULLIF($5, ''),
+			expires_at = $6, updated_at = NOW()
+		WHERE id = $7 AND status = $1
+	`
+	result, err := tx.Exec(querySession, s.Status, constraints, candidates, unavailable, s.EventID, s.ExpiresAt, s.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	if updated == 0 {
+		return ErrSessionBooked
+	}
+
+	queryTurn := `INSERT INTO session_turns (session_id, action, payload) VALUES ($1, $2, $3)`
+	_, err = tx.Exec(queryTurn, s.ID, turn.Action, string(payload))
+	if err != nil {
+		return fmt.Errorf("failed to save turn: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredSessions removes the active sessions past their TTL, with their turns,
+// and returns how many were removed. Booked sessions are kept.
+func (m *Models) DeleteExpiredSessions() (int64, error) {
+	result, err := m.DB.Exec(`DELETE FROM sessions WHERE status = $1 AND expires_at <= NOW()`, SessionActive)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted sessions: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// without returns values without value.
+func without(values []string, value string) []string {
+	kept := []string{}
+	for _, v := range values {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
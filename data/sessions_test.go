@@ -0,0 +1,230 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func expectSession(mock sqlmock.Sqlmock, id string, c SessionConstraints, candidates []Suggestion, expiresAt time.Time) {
+	constraints, _ := json.Marshal(c)
+	encoded, _ := json.Marshal(candidates)
+	mock.ExpectQuery(`SELECT organizer, status, constraints, candidates, unavailable`).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"organizer", "status", "constraints", "candidates", "unavailable", "event_id", "expires_at"}).
+			AddRow(c.Organizer, SessionActive, string(constraints), string(encoded), "[]", "", expiresAt))
+}
+
+func expectSessionClaim(mock sqlmock.Sqlmock, id string, claimed bool) {
+	rows := sqlmock.NewRows([]string{"id"})
+	if claimed {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery(`UPDATE sessions SET status = \$1, updated_at = NOW\(\) WHERE id = \$2 AND status = \$3`).
+		WithArgs(SessionBooked, id, SessionActive).
+		WillReturnRows(rows)
+}
+
+func TestSessionConstraintsPeriods(t *testing.T) {
+	monday := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	c := SessionConstraints{
+		From:         monday,
+		To:           monday.AddDate(0, 0, 7),
+		ExcludedDays: []string{"monday", "2024-05-08"},
+		Periods: []Period{
+			{Start: monday.Add(15 * time.Hour), End: monday.Add(24 * time.Hour)},
+			{Start: monday.Add(39 * time.Hour), End: monday.Add(48 * time.Hour)},
+			{Start: monday.Add(63 * time.Hour), End: monday.Add(72 * time.Hour)},
+		},
+	}
+
+	periods := c.periods(time.UTC)
+
+	// Only Tuesday's afternoon is left
+	if len(periods) != 1 || !periods[0].start.Equal(monday.Add(39*time.Hour)) || !periods[0].end.Equal(monday.Add(48*time.Hour)) {
+		t.Errorf("expected Tuesday after 3pm only, got %v", periods)
+	}
+}
+
+func TestGetSession(t *testing.T) {
+	c := SessionConstraints{Organizer: "anna@example.com", Attendees: []string{"bob@example.com"}}
+
+	tests := []struct {
+		name      string
+		actor     string
+		expiresAt time.Time
+		expected  error
+	}{
+		{name: "organizer", actor: "anna@example.com", expiresAt: time.Now().Add(time.Hour)},
+		{name: "someone else", actor: "bob@example.com", expiresAt: time.Now().Add(time.Hour), expected: ErrNotAllowed},
+		{name: "expired", actor: "anna@example.com", expiresAt: time.Now().Add(-time.Minute), expected: ErrSessionExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			defer db.Close()
+
+			models := NewModels(db)
+			expectSession(mock, "session-1", c, []Suggestion{}, tt.expiresAt)
+
+			s, err := models.GetSession("session-1", tt.actor)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected error %v, got %v", tt.expected, err)
+			}
+			if err == nil && s.Constraints.Attendees[0] != "bob@example.com" {
+				t.Errorf("expected the stored constraints, got %+v", s.Constraints)
+			}
+		})
+	}
+}
+
+func TestApplyTurnExcludeDays(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	monday := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	c := SessionConstraints{
+		Organizer:       "anna@example.com",
+		Title:           "Design review",
+		Attendees:       []string{"bob@example.com"},
+		Groups:          []string{},
+		DurationMinutes: 60,
+		From:            monday,
+		To:              monday.AddDate(0, 0, 2),
+		ExcludedDays:    []string{},
+		Limit:           3,
+	}
+
+	expectSession(mock, "session-1", c, []Suggestion{}, time.Now().Add(time.Hour))
+	expectToken(mock, "anna@example.com", "anna-token")
+	expectToken(mock, "bob@example.com", "bob-token")
+	expectHolds(mock)
+	expectPreferences(mock, "anna@example.com")
+	expectPreferences(mock, "bob@example.com")
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE sessions SET status`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO session_turns`).WithArgs("session-1", TurnExcludeDays, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	s, meeting, err := models.ApplyTurn(context.Background(), "session-1", "anna@example.com", Turn{Action: TurnExcludeDays, Days: []string{"Monday"}}, 30*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meeting != nil {
+		t.Errorf("expected no meeting, got %+v", meeting)
+	}
+	if len(s.Candidates) != 3 {
+		t.Fatalf("expected 3 candidates, got %v", s.Candidates)
+	}
+	for _, candidate := range s.Candidates {
+		if candidate.Start.Weekday() != time.Tuesday {
+			t.Errorf("expected candidates on Tuesday only, got %v", candidate.Start)
+		}
+	}
+	if s.Constraints.ExcludedDays[0] != "monday" {
+		t.Errorf("expected monday to be excluded, got %v", s.Constraints.ExcludedDays)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestApplyTurnChangeWindowKeepsBounds(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	models := NewModels(db)
+	models.Calendar = NewFakeCalendar()
+
+	c := SessionConstraints{
+		Organizer:       "anna@example.com",
+		Attendees:       []string{"bob@example.com"},
+		Groups:          []string{},
+		DurationMinutes: 60,
+		ExcludedDays:    []string{},
+	}
+	expectSession(mock, "session-1", c, []Suggestion{}, time.Now().Add(time.Hour))
+
+	_, _, err := models.ApplyTurn(context.Background(), "session-1", "anna@example.com", Turn{Action: TurnChangeWindow, When: "within 60 days"}, 30*time.Minute)
+	if !errors.Is(err, ErrInvalidTurn) {
+		t.Errorf("expected ErrInvalidTurn for a window longer than %s, got %v", maxSessionWindow, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestApplyTurnPick(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	start := time.Date(2024, 5, 7, 10, 0, 0, 0, time.UTC)
+	c := SessionConstraints{
+		Organizer:       "anna@example.com",
+		Title:           "Design review",
+		Attendees:       []string{"bob@example.com"},
+		Groups:          []string{},
+		DurationMinutes: 60,
+		ExcludedDays:    []string{},
+	}
+	candidates := []Suggestion{
+		{Start: start, End: start.Add(time.Hour)},
+		{Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour)},
+	}
+
+	expectSession(mock, "session-1", c, candidates, time.Now().Add(time.Hour))
+	expectSessionClaim(mock, "session-1", true)
+	expectScopes(mock, "anna@example.com", "https://www.googleapis.com/auth/calendar.events")
+	expectToken(mock, "anna@example.com", "anna-token")
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO meetings`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO meeting_attendees`).WithArgs("fake-event-1", "bob@example.com").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE sessions SET status`).
+		WithArgs(SessionBooked, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "fake-event-1", sqlmock.AnyArg(), "session-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO session_turns`).WithArgs("session-1", TurnPick, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	s, meeting, err := models.ApplyTurn(context.Background(), "session-1", "anna@example.com", Turn{Action: TurnPick, Option: 2}, 30*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !meeting.Start.Equal(candidates[1].Start) {
+		t.Errorf("expected the second candidate to be booked, got %v", meeting.Start)
+	}
+	if s.Status != SessionBooked || s.EventID != "fake-event-1" {
+		t.Errorf("expected the session to be booked, got %+v", s)
+	}
+
+	// A pick racing the first one finds the session claimed and books nothing
+	expectSession(mock, "session-1", c, candidates, time.Now().Add(time.Hour))
+	expectSessionClaim(mock, "session-1", false)
+	_, _, err = models.ApplyTurn(context.Background(), "session-1", "anna@example.com", Turn{Action: TurnPick, Option: 1}, 30*time.Minute)
+	if !errors.Is(err, ErrSessionBooked) {
+		t.Errorf("expected ErrSessionBooked, got %v", err)
+	}
+	if events := fake.Events("anna@example.com"); len(events) != 1 {
+		t.Errorf("expected no second event, got %d events", len(events))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package data
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// Working hours, as offsets from midnight, of users who have not set their own and
+// of searches without a schedule.
+const (
+	defaultWorkStart = 9 * time.Hour
+	defaultWorkEnd   = 17 * time.Hour
+)
+
+// SlotStatus classifies how a period of a calendar is occupied.
+type SlotStatus string
+
+const (
+	// SlotFree means no event blocks the period.
+	SlotFree SlotStatus = "free"
+	// SlotTentative means the period is only blocked by events the user answered
+	// "maybe" to, so it can be used when no fully free slot fits.
+	SlotTentative SlotStatus = "tentative"
+	// SlotBusy means an accepted or unanswered opaque event blocks the period.
+	SlotBusy SlotStatus = "busy"
+)
+
+// TimeSlot is an available period in a calendar. Start and End are expressed in the
+// time zone the slot was requested in and serialize as RFC 3339 timestamps.
+type TimeSlot struct {
+	Start    time.Time     `json:"start" format:"date-time" example:"2024-05-06T09:00:00+02:00"`
+	End      time.Time     `json:"end" format:"date-time" example:"2024-05-06T10:30:00+02:00"`
+	Duration time.Duration `json:"duration_minutes" swaggertype:"integer" example:"90"`
+	Status   SlotStatus    `json:"status" enums:"free,tentative" example:"free"`
+}
+
+// NewTimeSlot returns the slot between start and end.
+func NewTimeSlot(start, end time.Time, status SlotStatus) TimeSlot {
+	return TimeSlot{Start: start, End: end, Duration: end.Sub(start), Status: status}
+}
+
+// MarshalJSON encodes the slot with its duration in whole minutes.
+func (s TimeSlot) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Start           time.Time  `json:"start"`
+		End             time.Time  `json:"end"`
+		DurationMinutes int64      `json:"duration_minutes"`
+		Status          SlotStatus `json:"status"`
+	}{
+		Start:           s.Start,
+		End:             s.End,
+		DurationMinutes: int64(s.Duration / time.Minute),
+		Status:          s.Status,
+	})
+}
+
+// interval is a half-open time range [start, end).
+type interval struct {
+	start time.Time
+	end   time.Time
+}
+
+// mergeIntervals sorts busy intervals by start time and merges the ones that overlap,
+// touch or are nested inside each other. Empty intervals are dropped.
+func mergeIntervals(busy []interval) []interval {
+	sorted := make([]interval, 0, len(busy))
+	for _, b := range busy {
+		if b.end.After(b.start) {
+			sorted = append(sorted, b)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].start.Before(sorted[j].start)
+	})
+
+	var merged []interval
+	for _, b := range sorted {
+		last := len(merged) - 1
+		if last >= 0 && !b.start.After(merged[last].end) {
+			if b.end.After(merged[last].end) {
+				merged[last].end = b.end
+			}
+			continue
+		}
+		merged = append(merged, b)
+	}
+
+	return merged
+}
+
+// atClock returns the wall-clock time offset from midnight of day's date in loc.
+// It goes through time.Date so that days with a DST change keep their working hours.
+func atClock(day time.Time, offset time.Duration, loc *time.Location) time.Time {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, loc)
+}
+
+// freeIntervals subtracts the busy intervals from the working windows of opts.Schedule
+// between opts.From and opts.To, or from the default working hours in opts.Location
+// without a schedule. Only gaps of at least opts.MinDuration are returned, expressed in
+// opts.Location and in chronological order.
+func freeIntervals(busy []interval, opts SlotOptions) []interval {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	schedule := opts.Schedule
+	if schedule == nil {
+		schedule = everyDay(loc, HourRange{Start: defaultWorkStart, End: defaultWorkEnd})
+	}
+	merged := mergeIntervals(busy)
+
+	windows := schedule.windows(opts.From, opts.To)
+	if len(opts.Periods) > 0 {
+		periods := make([]interval, len(opts.Periods))
+		for i, period := range opts.Periods {
+			periods[i] = interval{start: period.Start, end: period.End}
+		}
+		windows = intersectIntervals(windows, mergeIntervals(periods))
+	}
+
+	var free []interval
+	for _, window := range windows {
+		cursor := window.start
+		for _, b := range merged {
+			if !b.end.After(cursor) {
+				continue
+			}
+			if !b.start.Before(window.end) {
+				break
+			}
+			if b.start.After(cursor) {
+				free = appendFree(free, interval{start: cursor, end: b.start}, opts.MinDuration, loc)
+			}
+			cursor = b.end
+		}
+		if cursor.Before(window.end) {
+			free = appendFree(free, interval{start: cursor, end: window.end}, opts.MinDuration, loc)
+		}
+	}
+
+	return free
+}
+
+// appendFree appends gap to free, expressed in loc, if it lasts at least minDuration.
+func appendFree(free []interval, gap interval, minDuration time.Duration, loc *time.Location) []interval {
+	if gap.end.Sub(gap.start) < minDuration {
+		return free
+	}
+	return append(free, interval{start: gap.start.In(loc), end: gap.end.In(loc)})
+}
+
+// classifiedSlots returns the fully free slots of the search window, which avoid both
+// busy and tentative intervals, followed in time order by the wider slots that are
+// only available by overriding a tentative event.
+func classifiedSlots(busy, tentative []interval, opts SlotOptions) []TimeSlot {
+	blocked := make([]interval, 0, len(busy)+len(tentative))
+	blocked = append(blocked, busy...)
+	blocked = append(blocked, tentative...)
+
+	slots := []TimeSlot{}
+	for _, free := range freeIntervals(blocked, opts) {
+		slots = append(slots, NewTimeSlot(free.start, free.end, SlotFree))
+	}
+	for _, soft := range freeIntervals(busy, opts) {
+		if overlapsAny(soft, tentative) {
+			slots = append(slots, NewTimeSlot(soft.start, soft.end, SlotTentative))
+		}
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool {
+		return slots[i].Start.Before(slots[j].Start)
+	})
+
+	return slots
+}
+
+// overlapsAny reports whether period shares any time with one of others.
+func overlapsAny(period interval, others []interval) bool {
+	for _, other := range others {
+		if other.start.Before(period.end) && period.start.Before(other.end) {
+			return true
+		}
+	}
+	return false
+}
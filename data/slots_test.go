@@ -0,0 +1,188 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("invalid time %q: %v", value, err)
+	}
+	return parsed
+}
+
+func TestMergeIntervals(t *testing.T) {
+	busy := []interval{
+		{start: mustTime(t, "2024-05-06T13:00:00Z"), end: mustTime(t, "2024-05-06T14:00:00Z")},
+		{start: mustTime(t, "2024-05-06T09:00:00Z"), end: mustTime(t, "2024-05-06T11:00:00Z")},
+		{start: mustTime(t, "2024-05-06T09:30:00Z"), end: mustTime(t, "2024-05-06T10:00:00Z")}, // nested
+		{start: mustTime(t, "2024-05-06T10:30:00Z"), end: mustTime(t, "2024-05-06T12:00:00Z")}, // overlapping
+		{start: mustTime(t, "2024-05-06T14:00:00Z"), end: mustTime(t, "2024-05-06T14:30:00Z")}, // touching
+	}
+
+	merged := mergeIntervals(busy)
+
+	expected := []interval{
+		{start: mustTime(t, "2024-05-06T09:00:00Z"), end: mustTime(t, "2024-05-06T12:00:00Z")},
+		{start: mustTime(t, "2024-05-06T13:00:00Z"), end: mustTime(t, "2024-05-06T14:30:00Z")},
+	}
+	if len(merged) != len(expected) {
+		t.Fatalf("expected %d intervals, got %d: %v", len(expected), len(merged), merged)
+	}
+	for i := range expected {
+		if !merged[i].start.Equal(expected[i].start) || !merged[i].end.Equal(expected[i].end) {
+			t.Errorf("interval %d: expected %v, got %v", i, expected[i], merged[i])
+		}
+	}
+}
+
+func TestFreeIntervals(t *testing.T) {
+	opts := SlotOptions{
+		From:        mustTime(t, "2024-05-06T00:00:00Z"),
+		To:          mustTime(t, "2024-05-09T00:00:00Z"),
+		MinDuration: 30 * time.Minute,
+		Location:    time.UTC,
+	}
+	busy := []interval{
+		// Monday: busy in the morning with a 15 minute gap that is too short
+		{start: mustTime(t, "2024-05-06T09:00:00Z"), end: mustTime(t, "2024-05-06T10:00:00Z")},
+		{start: mustTime(t, "2024-05-06T10:15:00Z"), end: mustTime(t, "2024-05-06T12:00:00Z")},
+		// Overnight event from Monday evening into Tuesday morning
+		{start: mustTime(t, "2024-05-06T16:00:00Z"), end: mustTime(t, "2024-05-07T10:00:00Z")},
+		// Wednesday has no events at all
+	}
+
+	free := freeIntervals(busy, opts)
+
+	expected := []interval{
+		{start: mustTime(t, "2024-05-06T12:00:00Z"), end: mustTime(t, "2024-05-06T16:00:00Z")},
+		{start: mustTime(t, "2024-05-07T10:00:00Z"), end: mustTime(t, "2024-05-07T17:00:00Z")},
+		{start: mustTime(t, "2024-05-08T09:00:00Z"), end: mustTime(t, "2024-05-08T17:00:00Z")},
+	}
+	if len(free) != len(expected) {
+		t.Fatalf("expected %d free intervals, got %d: %v", len(expected), len(free), free)
+	}
+	for i := range expected {
+		if !free[i].start.Equal(expected[i].start) || !free[i].end.Equal(expected[i].end) {
+			t.Errorf("free interval %d: expected %v, got %v", i, expected[i], free[i])
+		}
+	}
+}
+
+func TestFreeIntervalsClipsWindowAndZone(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+
+	// 2024-03-31 is the spring DST change in Warsaw; working hours must stay 09:00-17:00.
+	opts := SlotOptions{
+		From:        time.Date(2024, 3, 30, 15, 0, 0, 0, warsaw),
+		To:          time.Date(2024, 3, 31, 12, 0, 0, 0, warsaw),
+		MinDuration: time.Hour,
+		Location:    warsaw,
+	}
+
+	free := freeIntervals(nil, opts)
+
+	expected := []interval{
+		{start: time.Date(2024, 3, 30, 15, 0, 0, 0, warsaw), end: time.Date(2024, 3, 30, 17, 0, 0, 0, warsaw)},
+		{start: time.Date(2024, 3, 31, 9, 0, 0, 0, warsaw), end: time.Date(2024, 3, 31, 12, 0, 0, 0, warsaw)},
+	}
+	if len(free) != len(expected) {
+		t.Fatalf("expected %d free intervals, got %d: %v", len(expected), len(free), free)
+	}
+	for i := range expected {
+		if !free[i].start.Equal(expected[i].start) || !free[i].end.Equal(expected[i].end) {
+			t.Errorf("free interval %d: expected %v, got %v", i, expected[i], free[i])
+		}
+		if free[i].start.Location() != warsaw {
+			t.Errorf("free interval %d: expected location %v, got %v", i, warsaw, free[i].start.Location())
+		}
+	}
+}
+
+func TestFreeIntervalsWithinPeriods(t *testing.T) {
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	opts := SlotOptions{
+		From:        day,
+		To:          day.Add(48 * time.Hour),
+		MinDuration: 30 * time.Minute,
+		Location:    time.UTC,
+		Periods: []Period{
+			{Start: day.Add(39 * time.Hour), End: day.Add(48 * time.Hour)},
+			{Start: day.Add(15 * time.Hour), End: day.Add(24 * time.Hour)},
+		},
+	}
+	busy := []interval{{start: day.Add(15 * time.Hour), end: day.Add(16 * time.Hour)}}
+
+	free := freeIntervals(busy, opts)
+
+	// Only after 3pm, within working hours, on both days
+	expected := []interval{
+		{start: day.Add(16 * time.Hour), end: day.Add(17 * time.Hour)},
+		{start: day.Add(39 * time.Hour), end: day.Add(41 * time.Hour)},
+	}
+	if len(free) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, free)
+	}
+	for i := range expected {
+		if !free[i].start.Equal(expected[i].start) || !free[i].end.Equal(expected[i].end) {
+			t.Errorf("free interval %d: expected %v, got %v", i, expected[i], free[i])
+		}
+	}
+}
+
+func TestTimeSlotMarshalJSON(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+
+	slot := NewTimeSlot(time.Date(2024, 5, 6, 9, 0, 0, 0, warsaw), time.Date(2024, 5, 6, 10, 30, 0, 0, warsaw), SlotFree)
+
+	out, err := json.Marshal(slot)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"start":"2024-05-06T09:00:00+02:00","end":"2024-05-06T10:30:00+02:00","duration_minutes":90,"status":"free"}`
+	if string(out) != expected {
+		t.Fatalf("expected %s, got %s", expected, out)
+	}
+}
+
+func TestClassifiedSlots(t *testing.T) {
+	opts := SlotOptions{
+		From:        mustTime(t, "2024-05-06T00:00:00Z"),
+		To:          mustTime(t, "2024-05-07T00:00:00Z"),
+		MinDuration: 30 * time.Minute,
+		Location:    time.UTC,
+	}
+	busy := []interval{
+		{start: mustTime(t, "2024-05-06T09:00:00Z"), end: mustTime(t, "2024-05-06T12:00:00Z")},
+	}
+	tentative := []interval{
+		{start: mustTime(t, "2024-05-06T14:00:00Z"), end: mustTime(t, "2024-05-06T15:00:00Z")},
+	}
+
+	slots := classifiedSlots(busy, tentative, opts)
+
+	expected := []TimeSlot{
+		NewTimeSlot(mustTime(t, "2024-05-06T12:00:00Z"), mustTime(t, "2024-05-06T14:00:00Z"), SlotFree),
+		NewTimeSlot(mustTime(t, "2024-05-06T12:00:00Z"), mustTime(t, "2024-05-06T17:00:00Z"), SlotTentative),
+		NewTimeSlot(mustTime(t, "2024-05-06T15:00:00Z"), mustTime(t, "2024-05-06T17:00:00Z"), SlotFree),
+	}
+	if len(slots) != len(expected) {
+		t.Fatalf("expected %d slots, got %d: %v", len(expected), len(slots), slots)
+	}
+	for i := range expected {
+		if !slots[i].Start.Equal(expected[i].Start) || !slots[i].End.Equal(expected[i].End) || slots[i].Status != expected[i].Status {
+			t.Errorf("slot %d: expected %v, got %v", i, expected[i], slots[i])
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// suggestionStep is the granularity of suggested start times.
+	suggestionStep = 15 * time.Minute
+	// backToBackGap is the smallest break between meetings that does not count as back-to-back.
+	backToBackGap = 15 * time.Minute
+	// usefulGap is the shortest free time left around a suggestion that is still useful.
+	usefulGap = 30 * time.Minute
+	// Lunch is kept free between these offsets from midnight.
+	lunchStart = 12 * time.Hour
+	lunchEnd   = 13 * time.Hour
+)
+
+// SuggestionWeights sets how much each factor counts towards the score of a suggestion.
+// A zero weight switches the factor off.
+type SuggestionWeights struct {
+	PreferredHours float64 `json:"preferred_hours" example:"1"`
+	Soon           float64 `json:"soon" example:"1"`
+	BackToBack     float64 `json:"back_to_back" example:"1"`
+	Lunch          float64 `json:"lunch" example:"1"`
+	Fragmentation  float64 `json:"fragmentation" example:"1"`
+}
+
+// DefaultSuggestionWeights weighs all factors equally.
+var DefaultSuggestionWeights = SuggestionWeights{PreferredHours: 1, Soon: 1, BackToBack: 1, Lunch: 1, Fragmentation: 1}
+
+// SuggestionRequest describes a search for meeting times. Opts.MinDuration is the
+// length of the meeting.
+type SuggestionRequest struct {
+	Attendees []string
+	Groups    []string
+	Opts      SlotOptions
+	Limit     int
+	// PreferredHours maps attendees to the hours they prefer to meet in.
+	PreferredHours map[string]HourRange
+	Weights        SuggestionWeights
+}
+
+// SuggestionFactors are the scores of a suggestion per factor, each between 0 and 1.
+type SuggestionFactors struct {
+	PreferredHours float64 `json:"preferred_hours" example:"1"`
+	Soon           float64 `json:"soon" example:"0.86"`
+	BackToBack     float64 `json:"back_to_back" example:"1"`
+	Lunch          float64 `json:"lunch" example:"1"`
+	Fragmentation  float64 `json:"fragmentation" example:"0.5"`
+}
+
+// Suggestion is a scored meeting time.
+type Suggestion struct {
+	Start       time.Time         `json:"start" format:"date-time" example:"2024-05-06T10:00:00+02:00"`
+	End         time.Time         `json:"end" format:"date-time" example:"2024-05-06T10:30:00+02:00"`
+	Score       float64           `json:"score" example:"0.87"`
+	Factors     SuggestionFactors `json:"factors"`
+	Explanation string            `json:"explanation" example:"Mon 6 May 10:00-10:30: within everyone's preferred hours, no back-to-back meetings, keeps lunch free."`
+}
+
+// Suggestions is the result of a search for meeting times.
+type Suggestions struct {
+	Attendees   []string            `json:"attendees"`
+	Unavailable []UnavailableMember `json:"unavailable_attendees"`
+	Suggestions []Suggestion        `json:"suggestions"`
+}
+
+// SuggestTimes returns the req.Limit best times in which every attendee, including
+// the members of req.Groups, is free for req.Opts.MinDuration, best first. Attendees
+// whose calendars cannot be read are reported in Unavailable and left out of the
+// search. Suggestions never overlap each other.
+func (m *Models) SuggestTimes(ctx context.Context, req SuggestionRequest) (*Suggestions, error) {
+	attendees, err := m.resolveAttendees("", req.Attendees, req.Groups)
+	if err != nil {
+		return nil, err
+	}
+
+	fetch := req.Opts
+	fetch.From = req.Opts.From.Add(-memberLookaround)
+	fetch.To = req.Opts.To.Add(memberLookaround)
+
+	results := m.membersBusy(ctx, attendees, fetch)
+
+	held, err := m.heldIntervals(fetch.From, fetch.To)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := &Suggestions{
+		Attendees:   []string{},
+		Unavailable: []UnavailableMember{},
+		Suggestions: []Suggestion{},
+	}
+
+	var all []interval
+	var busy [][]interval
+	for i, result := range results {
+		if result.err != nil {
+			return nil, fmt.Errorf("failed to read calendar of %s: %w", attendees[i], result.err)
+		}
+		if result.reason != "" {
+			suggestions.Unavailable = append(suggestions.Unavailable, UnavailableMember{Email: attendees[i], Reason: result.reason})
+			continue
+		}
+
+		meetings := mergeIntervals(append(result.busy, held[attendees[i]]...))
+		unavailable, err := m.unavailableTime(attendees[i], meetings, req.Opts)
+		if err != nil {
+			return nil, err
+		}
+
+		// The end of the working day is no meeting, so back-to-back checks only use meetings
+		suggestions.Attendees = append(suggestions.Attendees, attendees[i])
+		busy = append(busy, meetings)
+		all = append(all, meetings...)
+		all = append(all, unavailable...)
+	}
+	if len(suggestions.Attendees) == 0 {
+		return suggestions, nil
+	}
+
+	scorer := suggestionScorer{
+		req:       req,
+		loc:       req.Opts.Location,
+		attendees: suggestions.Attendees,
+		busy:      busy,
+		now:       maxTime(time.Now(), req.Opts.From),
+	}
+	if scorer.loc == nil {
+		scorer.loc = time.UTC
+	}
+
+	var candidates []Suggestion
+	for _, free := range freeIntervals(all, acrossSchedules(req.Opts)) {
+		start := free.start.Truncate(suggestionStep)
+		if start.Before(free.start) {
+			start = start.Add(suggestionStep)
+		}
+		for end := start.Add(req.Opts.MinDuration); !end.After(free.end); start, end = start.Add(suggestionStep), end.Add(suggestionStep) {
+			candidates = append(candidates, scorer.score(interval{start: start, end: end}, free))
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	var picked []interval
+	for _, candidate := range candidates {
+		if len(suggestions.Suggestions) == req.Limit {
+			break
+		}
+		slot := interval{start: candidate.Start, end: candidate.End}
+		if overlapsAny(slot, picked) {
+			continue
+		}
+		picked = append(picked, slot)
+		suggestions.Suggestions = append(suggestions.Suggestions, candidate)
+	}
+
+	return suggestions, nil
+}
+
+// suggestionScorer scores candidate times for a set of attendees.
+type suggestionScorer struct {
+	req       SuggestionRequest
+	loc       *time.Location
+	attendees []string
+	busy      [][]interval
+	now       time.Time
+}
+
+// score rates slot, which lies within the common free interval free.
+func (s suggestionScorer) score(slot interval, free interval) Suggestion {
+	var factors SuggestionFactors
+	var reasons []string
+
+	// Preferred hours: the share of attendees with preferences whose hours contain the slot
+	var preferring, outside []string
+	for _, email := range s.attendees {
+		hours, ok := s.req.PreferredHours[email]
+		if !ok {
+			continue
+		}
+		preferring = append(preferring, email)
+		if !hours.contains(slot.start, slot.end, s.loc) {
+			outside = append(outside, email)
+		}
+	}
+	factors.PreferredHours = 1
+	if len(preferring) > 0 {
+		factors.PreferredHours = float64(len(preferring)-len(outside)) / float64(len(preferring))
+		if len(outside) == 0 {
+			reasons = append(reasons, "within everyone's preferred hours")
+		} else {
+			reasons = append(reasons, "outside the preferred hours of "+strings.Join(outside, ", "))
+		}
+	}
+
+	// Soon: earlier slots score higher, falling linearly to zero at the end of the window
+	factors.Soon = 1
+	if window := s.req.Opts.To.Sub(s.now); window > 0 {
+		factors.Soon = 1 - float64(slot.start.Sub(s.now))/float64(window)
+		factors.Soon = max(0, min(1, factors.Soon))
+	}
+
+	// Back-to-back: the share of attendees with a break before and after the slot
+	var adjacent []string
+	for i, email := range s.attendees {
+		for _, b := range s.busy[i] {
+			beforeGap := slot.start.Sub(b.end)
+			afterGap := b.start.Sub(slot.end)
+			if (beforeGap >= 0 && beforeGap < backToBackGap) || (afterGap >= 0 && afterGap < backToBackGap) {
+				adjacent = append(adjacent, email)
+				break
+			}
+		}
+	}
+	factors.BackToBack = 1 - float64(len(adjacent))/float64(len(s.attendees))
+	if len(adjacent) == 0 {
+		reasons = append(reasons, "no back-to-back meetings")
+	} else {
+		reasons = append(reasons, "back-to-back for "+strings.Join(adjacent, ", "))
+	}
+
+	// Lunch: the share of the lunch hour the slot leaves free
+	lunch := interval{start: atClock(slot.start.In(s.loc), lunchStart, s.loc), end: atClock(slot.start.In(s.loc), lunchEnd, s.loc)}
+	overlap := minTime(slot.end, lunch.end).Sub(maxTime(slot.start, lunch.start))
+	factors.Lunch = 1
+	if overlap > 0 {
+		factors.Lunch = 1 - float64(overlap)/float64(lunch.end.Sub(lunch.start))
+		reasons = append(reasons, "overlaps lunch")
+	} else {
+		reasons = append(reasons, "keeps lunch free")
+	}
+
+	// Fragmentation: free time left on either side should be zero or long enough to use
+	factors.Fragmentation = 1
+	for _, left := range []time.Duration{slot.start.Sub(free.start), free.end.Sub(slot.end)} {
+		if left > 0 && left < usefulGap {
+			factors.Fragmentation -= 0.5
+		}
+	}
+	if factors.Fragmentation < 1 {
+		reasons = append(reasons, "leaves a short gap of free time")
+	} else {
+		reasons = append(reasons, "leaves no unusable gaps")
+	}
+
+	w := s.req.Weights
+	total := w.PreferredHours + w.Soon + w.BackToBack + w.Lunch + w.Fragmentation
+	var score float64
+	if total > 0 {
+		score = (w.PreferredHours*factors.PreferredHours + w.Soon*factors.Soon + w.BackToBack*factors.BackToBack +
+			w.Lunch*factors.Lunch + w.Fragmentation*factors.Fragmentation) / total
+	}
+
+	start, end := slot.start.In(s.loc), slot.end.In(s.loc)
+	return Suggestion{
+		Start:       start,
+		End:         end,
+		Score:       roundScore(score),
+		Factors:     roundFactors(factors),
+		Explanation: fmt.Sprintf("%s-%s: %s.", start.Format("Mon 2 Jan 15:04"), end.Format("15:04"), strings.Join(reasons, ", ")),
+	}
+}
+
+// roundScore rounds score to two decimals so responses stay readable.
+func roundScore(score float64) float64 {
+	return float64(int(score*100+0.5)) / 100
+}
+
+func roundFactors(f SuggestionFactors) SuggestionFactors {
+	return SuggestionFactors{
+		PreferredHours: roundScore(f.PreferredHours),
+		Soon:           roundScore(f.Soon),
+		BackToBack:     roundScore(f.BackToBack),
+		Lunch:          roundScore(f.Lunch),
+		Fragmentation:  roundScore(f.Fragmentation),
+	}
+}
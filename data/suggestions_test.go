@@ -0,0 +1,103 @@
+package data
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSuggestTimes(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	fake := NewFakeCalendar()
+	models := NewModels(db)
+	models.Calendar = fake
+
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	fake.AddBusy("anna@example.com", BusyPeriod{Start: day.Add(9 * time.Hour), End: day.Add(10 * time.Hour), Status: SlotBusy})
+	fake.AddBusy("bob@example.com", BusyPeriod{Start: day.Add(14 * time.Hour), End: day.Add(17 * time.Hour), Status: SlotBusy})
+	expectToken(mock, "anna@example.com", "anna-token")
+	expectToken(mock, "bob@example.com", "bob-token")
+	expectHolds(mock)
+	expectPreferences(mock, "anna@example.com")
+	expectPreferences(mock, "bob@example.com")
+
+	suggestions, err := models.SuggestTimes(context.Background(), SuggestionRequest{
+		Attendees: []string{"anna@example.com", "bob@example.com"},
+		Opts: SlotOptions{
+			From:        day,
+			To:          day.Add(24 * time.Hour),
+			MinDuration: time.Hour,
+			Location:    time.UTC,
+		},
+		Limit:          3,
+		PreferredHours: map[string]HourRange{"bob@example.com": {Start: 10 * time.Hour, End: 12 * time.Hour}},
+		Weights:        DefaultSuggestionWeights,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(suggestions.Suggestions) != 3 {
+		t.Fatalf("expected 3 suggestions, got %v", suggestions.Suggestions)
+	}
+
+	// 10:00 follows anna's meeting and 10:15 leaves a 15 minute gap, so 10:30 is best
+	best := suggestions.Suggestions[0]
+	if !best.Start.Equal(day.Add(10*time.Hour+30*time.Minute)) || best.Score != 1 {
+		t.Errorf("unexpected best suggestion: %+v", best)
+	}
+	if !strings.Contains(best.Explanation, "within everyone's preferred hours") {
+		t.Errorf("unexpected explanation: %q", best.Explanation)
+	}
+
+	for i, s := range suggestions.Suggestions {
+		if i > 0 && s.Score > suggestions.Suggestions[i-1].Score {
+			t.Errorf("suggestions not ordered by score: %v", suggestions.Suggestions)
+		}
+		for _, other := range suggestions.Suggestions[i+1:] {
+			if s.Start.Before(other.End) && other.Start.Before(s.End) {
+				t.Errorf("suggestions %v and %v overlap", s, other)
+			}
+		}
+		if s.Start.Before(day.Add(10*time.Hour)) || s.End.After(day.Add(14*time.Hour)) {
+			t.Errorf("suggestion %v is outside the common free time", s)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSuggestionScorerLunchAndPreferredHours(t *testing.T) {
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	scorer := suggestionScorer{
+		req: SuggestionRequest{
+			Opts:           SlotOptions{From: day, To: day.Add(24 * time.Hour)},
+			PreferredHours: map[string]HourRange{"anna@example.com": {Start: 9 * time.Hour, End: 12 * time.Hour}},
+			Weights:        DefaultSuggestionWeights,
+		},
+		loc:       time.UTC,
+		attendees: []string{"anna@example.com"},
+		busy:      [][]interval{nil},
+		now:       day,
+	}
+
+	free := interval{start: day.Add(9 * time.Hour), end: day.Add(17 * time.Hour)}
+	s := scorer.score(interval{start: day.Add(12*time.Hour + 30*time.Minute), end: day.Add(13*time.Hour + 30*time.Minute)}, free)
+
+	if s.Factors.Lunch != 0.5 {
+		t.Errorf("expected half of lunch to be taken, got %v", s.Factors.Lunch)
+	}
+	if s.Factors.PreferredHours != 0 {
+		t.Errorf("expected the slot to be outside preferred hours, got %v", s.Factors.PreferredHours)
+	}
+	if !strings.Contains(s.Explanation, "overlaps lunch") || !strings.Contains(s.Explanation, "outside the preferred hours of anna@example.com") {
+		t.Errorf("unexpected explanation: %q", s.Explanation)
+	}
+}
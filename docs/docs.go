@@ -25,7 +25,7 @@ const docTemplate = `{
     "paths": {
         "/add-user": {
             "post": {
-                "description": "Redirects the user to Google OAuth2 authorization page to allow app access.",
+                "description": "Redirects the user to Google OAuth2 authorization page to allow app access.\nWith access=write the link also asks for permission to create and change events, which booking meetings requires.\nThe link has to be opened in the browser that requested it: the response sets a cookie, valid for 10 minutes, with the link's state, which the callback checks.",
                 "consumes": [
                     "application/json"
                 ],
@@ -36,25 +36,69 @@ const docTemplate = `{
                     "User"
                 ],
                 "summary": "Initiates user authorization",
+                "parameters": [
+                    {
+                        "enum": [
+                            "read",
+                            "write"
+                        ],
+                        "type": "string",
+                        "description": "Requested calendar access: read (default) or write",
+                        "name": "access",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email of the Google account to preselect",
+                        "name": "email",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "User authorization link",
                         "schema": {
-                            "type": "string"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid access level",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
                         }
                     },
                     "500": {
                         "description": "Error initiating authorization",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.jsonResponse"
                         }
                     }
                 }
             }
         },
-        "/check-availability": {
-            "get": {
-                "description": "Retrieves free slots from the user's Google Calendar within a given time range.",
+        "/add-user-to-group": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Adds a specified user to a specified group as a member (default) or an admin. Admins may reschedule and cancel meetings booked for the group.",
                 "consumes": [
                     "application/json"
                 ],
@@ -62,31 +106,53 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Calendar"
+                    "Group"
+                ],
+                "summary": "Add a user to a group",
+                "parameters": [
+                    {
+                        "description": "User and Group Data",
+                        "name": "user_data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.AddUserToGroupRequest"
+                        }
+                    }
                 ],
-                "summary": "Check user calendar availability",
                 "responses": {
                     "200": {
-                        "description": "List of free slots",
+                        "description": "User added to group",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid body or role",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
                         }
                     },
                     "500": {
-                        "description": "Error retrieving availability",
+                        "description": "Error adding user to group",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.jsonResponse"
                         }
                     }
                 }
             }
         },
-        "/list-groups": {
+        "/api-keys": {
             "get": {
-                "description": "Retrieves the list of all groups from the database.",
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns every issued key with its scopes and expiry, without the secrets.",
                 "consumes": [
                     "application/json"
                 ],
@@ -94,31 +160,139 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Group"
+                    "Auth"
                 ],
-                "summary": "List all groups",
+                "summary": "List API keys",
                 "responses": {
                     "200": {
-                        "description": "List of groups",
+                        "description": "API keys",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "string"
-                            }
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/data.APIKey"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Credentials lack the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
                         }
                     },
                     "500": {
-                        "description": "Error listing groups",
+                        "description": "Error listing keys",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Issues a key for a service calling the API, such as the WatsonX extension, sent in the X-API-Key header. read-availability allows reading users, groups and availability, book-meetings allows booking and changing meetings, and admin allows everything.\nThe key is only returned in this response; only its hash is stored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Issue an API key",
+                "parameters": [
+                    {
+                        "description": "Name, scopes and expiry of the key",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateAPIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "API key issued",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.APIKey"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid key",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Credentials lack the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error issuing key",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.jsonResponse"
                         }
                     }
                 }
             }
         },
-        "/list-users": {
-            "get": {
-                "description": "Retrieves the list of all users from the database.",
+        "/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes the key, which stops working immediately.",
                 "consumes": [
                     "application/json"
                 ],
@@ -126,31 +300,55 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Auth"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List all users",
                 "responses": {
                     "200": {
-                        "description": "List of users",
+                        "description": "API key revoked",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Credentials lack the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/main.jsonResponse"
                         }
                     },
                     "500": {
-                        "description": "Error listing users",
+                        "description": "Error revoking key",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.jsonResponse"
                         }
                     }
                 }
             }
         },
-        "/oauth2callback": {
+        "/book/{slug}": {
             "get": {
-                "description": "Handles the Google OAuth2 callback and stores the access token.",
+                "description": "Public endpoint returning a booking page's title, questions and the times guests can book, computed from the owner's or group's availability, working hours and booking constraints. Requests are rate limited per client IP.",
                 "consumes": [
                     "application/json"
                 ],
@@ -158,28 +356,324 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Booking"
+                ],
+                "summary": "Show a booking page",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Booking page slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA time zone of the times (default UTC)",
+                        "name": "tz",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Handles OAuth2 callback",
                 "responses": {
                     "200": {
-                        "description": "Authorization successful",
+                        "description": "Booking page",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.BookingPageView"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid time zone",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Booking page not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
                         }
                     },
                     "500": {
-                        "description": "Error during OAuth2 callback",
+                        "description": "Error reading availability",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Public endpoint booking one of the times a booking page offers. The meeting is created on the owner's calendar, with the group's members for group pages, and the guest is invited by email. The response shows the guest only the title, times, Meet link and a booking reference. Each guest email can book at most the page's max_bookings_per_guest meetings, and requests are rate limited per client IP.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Booking"
+                ],
+                "summary": "Book through a booking page",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Booking page slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Guest details, chosen start time and answers keyed by question id",
+                        "name": "booking",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.BookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Meeting booked",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.BookingView"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Missing guest email or required answer",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Booking page not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Time not available or booking limit reached",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error booking",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/booking-pages": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a public page at /book/{slug} through which people outside the service book meetings of duration_minutes with the user in the X-User-Email header, or with a group that user administers. Guests answer the page's questions when booking.\nThe rules limit how many days ahead times are offered (window_days, default 14), the minimum notice, buffers around meetings, the meetings per day and the bookings per guest email (max_bookings_per_guest, default 1).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Booking"
+                ],
+                "summary": "Create a booking page",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email of the page owner",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Booking page; owner is taken from the header",
+                        "name": "page",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/data.BookingPage"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Booking page created",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.BookingPage"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid booking page",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin of the group",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Slug already in use",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error creating booking page",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/booking-pages/{slug}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes a booking page. Meetings booked through it are kept. Only the owner, identified by the X-User-Email header, may remove a page.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Booking"
+                ],
+                "summary": "Remove a booking page",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Booking page slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email of the page owner",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Booking page removed",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the owner of the page",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Booking page not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error removing booking page",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
                         }
                     }
                 }
             }
         },
-        "/routes": {
+        "/groups/{name}/availability": {
             "get": {
-                "description": "Defines all the routes and the associated handlers for the application.",
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves the slots in which all members of a group with a valid token are available, using Google's free/busy data only. Members without a valid token are listed in unavailable_members.\nWith required, optional or min_attendees the search only needs the required members and at least min_attendees people to be free, and returns ranked_slots ordered by how many optional members can make it, each listing who is missing.\nWith room_capacity, room_building or room_features slots are limited to times in which a matching room is free.\nFor a group with a distribution mode only one member hosts each meeting, so slots are the times in which any member is free, and distribution names the mode.",
                 "consumes": [
                     "application/json"
                 ],
@@ -187,18 +681,3952 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Routes"
+                    "Group"
+                ],
+                "summary": "Check group availability",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the window (RFC 3339), defaults to now",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the window (RFC 3339), defaults to seven days after from",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The window in words instead of from and to, e.g. next Tuesday afternoon; read in tz. A length it mentions is the default min_duration",
+                        "name": "when",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum slot length as a Go duration, e.g. 30m or 1h (default 30m)",
+                        "name": "min_duration",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA time zone of the response (default UTC); slots follow the working hours in each user's preferences",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Time kept free before every meeting, overriding the users' preferences, e.g. 10m",
+                        "name": "buffer_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Time kept free after every meeting, overriding the users' preferences, e.g. 10m",
+                        "name": "buffer_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "How far ahead of now slots have to start, overriding the users' preferences, e.g. 2h",
+                        "name": "min_notice",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Days with this many meetings are closed, overriding the users' preferences; 0 for no cap",
+                        "name": "max_per_day",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Weeks with this many meetings are closed, overriding the users' preferences; 0 for no cap",
+                        "name": "max_per_week",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Longest run of meetings without a break, overriding the users' preferences, e.g. 2h",
+                        "name": "max_continuous",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Gap that ends a run of meetings (default 15m when max_continuous is set)",
+                        "name": "break",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated emails of members who must attend",
+                        "name": "required",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated emails of members who are nice to have",
+                        "name": "optional",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum number of attendees who must be free",
+                        "name": "min_attendees",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Require a free room with at least this many seats",
+                        "name": "room_capacity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Require the room to be in this building",
+                        "name": "room_building",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated features the room must have, e.g. video",
+                        "name": "room_features",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Set up API routes for the application",
                 "responses": {
                     "200": {
-                        "description": "Routes set successfully",
+                        "description": "Group availability",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.GroupAvailability"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters or a quorum email outside the group",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Group not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error retrieving availability",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.jsonResponse"
                         }
                     }
                 }
             }
+        },
+        "/groups/{name}/distribution": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Makes meetings booked with the group go to one host instead of every member. round_robin takes turns in alphabetical order, least_busy picks the member with the least meeting time in the week and weighted keeps each member's share of meetings in proportion to their weight (1 by default). An empty mode makes every member attend again.\nHosts are only picked among the members free at the meeting time, and past assignments are stored so turns carry over restarts. Only an admin of the group, identified by the X-User-Email header, may change the mode.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Group"
+                ],
+                "summary": "Set a group's distribution mode",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email of a group admin",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Distribution mode and member weights",
+                        "name": "distribution",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/data.Distribution"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Distribution updated",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Distribution"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid mode or a weight for someone outside the group",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin of the group",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Group not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error updating distribution",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/holds": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Reserves a proposed meeting slot for the organizer and the attendees, including every member of the listed groups, or the one member picked to host for a group with a distribution mode, until the hold expires or is confirmed. While a hold is active its slot is busy in every availability search, so the same slot is not offered to anyone else.\nThe organizer has to be the user in the X-User-Email header, unless the call has the admin scope. ttl is a duration such as 15m (default 15m, at most 24h). Holds are kept in the service only; nothing is written to the calendars until the hold is confirmed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Meeting"
+                ],
+                "summary": "Hold a slot",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email of the organizer",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Meeting to hold a slot for",
+                        "name": "hold",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateHoldRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Slot held",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Hold"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid hold",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Organizer is not the acting user, or has to grant write access",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Organizer or group not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Slot overlaps an active hold or no host is available",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error holding slot",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/holds/{id}/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Books the meeting a hold was created for, with a Google Meet link and invitations, and returns it. Only the organizer of the hold, identified by the X-User-Email header, may confirm it, and only before it expires.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Meeting"
+                ],
+                "summary": "Confirm a hold",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Hold ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email of the organizer",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Meeting created",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Meeting"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not allowed to confirm the hold",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Hold, organizer or group not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Hold expired or already confirmed",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error confirming hold",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/list-groups": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves the list of all groups from the database.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Group"
+                ],
+                "summary": "List all groups",
+                "responses": {
+                    "200": {
+                        "description": "List of groups",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "string"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Error listing groups",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/list-users": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves the list of all users from the database.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "List all users",
+                "responses": {
+                    "200": {
+                        "description": "List of users",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "string"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Error listing users",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/meetings": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates an event with a Google Meet conference on the organizer's calendar and emails invitations to the attendees and to every member of the listed groups.\nThe organizer has to be the user in the X-User-Email header, unless the call has the admin scope. If the organizer has only granted read access, the 403 response carries in data the link that grants write access.\nWith a recurrence the meeting is created as one recurring event in the organizer's time zone. Every occurrence is checked first: occurrences at which attendees are busy or outside their working hours are returned with a 409, each with up to three free times on the same day, unless force is set.\nWith room the smallest registered room meeting the requirement that is free for the meeting, or for every occurrence, is booked as a resource attendee; a 409 is returned when there is none.\nA group with a distribution mode sends one host instead of all its members: the host is picked among the members free at the time and returned in host. Only one such group can be listed; a 409 is returned when none of its members is free.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Meeting"
+                ],
+                "summary": "Book a meeting",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email of the organizer",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Meeting details",
+                        "name": "meeting",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateMeetingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Meeting created",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Meeting"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid meeting",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Organizer is not the acting user, or has to grant write access",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Organizer or group not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Attendees are busy at some occurrences, or no room or host is free",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/data.OccurrenceConflict"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Error creating meeting",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/meetings/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes a meeting booked through the API from the organizer's calendar, notifies the attendees and records it as cancelled.\nOnly the organizer or an admin of one of the meeting's groups, identified by the X-User-Email header, may cancel a meeting.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Meeting"
+                ],
+                "summary": "Cancel a meeting",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Google Calendar event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email of the user cancelling the meeting",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Meeting cancelled",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Meeting"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not allowed to cancel the meeting",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Meeting not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Meeting already cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error cancelling meeting",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Changes the title, description or time of a meeting booked through the API and notifies the attendees. Before moving a meeting the availability of the attendees and of its room at the new time is checked; busy attendees and rooms are returned with a 409 unless force is set. A recurring meeting cannot be moved, which is refused with a 409.\nOnly the organizer or an admin of one of the meeting's groups, identified by the X-User-Email header, may change a meeting.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Meeting"
+                ],
+                "summary": "Reschedule a meeting",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Google Calendar event ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email of the user making the change",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "change",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateMeetingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Meeting updated",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Meeting"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid change",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not allowed to change the meeting",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Meeting not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Attendees or the room are busy at the new time, the meeting is recurring or it was cancelled",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "string"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Error updating meeting",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/oauth2callback": {
+            "get": {
+                "description": "Handles the Google OAuth2 callback, verifies the user's ID token and stores the access token under the verified email.\nThe response carries a session token, valid for 12 hours, to send as \"Authorization: Bearer \u003ctoken\u003e\" on calls made as this user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Handles OAuth2 callback",
+                "responses": {
+                    "200": {
+                        "description": "Authorization successful",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.SignIn"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "No code in request, or state not matching the consent link's cookie",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "ID token could not be verified",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error during OAuth2 callback",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/parse-window": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Converts an English phrase such as \"next Tuesday afternoon\", \"sometime this week after 3pm\" or \"an hour at the end of the month\" into the ranges it refers to, in the given time zone or in the time zone of the user with email. Relative days, weekdays, dates, parts of the day, times and lengths of meetings are understood.\nThe response echoes the interpretation in words so it can be confirmed with the user, with a confidence that drops when words were not understood or had to be guessed. ranges can be passed on to the availability endpoints, which also accept the phrase itself as when.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Calendar"
+                ],
+                "summary": "Parse a time window",
+                "parameters": [
+                    {
+                        "description": "Phrase and time zone",
+                        "name": "phrase",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ParseWindowRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Interpreted window",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/timeparse.Window"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "No time recognized, the time has passed or invalid time zone",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error reading preferences",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/polls": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Asks participants, who may be outside the organization, which of the candidate slots suit them. Each participant gets a token for their vote link, POST /polls/{id}/votes/{token}, returned only in this response.\nWith only_free the slots in which the organizer is busy or outside their working hours are dropped first. The organizer needs write access, as closing the poll books the meeting on their calendar, and has to be the user in the X-User-Email header unless the call has the admin scope.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Poll"
+                ],
+                "summary": "Create a scheduling poll",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email of the organizer",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Poll details",
+                        "name": "poll",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/data.PollRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Poll created",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Poll"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid poll",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Organizer is not the acting user, or has to grant write access",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Organizer not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error creating poll",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/polls/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the poll's slots, each listing the participants who answered yes, if_needed or no, and which participants have voted. Vote tokens are not included.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Poll"
+                ],
+                "summary": "Show a scheduling poll",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Poll ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Poll",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Poll"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Poll not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error reading poll",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/polls/{id}/close": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Closes the poll and books a meeting with every participant on the organizer's calendar, with a Google Meet link and invitations. The given slot is booked, or else the one most participants can attend, preferring yes over if_needed answers and then the earlier slot.\nOnly the organizer, identified by the X-User-Email header, may close a poll.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Poll"
+                ],
+                "summary": "Close a scheduling poll",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Poll ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email of the organizer",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Slot to book",
+                        "name": "close",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.ClosePollRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Meeting created",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.ClosedPoll"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid slot",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the organizer of the poll",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Poll not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Poll is already closed",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error closing poll",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/polls/{id}/votes/{token}": {
+            "post": {
+                "description": "Records the answers of the participant the token was issued to, one of yes, if_needed or no per slot in order. Voting again replaces the earlier answers until the poll is closed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Poll"
+                ],
+                "summary": "Vote on a scheduling poll",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Poll ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Participant's vote token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Answers",
+                        "name": "vote",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.VoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Vote recorded",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid answers",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Vote link is not valid",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Poll not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Poll is closed",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error recording vote",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/resources": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the registered rooms, smallest first, optionally only those with at least capacity seats, in building and with all of features.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Resource"
+                ],
+                "summary": "List rooms",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Least number of seats",
+                        "name": "capacity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Building the room is in",
+                        "name": "building",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated features the room must have, e.g. video",
+                        "name": "features",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rooms",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/data.Resource"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error listing rooms",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Registers a Google resource calendar as a bookable room. Only admins, identified by the X-User-Email header, may manage rooms.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Resource"
+                ],
+                "summary": "Register a room",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email of the admin",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Room details",
+                        "name": "resource",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/data.Resource"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Room registered",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Resource"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid room",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Room already registered",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error registering room",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/resources/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the name, capacity, building and features of a room. Only admins, identified by the X-User-Email header, may manage rooms.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Resource"
+                ],
+                "summary": "Update a room",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Calendar ID of the room",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email of the admin",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Room details; calendar_id is taken from the path",
+                        "name": "resource",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/data.Resource"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Room updated",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Resource"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid room",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Room not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error updating room",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes a room so it is no longer booked. Meetings already booked in it are kept. Only admins, identified by the X-User-Email header, may manage rooms.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Resource"
+                ],
+                "summary": "Remove a room",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Calendar ID of the room",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email of the admin",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Room removed",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Room not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error removing room",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Starts a multi-turn search for a meeting of the user in the X-User-Email header with the attendees and the members of the groups. The window is given by when, a phrase such as \"next week\", or by from and to (default: the next seven days); a length in when sets duration_minutes if it is not given (default 30).\nThe session keeps its constraints and the candidate times, up to limit (default 5), ranked like /suggestions. It expires 30 minutes after its last turn.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Session"
+                ],
+                "summary": "Start a scheduling session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email of the organizer",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "What to search for; organizer is taken from the header",
+                        "name": "session",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/data.SessionConstraints"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Session started",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Session"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid session",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Group not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error starting session",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the session's constraints and current candidate times. Only the organizer, identified by the X-User-Email header, may read a session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Session"
+                ],
+                "summary": "Show a scheduling session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email of the organizer",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Session",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Session"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the organizer of the session",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Session expired",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error reading session",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions/{id}/turns": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Applies one turn of the conversation and returns the session with new candidate times:\nexclude_days removes weekdays or YYYY-MM-DD dates in days, change_duration sets duration_minutes, change_window replaces the window with the when phrase, and add_attendee and remove_attendee change the attendee or group.\npick books the candidate at position option, counted from 1, with a Google Meet link and returns the meeting with a 201. Each turn keeps the session for another 30 minutes.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Session"
+                ],
+                "summary": "Refine a scheduling session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email of the organizer",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Refinement",
+                        "name": "turn",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/data.Turn"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Session refined",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.TurnResult"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "201": {
+                        "description": "Meeting created",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/main.TurnResult"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid turn",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the organizer, or the organizer has to grant write access",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Session or group not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Session expired or already booked",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error applying turn",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/suggestions": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the best times, up to limit (default 5, at most 20), in which all attendees and the members of the listed groups are free for the whole duration (default 30m) between from and to (default: the next seven days).\nEach suggestion is scored between 0 and 1 from these factors: preferred_hours (attendees' preferred hours), soon (earlier is better), back_to_back (attendees get a break before and after), lunch (12:00-13:00 in tz stays free) and fragmentation (no unusably short gaps are left). weights sets how much each factor counts; all count equally by default and a zero weight switches a factor off.\nAttendees' buffers, minimum notice, meeting caps and breaks from their preferences apply; the constraint fields of the body override them for this search.\nEvery suggestion carries an explanation that can be read back to the user. Suggestions never overlap each other.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Meeting"
+                ],
+                "summary": "Suggest meeting times",
+                "parameters": [
+                    {
+                        "description": "Attendees, duration and window",
+                        "name": "search",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.SuggestionsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Suggested times",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Suggestions"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid search",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Group not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error suggesting times",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{email}/availability": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves free slots from the user's Google Calendar within a given time range.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Calendar"
+                ],
+                "summary": "Check user calendar availability",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User email",
+                        "name": "email",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the window (RFC 3339), defaults to now",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the window (RFC 3339), defaults to seven days after from",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "The window in words instead of from and to, e.g. next Tuesday afternoon; read in tz. A length it mentions is the default min_duration",
+                        "name": "when",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum slot length as a Go duration, e.g. 30m or 1h (default 30m)",
+                        "name": "min_duration",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IANA time zone of the response (default UTC); slots follow the working hours in each user's preferences",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "busy",
+                            "ignore"
+                        ],
+                        "type": "string",
+                        "description": "Whether all-day events block their days: busy (default) or ignore",
+                        "name": "all_day",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Time kept free before every meeting, overriding the users' preferences, e.g. 10m",
+                        "name": "buffer_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Time kept free after every meeting, overriding the users' preferences, e.g. 10m",
+                        "name": "buffer_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "How far ahead of now slots have to start, overriding the users' preferences, e.g. 2h",
+                        "name": "min_notice",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Days with this many meetings are closed, overriding the users' preferences; 0 for no cap",
+                        "name": "max_per_day",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Weeks with this many meetings are closed, overriding the users' preferences; 0 for no cap",
+                        "name": "max_per_week",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Longest run of meetings without a break, overriding the users' preferences, e.g. 2h",
+                        "name": "max_continuous",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Gap that ends a run of meetings (default 15m when max_continuous is set)",
+                        "name": "break",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of free slots",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/data.TimeSlot"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "User's authorization expired or was revoked",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User has not authorized the app",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error retrieving availability",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{email}/preferences": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the user's time zone, working hours per weekday, minimum meeting notice and maximum meetings per day. Users who have not set preferences work nine to five on weekdays in the time zone of their Google Calendar, or in UTC if it is not known.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get user preferences",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User email",
+                        "name": "email",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User preferences",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Preferences"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error getting preferences",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKey": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the user's scheduling preferences. working_hours maps weekday names (monday to sunday) to lists of ranges such as {\"start\": \"09:00\", \"end\": \"13:00\"}, so split shifts and weekend hours can be expressed; days that are left out are days off. Times are wall-clock times in timezone, which has to be an IANA time zone name. A max_meetings_per_day of 0 means no limit. Users set their own preferences; calls with the admin scope may set anyone's.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Set user preferences",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User email",
+                        "name": "email",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email of the user, unless the call has the admin scope",
+                        "name": "X-User-Email",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "New preferences; email is taken from the path",
+                        "name": "preferences",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/data.Preferences"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Preferences saved",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/main.jsonResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/data.Preferences"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid preferences",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing X-User-Email header",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the acting user",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Error saving preferences",
+                        "schema": {
+                            "$ref": "#/definitions/main.jsonResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "data.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T10:00:00Z"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is when the key stops working; keys without it do not expire.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-01-01T00:00:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "5d41402abc4b2a76b9719d911017c592"
+                },
+                "key": {
+                    "description": "Key is the secret to send in the X-API-Key header. It is only returned when the\nkey is created.",
+                    "type": "string",
+                    "example": "cal_9f86d081884c7d659a2feaa0c55ad015"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "WatsonX extension"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "read-availability",
+                            "book-meetings",
+                            "admin"
+                        ]
+                    },
+                    "example": [
+                        "read-availability",
+                        "book-meetings"
+                    ]
+                }
+            }
+        },
+        "data.BookingPage": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "A short call to get to know each other"
+                },
+                "duration_minutes": {
+                    "description": "DurationMinutes is the length of the meetings booked through the page.",
+                    "type": "integer",
+                    "example": 30
+                },
+                "group": {
+                    "type": "string",
+                    "example": "sales"
+                },
+                "owner": {
+                    "type": "string",
+                    "example": "anna@example.com"
+                },
+                "questions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.BookingQuestion"
+                    }
+                },
+                "rules": {
+                    "$ref": "#/definitions/data.BookingRules"
+                },
+                "slug": {
+                    "type": "string",
+                    "example": "anna-intro"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Intro call"
+                }
+            }
+        },
+        "data.BookingQuestion": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "example": "company"
+                },
+                "label": {
+                    "type": "string",
+                    "example": "Which company are you with?"
+                },
+                "required": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "data.BookingRules": {
+            "type": "object",
+            "properties": {
+                "buffer_minutes": {
+                    "description": "BufferMinutes is kept free before and after every meeting.",
+                    "type": "integer",
+                    "example": 10
+                },
+                "max_bookings_per_guest": {
+                    "description": "MaxBookingsPerGuest caps the meetings one guest email can book, 1 by default.",
+                    "type": "integer",
+                    "example": 1
+                },
+                "max_per_day": {
+                    "description": "MaxPerDay closes days with this many meetings.",
+                    "type": "integer",
+                    "example": 3
+                },
+                "min_notice_minutes": {
+                    "description": "MinNoticeMinutes overrides the owner's minimum notice.",
+                    "type": "integer",
+                    "example": 240
+                },
+                "window_days": {
+                    "description": "WindowDays is how many days ahead times are offered, 14 by default.",
+                    "type": "integer",
+                    "example": 14
+                }
+            }
+        },
+        "data.Distribution": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "type": "string",
+                    "enum": [
+                        "round_robin",
+                        "least_busy",
+                        "weighted"
+                    ],
+                    "example": "round_robin"
+                },
+                "weights": {
+                    "description": "Weights are the members' shares of meetings in weighted mode, 1 by default.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "data.GroupAvailability": {
+            "type": "object",
+            "properties": {
+                "distribution": {
+                    "description": "Distribution is the group's host assignment mode. When set, Slots are the periods\nin which any member is available to host.",
+                    "type": "string",
+                    "example": "round_robin"
+                },
+                "group": {
+                    "type": "string",
+                    "example": "design"
+                },
+                "members": {
+                    "description": "Members are the members whose calendars were taken into account.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "ranked_slots": {
+                    "description": "RankedSlots are the periods in which the requested quorum is available, ordered\nby how many optional members can make it.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.QuorumSlot"
+                    }
+                },
+                "slots": {
+                    "description": "Slots are the periods in which every member in Members is available. They are\nonly computed when no quorum was requested.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.TimeSlot"
+                    }
+                },
+                "unavailable_members": {
+                    "description": "Unavailable are the members left out because their token is missing or invalid.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.UnavailableMember"
+                    }
+                }
+            }
+        },
+        "data.Hold": {
+            "type": "object",
+            "properties": {
+                "attendees": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "end": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T10:30:00+02:00"
+                },
+                "event_id": {
+                    "description": "EventID is the Google Calendar event the hold was confirmed into.",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-03T12:15:00Z"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "host": {
+                    "description": "Host is the member held to host the meeting for a group that assigns hosts.",
+                    "type": "string",
+                    "example": "bob@example.com"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015"
+                },
+                "organizer": {
+                    "type": "string",
+                    "example": "anna@example.com"
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T10:00:00+02:00"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "confirmed",
+                        "expired"
+                    ],
+                    "example": "active"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Design sync"
+                }
+            }
+        },
+        "data.HourRange": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "string",
+                    "example": "17:00"
+                },
+                "start": {
+                    "type": "string",
+                    "example": "09:00"
+                }
+            }
+        },
+        "data.Meeting": {
+            "type": "object",
+            "properties": {
+                "attendees": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Weekly review of open design questions"
+                },
+                "end": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T10:30:00+02:00"
+                },
+                "event_id": {
+                    "type": "string",
+                    "example": "5q8s0m7h2kq1m3b0f9g6v4c2pl"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "host": {
+                    "description": "Host is the member chosen to host the meeting for a group that assigns hosts.",
+                    "type": "string",
+                    "example": "bob@example.com"
+                },
+                "html_link": {
+                    "type": "string",
+                    "example": "https://www.google.com/calendar/event?eid=NXE4czBt"
+                },
+                "meet_url": {
+                    "type": "string",
+                    "example": "https://meet.google.com/abc-defg-hij"
+                },
+                "organizer": {
+                    "type": "string",
+                    "example": "anna@example.com"
+                },
+                "previous_end": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "previous_start": {
+                    "description": "PreviousStart and PreviousEnd are the times before the last reschedule.",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "recurrence": {
+                    "type": "string",
+                    "example": "RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=10"
+                },
+                "room": {
+                    "description": "Room is the calendar ID of the room booked for the meeting.",
+                    "type": "string",
+                    "example": "c_1888abc@resource.calendar.google.com"
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T10:00:00+02:00"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "scheduled",
+                        "rescheduled",
+                        "cancelled"
+                    ],
+                    "example": "scheduled"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Design sync"
+                }
+            }
+        },
+        "data.OccurrenceConflict": {
+            "type": "object",
+            "properties": {
+                "alternatives": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.TimeSlot"
+                    }
+                },
+                "busy": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "bob@example.com"
+                    ]
+                },
+                "end": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-13T10:30:00+02:00"
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-13T10:00:00+02:00"
+                }
+            }
+        },
+        "data.Period": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-14T17:00:00+02:00"
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-14T12:00:00+02:00"
+                }
+            }
+        },
+        "data.Poll": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "description": "EventID is the Google Calendar event booked when the poll was closed.",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015"
+                },
+                "organizer": {
+                    "type": "string",
+                    "example": "anna@example.com"
+                },
+                "participants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.PollParticipant"
+                    }
+                },
+                "slots": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.PollSlot"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "open",
+                        "closed"
+                    ],
+                    "example": "open"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Partner kickoff"
+                },
+                "winning_slot": {
+                    "description": "WinningSlot is the index of the slot the poll was closed with.",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "data.PollParticipant": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "jan@partner.example"
+                },
+                "token": {
+                    "description": "Token identifies the participant's vote link. It is only returned when the poll\nis created.",
+                    "type": "string",
+                    "example": "3f1d0c9e7b2a4c6d8e0f1a2b3c4d5e6f"
+                },
+                "voted": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "data.PollRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Kickoff of the integration project"
+                },
+                "only_free": {
+                    "description": "OnlyFree drops the candidate slots in which the organizer is not free.",
+                    "type": "boolean",
+                    "example": true
+                },
+                "organizer": {
+                    "type": "string",
+                    "example": "anna@example.com"
+                },
+                "participants": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "jan@partner.example"
+                    ]
+                },
+                "slots": {
+                    "description": "Slots are the candidate times, each with a start and an end.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.PollSlot"
+                    }
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Partner kickoff"
+                }
+            }
+        },
+        "data.PollSlot": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T11:00:00+02:00"
+                },
+                "if_needed": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "no": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T10:00:00+02:00"
+                },
+                "yes": {
+                    "description": "Yes, IfNeeded and No list the participants who gave each answer.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "data.Preferences": {
+            "type": "object",
+            "properties": {
+                "break_minutes": {
+                    "type": "integer",
+                    "example": 15
+                },
+                "buffer_after_minutes": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "buffer_before_minutes": {
+                    "description": "BufferBeforeMinutes and BufferAfterMinutes are kept free around every meeting.",
+                    "type": "integer",
+                    "example": 5
+                },
+                "email": {
+                    "type": "string",
+                    "example": "anna@example.com"
+                },
+                "max_continuous_minutes": {
+                    "description": "MaxContinuousMinutes is the longest run of meetings allowed without a break of at\nleast BreakMinutes; zero means no limit.",
+                    "type": "integer",
+                    "example": 120
+                },
+                "max_meetings_per_day": {
+                    "description": "MaxMeetingsPerDay caps the meetings booked on a day; zero means no cap.",
+                    "type": "integer",
+                    "example": 5
+                },
+                "max_meetings_per_week": {
+                    "description": "MaxMeetingsPerWeek caps the meetings booked from Monday to Sunday; zero means no cap.",
+                    "type": "integer",
+                    "example": 20
+                },
+                "min_notice_minutes": {
+                    "description": "MinNoticeMinutes is how far ahead meetings have to be booked.",
+                    "type": "integer",
+                    "example": 120
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "Europe/Warsaw"
+                },
+                "working_hours": {
+                    "$ref": "#/definitions/data.WorkingHours"
+                }
+            }
+        },
+        "data.QuorumSlot": {
+            "type": "object",
+            "properties": {
+                "attendees": {
+                    "description": "Attendees are the members who are free for the whole slot.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "available_optional": {
+                    "description": "AvailableOptional is the number of optional members who can make it.",
+                    "type": "integer",
+                    "example": 6
+                },
+                "missing": {
+                    "description": "Missing are the members who are busy during at least part of the slot.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "slot": {
+                    "$ref": "#/definitions/data.TimeSlot"
+                }
+            }
+        },
+        "data.Resource": {
+            "type": "object",
+            "properties": {
+                "building": {
+                    "type": "string",
+                    "example": "Krakow HQ"
+                },
+                "calendar_id": {
+                    "description": "CalendarID is the email address of the resource calendar.",
+                    "type": "string",
+                    "example": "c_1888abc@resource.calendar.google.com"
+                },
+                "capacity": {
+                    "type": "integer",
+                    "example": 8
+                },
+                "features": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "video",
+                        "whiteboard"
+                    ]
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Krakow-3-Wawel"
+                }
+            }
+        },
+        "data.RoomRequirement": {
+            "type": "object",
+            "properties": {
+                "building": {
+                    "type": "string",
+                    "example": "Krakow HQ"
+                },
+                "capacity": {
+                    "description": "Capacity is the least number of seats.",
+                    "type": "integer",
+                    "example": 6
+                },
+                "features": {
+                    "description": "Features lists what the room has to offer, such as video.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "video"
+                    ]
+                }
+            }
+        },
+        "data.Session": {
+            "type": "object",
+            "properties": {
+                "candidates": {
+                    "description": "Candidates are the times on offer, best first; pick refers to them by position.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.Suggestion"
+                    }
+                },
+                "constraints": {
+                    "$ref": "#/definitions/data.SessionConstraints"
+                },
+                "event_id": {
+                    "description": "EventID is the Google Calendar event booked by pick.",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-08T10:30:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "booked"
+                    ],
+                    "example": "active"
+                },
+                "unavailable_attendees": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.UnavailableMember"
+                    }
+                }
+            }
+        },
+        "data.SessionConstraints": {
+            "type": "object",
+            "properties": {
+                "attendees": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "bob@example.com"
+                    ]
+                },
+                "duration_minutes": {
+                    "description": "DurationMinutes is the length of the meeting, 30 by default.",
+                    "type": "integer",
+                    "example": 30
+                },
+                "excluded_days": {
+                    "description": "ExcludedDays are weekdays, such as monday, or dates in YYYY-MM-DD form.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "monday"
+                    ]
+                },
+                "from": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-13T00:00:00+02:00"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "design"
+                    ]
+                },
+                "limit": {
+                    "description": "Limit is how many candidate times are offered, 5 by default.",
+                    "type": "integer",
+                    "example": 5
+                },
+                "organizer": {
+                    "type": "string",
+                    "example": "anna@example.com"
+                },
+                "periods": {
+                    "description": "Periods are the parts of the window When describes.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.Period"
+                    }
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Design review"
+                },
+                "to": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-20T00:00:00+02:00"
+                },
+                "tz": {
+                    "type": "string",
+                    "example": "Europe/Warsaw"
+                },
+                "when": {
+                    "description": "When describes the window in words, such as \"next week\", instead of From and To.",
+                    "type": "string",
+                    "example": "next week"
+                }
+            }
+        },
+        "data.SlotStatus": {
+            "type": "string",
+            "enum": [
+                "free",
+                "tentative",
+                "busy"
+            ],
+            "x-enum-varnames": [
+                "SlotFree",
+                "SlotTentative",
+                "SlotBusy"
+            ]
+        },
+        "data.Suggestion": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T10:30:00+02:00"
+                },
+                "explanation": {
+                    "type": "string",
+                    "example": "Mon 6 May 10:00-10:30: within everyone's preferred hours, no back-to-back meetings, keeps lunch free."
+                },
+                "factors": {
+                    "$ref": "#/definitions/data.SuggestionFactors"
+                },
+                "score": {
+                    "type": "number",
+                    "example": 0.87
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T10:00:00+02:00"
+                }
+            }
+        },
+        "data.SuggestionFactors": {
+            "type": "object",
+            "properties": {
+                "back_to_back": {
+                    "type": "number",
+                    "example": 1
+                },
+                "fragmentation": {
+                    "type": "number",
+                    "example": 0.5
+                },
+                "lunch": {
+                    "type": "number",
+                    "example": 1
+                },
+                "preferred_hours": {
+                    "type": "number",
+                    "example": 1
+                },
+                "soon": {
+                    "type": "number",
+                    "example": 0.86
+                }
+            }
+        },
+        "data.SuggestionWeights": {
+            "type": "object",
+            "properties": {
+                "back_to_back": {
+                    "type": "number",
+                    "example": 1
+                },
+                "fragmentation": {
+                    "type": "number",
+                    "example": 1
+                },
+                "lunch": {
+                    "type": "number",
+                    "example": 1
+                },
+                "preferred_hours": {
+                    "type": "number",
+                    "example": 1
+                },
+                "soon": {
+                    "type": "number",
+                    "example": 1
+                }
+            }
+        },
+        "data.Suggestions": {
+            "type": "object",
+            "properties": {
+                "attendees": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "suggestions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.Suggestion"
+                    }
+                },
+                "unavailable_attendees": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.UnavailableMember"
+                    }
+                }
+            }
+        },
+        "data.TimeSlot": {
+            "type": "object",
+            "properties": {
+                "duration_minutes": {
+                    "type": "integer",
+                    "example": 90
+                },
+                "end": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T10:30:00+02:00"
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T09:00:00+02:00"
+                },
+                "status": {
+                    "enum": [
+                        "free",
+                        "tentative"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/data.SlotStatus"
+                        }
+                    ],
+                    "example": "free"
+                }
+            }
+        },
+        "data.Turn": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "exclude_days",
+                        "change_duration",
+                        "change_window",
+                        "add_attendee",
+                        "remove_attendee",
+                        "pick"
+                    ],
+                    "example": "exclude_days"
+                },
+                "attendee": {
+                    "description": "Attendee and Group are added or removed by add_attendee and remove_attendee.",
+                    "type": "string",
+                    "example": "carol@example.com"
+                },
+                "days": {
+                    "description": "Days are the weekdays or YYYY-MM-DD dates exclude_days removes.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "monday"
+                    ]
+                },
+                "duration_minutes": {
+                    "description": "DurationMinutes is the new length for change_duration.",
+                    "type": "integer",
+                    "example": 45
+                },
+                "group": {
+                    "type": "string",
+                    "example": "design"
+                },
+                "option": {
+                    "description": "Option is the candidate, counted from 1, that pick books.",
+                    "type": "integer",
+                    "example": 2
+                },
+                "when": {
+                    "description": "When is the new window for change_window, such as \"next week\".",
+                    "type": "string",
+                    "example": "next week"
+                }
+            }
+        },
+        "data.UnavailableMember": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "anna@example.com"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "user has not authorized the app"
+                }
+            }
+        },
+        "data.WorkingHours": {
+            "type": "object",
+            "additionalProperties": {
+                "type": "array",
+                "items": {
+                    "$ref": "#/definitions/data.HourRange"
+                }
+            }
+        },
+        "main.AddUserToGroupRequest": {
+            "type": "object",
+            "properties": {
+                "group_name": {
+                    "type": "string",
+                    "example": "design"
+                },
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "member",
+                        "admin"
+                    ],
+                    "example": "member"
+                },
+                "user_email": {
+                    "type": "string",
+                    "example": "anna@example.com"
+                }
+            }
+        },
+        "main.BookRequest": {
+            "type": "object",
+            "properties": {
+                "answers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "guest_email": {
+                    "type": "string",
+                    "example": "partner@example.org"
+                },
+                "guest_name": {
+                    "type": "string",
+                    "example": "Pat Partner"
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T10:00:00+02:00"
+                }
+            }
+        },
+        "main.BookingPageView": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "A short call to get to know each other"
+                },
+                "duration_minutes": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "questions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.BookingQuestion"
+                    }
+                },
+                "slug": {
+                    "type": "string",
+                    "example": "anna-intro"
+                },
+                "times": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/data.TimeSlot"
+                    }
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Intro call"
+                }
+            }
+        },
+        "main.BookingView": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "meet_url": {
+                    "type": "string",
+                    "example": "https://meet.google.com/abc-defg-hij"
+                },
+                "reference": {
+                    "description": "Reference identifies the booking, as the ID of its event.",
+                    "type": "string",
+                    "example": "5q8s0m7h2kq1m3b0f9g6v4c2pl"
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Intro call with Pat Partner"
+                }
+            }
+        },
+        "main.ClosePollRequest": {
+            "type": "object",
+            "properties": {
+                "slot": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "main.ClosedPoll": {
+            "type": "object",
+            "properties": {
+                "meeting": {
+                    "$ref": "#/definitions/data.Meeting"
+                },
+                "poll": {
+                    "$ref": "#/definitions/data.Poll"
+                }
+            }
+        },
+        "main.CreateAPIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "description": "ExpiresAt is when the key stops working; without it the key does not expire.",
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2025-01-01T00:00:00Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "WatsonX extension"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "read-availability",
+                            "book-meetings",
+                            "admin"
+                        ]
+                    },
+                    "example": [
+                        "read-availability",
+                        "book-meetings"
+                    ]
+                }
+            }
+        },
+        "main.CreateHoldRequest": {
+            "type": "object",
+            "properties": {
+                "attendees": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "bob@example.com"
+                    ]
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Weekly review of open design questions"
+                },
+                "end": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T10:30:00+02:00"
+                },
+                "force": {
+                    "description": "Force books a series even if attendees are busy at some occurrences.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "design"
+                    ]
+                },
+                "organizer": {
+                    "type": "string",
+                    "example": "anna@example.com"
+                },
+                "recurrence": {
+                    "description": "Recurrence is an RRULE with FREQ=DAILY, WEEKLY or MONTHLY, INTERVAL, BYDAY and\nCOUNT or UNTIL. Start has to be the first occurrence.",
+                    "type": "string",
+                    "example": "RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=10"
+                },
+                "room": {
+                    "description": "Room asks for a free room to be booked with the meeting.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/data.RoomRequirement"
+                        }
+                    ]
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T10:00:00+02:00"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Design sync"
+                },
+                "ttl": {
+                    "type": "string",
+                    "example": "15m"
+                }
+            }
+        },
+        "main.CreateMeetingRequest": {
+            "type": "object",
+            "properties": {
+                "attendees": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "bob@example.com"
+                    ]
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Weekly review of open design questions"
+                },
+                "end": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T10:30:00+02:00"
+                },
+                "force": {
+                    "description": "Force books a series even if attendees are busy at some occurrences.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "design"
+                    ]
+                },
+                "organizer": {
+                    "type": "string",
+                    "example": "anna@example.com"
+                },
+                "recurrence": {
+                    "description": "Recurrence is an RRULE with FREQ=DAILY, WEEKLY or MONTHLY, INTERVAL, BYDAY and\nCOUNT or UNTIL. Start has to be the first occurrence.",
+                    "type": "string",
+                    "example": "RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=10"
+                },
+                "room": {
+                    "description": "Room asks for a free room to be booked with the meeting.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/data.RoomRequirement"
+                        }
+                    ]
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T10:00:00+02:00"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Design sync"
+                }
+            }
+        },
+        "main.ParseWindowRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "anna@example.com"
+                },
+                "text": {
+                    "type": "string",
+                    "example": "next Tuesday afternoon"
+                },
+                "tz": {
+                    "description": "TZ is the IANA time zone to read the phrase in. Without it the time zone in the\npreferences of Email is used, or UTC.",
+                    "type": "string",
+                    "example": "Europe/Warsaw"
+                }
+            }
+        },
+        "main.SignIn": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "anna@example.com"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T22:00:00Z"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "eyJlbWFpbCI6ImFubmFAZXhhbXBsZS5jb20ifQ.c2lnbmF0dXJl"
+                }
+            }
+        },
+        "main.SuggestionsRequest": {
+            "type": "object",
+            "properties": {
+                "attendees": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "anna@example.com",
+                        "bob@example.com"
+                    ]
+                },
+                "break": {
+                    "type": "string",
+                    "example": "15m"
+                },
+                "buffer_after": {
+                    "type": "string",
+                    "example": "10m"
+                },
+                "buffer_before": {
+                    "type": "string",
+                    "example": "10m"
+                },
+                "duration": {
+                    "description": "Duration is the length of the meeting as a Go duration.",
+                    "type": "string",
+                    "example": "30m"
+                },
+                "from": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-06T00:00:00+02:00"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "design"
+                    ]
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "max_continuous": {
+                    "type": "string",
+                    "example": "2h"
+                },
+                "max_per_day": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "max_per_week": {
+                    "type": "integer",
+                    "example": 15
+                },
+                "min_notice": {
+                    "type": "string",
+                    "example": "2h"
+                },
+                "preferred_hours": {
+                    "description": "PreferredHours maps attendee emails to the hours they prefer to meet in.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/data.HourRange"
+                    }
+                },
+                "to": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-10T00:00:00+02:00"
+                },
+                "tz": {
+                    "type": "string",
+                    "example": "Europe/Warsaw"
+                },
+                "weights": {
+                    "$ref": "#/definitions/data.SuggestionWeights"
+                }
+            }
+        },
+        "main.TurnResult": {
+            "type": "object",
+            "properties": {
+                "meeting": {
+                    "$ref": "#/definitions/data.Meeting"
+                },
+                "session": {
+                    "$ref": "#/definitions/data.Session"
+                }
+            }
+        },
+        "main.UpdateMeetingRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Moved because of the release"
+                },
+                "end": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-07T10:30:00+02:00"
+                },
+                "force": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-07T10:00:00+02:00"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Design sync (moved)"
+                }
+            }
+        },
+        "main.VoteRequest": {
+            "type": "object",
+            "properties": {
+                "answers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "yes",
+                            "if_needed",
+                            "no"
+                        ]
+                    },
+                    "example": [
+                        "yes",
+                        "if_needed",
+                        "no"
+                    ]
+                }
+            }
+        },
+        "main.jsonResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "error": {
+                    "type": "boolean"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "timeparse.Range": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-14T17:00:00+02:00"
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-14T12:00:00+02:00"
+                }
+            }
+        },
+        "timeparse.Window": {
+            "type": "object",
+            "properties": {
+                "confidence": {
+                    "description": "Confidence is between 0 and 1, lower when words were not understood or had to\nbe guessed, such as \"at 3\" without am or pm.",
+                    "type": "number",
+                    "example": 0.9
+                },
+                "duration_minutes": {
+                    "description": "DurationMinutes is the meeting length the phrase mentions, if any.",
+                    "type": "integer",
+                    "example": 60
+                },
+                "end": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-14T17:00:00+02:00"
+                },
+                "interpretation": {
+                    "description": "Interpretation describes the window in words, to be read back to the user.",
+                    "type": "string",
+                    "example": "Tuesday 14 May, 12:00-17:00 (Europe/Warsaw)"
+                },
+                "ranges": {
+                    "description": "Ranges are the parts of the window the phrase refers to, in order.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/timeparse.Range"
+                    }
+                },
+                "start": {
+                    "type": "string",
+                    "format": "date-time",
+                    "example": "2024-05-14T12:00:00+02:00"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKey": {
+            "description": "API key issued with POST /api-keys",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "Session token returned after signing in, as \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -0,0 +1,100 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testBase struct {
+	ID string `json:"id" example:"abc"`
+}
+
+type testItem struct {
+	testBase
+	Name     string        `json:"name" example:"Design sync"`
+	Status   string        `json:"status" enums:"open,closed" example:"open"`
+	Answers  []string      `json:"answers" enums:"yes,no" example:"yes,no"`
+	Count    int           `json:"count" example:"3"`
+	Ratio    float64       `json:"ratio" example:"0.5"`
+	Start    time.Time     `json:"start" example:"2024-05-06T10:00:00+02:00"`
+	Length   time.Duration `json:"length_minutes" swaggertype:"integer" example:"30"`
+	Labels   map[string]string
+	Children []*testItem `json:"children,omitempty"`
+	Secret   string      `json:"-"`
+	hidden   string
+}
+
+func TestSchema(t *testing.T) {
+	doc := New(Info{Title: "Test", Version: "1"})
+
+	ref := doc.Schema([]testItem{})
+	if ref.Type != "array" || ref.Items == nil || ref.Items.Ref != "#/components/schemas/testItem" {
+		t.Fatalf("expected an array of references to testItem, got %+v", ref)
+	}
+
+	s := doc.Components.Schemas["testItem"]
+	if s == nil {
+		t.Fatal("expected testItem in the components")
+	}
+
+	expected := map[string]*Schema{
+		"id":             {Type: "string", Example: "abc"},
+		"name":           {Type: "string", Example: "Design sync"},
+		"status":         {Type: "string", Enum: []any{"open", "closed"}, Example: "open"},
+		"answers":        {Type: "array", Items: &Schema{Type: "string", Enum: []any{"yes", "no"}}, Example: []any{"yes", "no"}},
+		"count":          {Type: "integer", Example: int64(3)},
+		"ratio":          {Type: "number", Example: 0.5},
+		"start":          {Type: "string", Format: "date-time", Example: "2024-05-06T10:00:00+02:00"},
+		"length_minutes": {Type: "integer", Example: int64(30)},
+		"Labels":         {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+		"children":       {Type: "array", Items: &Schema{Ref: "#/components/schemas/testItem"}},
+	}
+	if len(s.Properties) != len(expected) {
+		t.Errorf("expected %d properties, got %d: %v", len(expected), len(s.Properties), s.Properties)
+	}
+	for name, want := range expected {
+		if got := s.Properties[name]; !reflect.DeepEqual(got, want) {
+			t.Errorf("property %s: expected %+v, got %+v", name, want, got)
+		}
+	}
+
+	_, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("encoding document: %v", err)
+	}
+}
+
+func TestSchemaNameCollision(t *testing.T) {
+	doc := New(Info{Title: "Test", Version: "1"})
+	first := doc.Schema(testBase{})
+
+	// A type of the same name declared elsewhere.
+	type testBase struct {
+		Other bool `json:"other"`
+	}
+	second := doc.Schema(testBase{})
+
+	if first.Ref != "#/components/schemas/testBase" {
+		t.Errorf("unexpected reference %s", first.Ref)
+	}
+	if second.Ref != "#/components/schemas/openapi.testBase" {
+		t.Errorf("expected the second testBase to be qualified, got %s", second.Ref)
+	}
+	if doc.Schema(testBase{}).Ref != second.Ref {
+		t.Error("expected the same type to keep its name")
+	}
+}
+
+func TestAddTwice(t *testing.T) {
+	doc := New(Info{Title: "Test", Version: "1"})
+	doc.Add("GET", "/items", &Operation{OperationID: "listItems"})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected adding an operation twice to panic")
+		}
+	}()
+	doc.Add("get", "/items", &Operation{OperationID: "listItemsAgain"})
+}
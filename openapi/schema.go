@@ -0,0 +1,198 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema describes a JSON value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Example              any                `json:"example,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	numberType   = reflect.TypeOf(json.Number(""))
+)
+
+// Schema returns the schema of the JSON encoding of v's type. Structs are added to the
+// document's components and referred to by name; their fields are described by the
+// json, example, enums, format and swaggertype tags also read by swag, so the types
+// documented for Swagger need no changes.
+func (d *Document) Schema(v any) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+	return d.schema(reflect.TypeOf(v))
+}
+
+func (d *Document) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64"}
+	case numberType:
+		return &Schema{Type: "number"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem())}
+	case reflect.Struct:
+		return d.ref(t)
+	}
+	// Interfaces can hold any value.
+	return &Schema{}
+}
+
+// ref adds the schema of struct type t to the components, once, and refers to it.
+func (d *Document) ref(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return d.object(t)
+	}
+
+	name, ok := d.types[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := d.Components.Schemas[name]; taken {
+			pkg := t.PkgPath()
+			name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+		}
+		d.types[t] = name
+		// Registered before the fields are read so recursive types end in a reference.
+		d.Components.Schemas[name] = &Schema{}
+		*d.Components.Schemas[name] = *d.object(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// object returns the schema of struct type t with a property per encoded field.
+func (d *Document) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	d.fields(t, s)
+	return s
+}
+
+func (d *Document) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || f.Tag.Get("swaggerignore") == "true" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				d.fields(ft, s)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		s.Properties[name] = d.field(f)
+	}
+}
+
+// field returns the schema of a struct field, applying its tags.
+func (d *Document) field(f reflect.StructField) *Schema {
+	var s *Schema
+	switch typ := f.Tag.Get("swaggertype"); typ {
+	case "":
+		s = d.schema(f.Type)
+	case "primitive,integer":
+		s = &Schema{Type: "integer"}
+	default:
+		s = &Schema{Type: strings.TrimPrefix(typ, "primitive,")}
+	}
+
+	if format := f.Tag.Get("format"); format != "" {
+		s.Format = format
+	}
+
+	// Tags of a list describe its items: enums lists the allowed items and example is
+	// comma separated, as for swag.
+	value := s
+	if s.Type == "array" && s.Items != nil && s.Items.Ref == "" {
+		value = s.Items
+	}
+	if enums := f.Tag.Get("enums"); enums != "" {
+		for _, e := range strings.Split(enums, ",") {
+			value.Enum = append(value.Enum, parseValue(value.Type, e))
+		}
+	}
+	if example, ok := f.Tag.Lookup("example"); ok {
+		if s.Type == "array" {
+			var items []any
+			for _, e := range strings.Split(example, ",") {
+				items = append(items, parseValue(value.Type, e))
+			}
+			s.Example = items
+		} else if s.Ref == "" {
+			s.Example = parseValue(s.Type, example)
+		}
+	}
+	return s
+}
+
+// parseValue converts the text of a tag to a value of the JSON type typ, leaving text
+// that does not parse as a string.
+func parseValue(typ, text string) any {
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(text, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(text); err == nil {
+			return b
+		}
+	}
+	return text
+}
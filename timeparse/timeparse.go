@@ -0,0 +1,883 @@
+// Package timeparse turns English descriptions of when to meet, such as "next Tuesday
+// afternoon" or "sometime this week after 3pm", into concrete time ranges.
+package timeparse
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoTime is returned for phrases in which no time expression is recognized.
+	ErrNoTime = errors.New("no time expression recognized")
+	// ErrInPast is returned when the time a phrase describes has already passed.
+	ErrInPast = errors.New("the time described has already passed")
+)
+
+const (
+	// defaultDays is how many days a phrase naming only a duration covers.
+	defaultDays = 7
+	// defaultAtLength is the length of the range of "at 3pm" when no duration is given.
+	defaultAtLength = time.Hour
+	minutesPerDay   = 24 * 60
+)
+
+// Range is a period a phrase refers to.
+type Range struct {
+	Start time.Time `json:"start" format:"date-time" example:"2024-05-14T12:00:00+02:00"`
+	End   time.Time `json:"end" format:"date-time" example:"2024-05-14T17:00:00+02:00"`
+}
+
+// Window is the interpretation of a phrase: the period it covers and, when it names a
+// time of day, the range on each day.
+type Window struct {
+	Start time.Time `json:"start" format:"date-time" example:"2024-05-14T12:00:00+02:00"`
+	End   time.Time `json:"end" format:"date-time" example:"2024-05-14T17:00:00+02:00"`
+	// Ranges are the parts of the window the phrase refers to, in order.
+	Ranges []Range `json:"ranges"`
+	// DurationMinutes is the meeting length the phrase mentions, if any.
+	DurationMinutes int `json:"duration_minutes,omitempty" example:"60"`
+	// Confidence is between 0 and 1, lower when words were not understood or had to
+	// be guessed, such as "at 3" without am or pm.
+	Confidence float64 `json:"confidence" example:"0.9"`
+	// Interpretation describes the window in words, to be read back to the user.
+	Interpretation string `json:"interpretation" example:"Tuesday 14 May, 12:00-17:00 (Europe/Warsaw)"`
+}
+
+// Duration returns the meeting length the phrase mentions, or 0.
+func (w *Window) Duration() time.Duration {
+	return time.Duration(w.DurationMinutes) * time.Minute
+}
+
+// Parse interprets phrase relative to now, in loc. Days start at midnight in loc and
+// weeks on Monday; ranges that have already passed are dropped.
+func Parse(phrase string, now time.Time, loc *time.Location) (*Window, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	now = now.In(loc)
+
+	p := &parser{
+		tokens:  tokenize(phrase),
+		now:     now,
+		today:   time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc),
+		to:      minutesPerDay,
+		penalty: 1,
+	}
+	p.parse()
+
+	if p.known == 0 {
+		return nil, ErrNoTime
+	}
+	return p.window()
+}
+
+var (
+	clockPattern    = regexp.MustCompile(`^(\d{1,2})(?:[:.](\d{2}))?(am|pm)?$`)
+	durationPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)(m|min|mins|minutes?|h|hr|hrs|hours?)$`)
+	ordinalPattern  = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+)
+
+var weekdays = map[string]time.Weekday{
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday,
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+var numbers = map[string]float64{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	"fifteen": 15, "twenty": 20, "thirty": 30, "forty": 40, "sixty": 60, "ninety": 90,
+	"couple": 2, "few": 3,
+}
+
+// dayParts are the clock ranges, in minutes, of parts of the day.
+var dayParts = map[string][2]int{
+	"morning":   {9 * 60, 12 * 60},
+	"afternoon": {12 * 60, 17 * 60},
+	"evening":   {17 * 60, 21 * 60},
+	"night":     {17 * 60, 21 * 60},
+	"lunch":     {12 * 60, 13 * 60},
+	"lunchtime": {12 * 60, 13 * 60},
+	"noon":      {12 * 60, 13 * 60},
+	"midday":    {12 * 60, 13 * 60},
+}
+
+// fillers are words that carry no time information.
+var fillers = map[string]bool{
+	"a": true, "an": true, "the": true, "on": true, "in": true, "at": true, "for": true,
+	"of": true, "to": true, "and": true, "or": true, "with": true, "during": true,
+	"sometime": true, "some": true, "time": true, "anytime": true, "any": true,
+	"around": true, "about": true, "roughly": true, "approximately": true,
+	"please": true, "maybe": true, "perhaps": true, "preferably": true, "ideally": true,
+	"i": true, "we": true, "me": true, "us": true, "is": true, "are": true, "be": true,
+	"can": true, "could": true, "would": true, "should": true, "let's": true, "lets": true,
+	"want": true, "need": true, "like": true, "how": true, "what": true, "when": true,
+	"free": true, "available": true, "meet": true, "meeting": true, "call": true,
+	"sync": true, "schedule": true, "book": true, "find": true, "slot": true, "by": true,
+	"-": true,
+}
+
+// parser holds what has been understood of a phrase so far.
+type parser struct {
+	tokens []string
+	pos    int
+	now    time.Time
+	today  time.Time
+
+	// Days the phrase refers to, inclusive
+	firstDay, lastDay time.Time
+	hasDays           bool
+	daysText          string
+
+	// Clock range, in minutes after midnight, applied to every day
+	from, to  int
+	hasClock  bool
+	at        int
+	hasAt     bool
+	notBefore time.Time
+
+	length time.Duration
+	// modifier is a pending "early" or "late", applied to the next part of day, week or month
+	modifier string
+
+	known, unknown int
+	penalty        float64
+}
+
+// tokenize splits phrase into lowercase words, keeping clock times such as 3:30pm together.
+func tokenize(phrase string) []string {
+	phrase = strings.ToLower(phrase)
+	phrase = strings.NewReplacer("a.m.", "am", "p.m.", "pm", "-", " - ", "’", "'").Replace(phrase)
+
+	var tokens []string
+	for _, field := range strings.Fields(phrase) {
+		field = strings.Trim(field, ",.!?;:\"()")
+		if field != "" {
+			tokens = append(tokens, field)
+		}
+	}
+	return tokens
+}
+
+func (p *parser) peek(offset int) string {
+	if p.pos+offset < len(p.tokens) {
+		return p.tokens[p.pos+offset]
+	}
+	return ""
+}
+
+// consume advances past n recognized tokens.
+func (p *parser) consume(n int) bool {
+	p.pos += n
+	p.known += n
+	return true
+}
+
+func (p *parser) parse() {
+	rules := []func() bool{
+		p.relativeOffset,
+		p.dayAfterTomorrow,
+		p.namedDay,
+		p.relative,
+		p.boundary,
+		p.weekday,
+		p.monthDay,
+		p.duration,
+		p.clockRange,
+		p.dayPart,
+		p.modifierWord,
+	}
+
+	for p.pos < len(p.tokens) {
+		matched := false
+		for _, rule := range rules {
+			if rule() {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		if !fillers[p.peek(0)] {
+			p.unknown++
+		}
+		p.pos++
+	}
+
+	if p.modifier != "" {
+		p.unknown++
+	}
+}
+
+// setDays records the days the phrase refers to, narrowed by a pending modifier.
+func (p *parser) setDays(first, last time.Time, text string) {
+	// "early" and "late" halve ranges of days; single days keep them for a part of day
+	if days := int(last.Sub(first).Hours()/24+0.5) + 1; p.modifier != "" && days > 1 {
+		half := (days + 1) / 2
+		if p.modifier == "early" {
+			last = first.AddDate(0, 0, half-1)
+		} else {
+			first = last.AddDate(0, 0, -(half - 1))
+		}
+		text = p.modifier + " " + text
+		p.modifier = ""
+	}
+
+	if p.hasDays {
+		// A second day expression narrows the first, as in "next week on Tuesday"
+		p.penalty *= 0.9
+	}
+	p.firstDay, p.lastDay, p.hasDays, p.daysText = first, last, true, text
+}
+
+// setClock narrows the clock range to from..to minutes after midnight.
+func (p *parser) setClock(from, to int) {
+	if p.hasClock {
+		from, to = max(from, p.from), min(to, p.to)
+		if from >= to {
+			// Contradicting ranges: keep the latest
+			p.penalty *= 0.5
+			from, to = max(from, 0), min(to, minutesPerDay)
+		}
+	}
+	p.from, p.to, p.hasClock = from, to, true
+}
+
+// number reads a count such as "2", "two" or "a couple of" at offset, returning the
+// value and the tokens it takes.
+func (p *parser) number(offset int) (float64, int, bool) {
+	token := p.peek(offset)
+	if n, err := strconv.ParseFloat(token, 64); err == nil && n > 0 {
+		return n, 1, true
+	}
+	n, ok := numbers[token]
+	if !ok {
+		return 0, 0, false
+	}
+	if (token == "couple" || token == "few") && p.peek(offset+1) == "of" {
+		return n, 2, true
+	}
+	return n, 1, true
+}
+
+// relativeOffset handles "in 3 days", "in two weeks" and "in 2 hours".
+func (p *parser) relativeOffset() bool {
+	if p.peek(0) != "in" && p.peek(0) != "within" {
+		return false
+	}
+	within := p.peek(0) == "within"
+	offset := 1
+	if within && p.peek(offset) == "the" && p.peek(offset+1) == "next" {
+		offset += 2
+	}
+	n, size, ok := p.number(offset)
+	if !ok || n != math.Trunc(n) {
+		return false
+	}
+	count := int(n)
+	unit := p.peek(offset + size)
+
+	switch strings.TrimSuffix(unit, "s") {
+	case "day":
+		if within {
+			p.setDays(p.today, p.today.AddDate(0, 0, count-1), fmt.Sprintf("the next %d days", count))
+		} else {
+			day := p.today.AddDate(0, 0, count)
+			p.setDays(day, day, formatDay(day))
+		}
+	case "week":
+		if within {
+			p.setDays(p.today, p.today.AddDate(0, 0, 7*count-1), fmt.Sprintf("the next %d weeks", count))
+		} else {
+			monday := weekStart(p.today.AddDate(0, 0, 7*count))
+			p.setDays(monday, monday.AddDate(0, 0, 6), "the week of "+formatDay(monday))
+		}
+	case "hour", "minute", "min", "hr":
+		if within {
+			return false
+		}
+		step := time.Hour
+		if !strings.HasPrefix(unit, "h") {
+			step = time.Minute
+		}
+		p.notBefore = p.now.Add(time.Duration(count) * step)
+		day := time.Date(p.notBefore.Year(), p.notBefore.Month(), p.notBefore.Day(), 0, 0, 0, 0, p.now.Location())
+		p.setDays(day, day, formatDay(day))
+	default:
+		return false
+	}
+	return p.consume(offset + size + 1)
+}
+
+// dayAfterTomorrow handles "the day after tomorrow".
+func (p *parser) dayAfterTomorrow() bool {
+	if p.peek(0) != "day" || p.peek(1) != "after" || p.peek(2) != "tomorrow" {
+		return false
+	}
+	day := p.today.AddDate(0, 0, 2)
+	p.setDays(day, day, formatDay(day))
+	return p.consume(3)
+}
+
+// namedDay handles "today", "tonight" and "tomorrow".
+func (p *parser) namedDay() bool {
+	switch p.peek(0) {
+	case "today":
+		p.setDays(p.today, p.today, "today")
+	case "tonight":
+		p.setDays(p.today, p.today, "today")
+		p.setClock(dayParts["evening"][0], dayParts["evening"][1])
+	case "tomorrow", "tmrw", "tmr":
+		day := p.today.AddDate(0, 0, 1)
+		p.setDays(day, day, "tomorrow, "+formatDay(day))
+	case "weekend":
+		p.weekend(false)
+	default:
+		return false
+	}
+	return p.consume(1)
+}
+
+// relative handles "this", "next" and "coming" followed by a week, weekend, month,
+// weekday or a number of days.
+func (p *parser) relative() bool {
+	which := p.peek(0)
+	if which != "this" && which != "next" && which != "coming" && which != "upcoming" {
+		return false
+	}
+	next := which == "next"
+	unit := p.peek(1)
+
+	if n, size, ok := p.number(1); ok && next && n == math.Trunc(n) {
+		count := int(n)
+		switch strings.TrimSuffix(p.peek(1+size), "s") {
+		case "day":
+			p.setDays(p.today, p.today.AddDate(0, 0, count-1), fmt.Sprintf("the next %d days", count))
+		case "week":
+			p.setDays(p.today, p.today.AddDate(0, 0, 7*count-1), fmt.Sprintf("the next %d weeks", count))
+		default:
+			return false
+		}
+		return p.consume(2 + size)
+	}
+
+	switch {
+	case !next && dayParts[unit] != [2]int{}:
+		// "this afternoon" is today; the part of day is read next
+		p.setDays(p.today, p.today, "today")
+		return p.consume(1)
+	case unit == "week":
+		p.week(next)
+	case unit == "weekend":
+		p.weekend(next)
+	case unit == "month":
+		p.month(next)
+	default:
+		day, ok := weekdays[unit]
+		if !ok {
+			return false
+		}
+		p.weekdayDay(day, next)
+	}
+	return p.consume(2)
+}
+
+// week sets the days of this week, from today, or of next week.
+func (p *parser) week(next bool) {
+	monday := weekStart(p.today)
+	if next {
+		monday = monday.AddDate(0, 0, 7)
+		p.setDays(monday, monday.AddDate(0, 0, 6), "next week")
+		return
+	}
+	p.setDays(p.today, monday.AddDate(0, 0, 6), "this week")
+}
+
+// weekend sets the Saturday and Sunday of this or next week.
+func (p *parser) weekend(next bool) {
+	saturday := weekStart(p.today).AddDate(0, 0, 5)
+	text := "this weekend"
+	if next {
+		saturday = saturday.AddDate(0, 0, 7)
+		text = "next weekend"
+	}
+	p.setDays(maxTime(saturday, p.today), saturday.AddDate(0, 0, 1), text)
+}
+
+// month sets the days of this month, from today, or of next month.
+func (p *parser) month(next bool) {
+	first := time.Date(p.today.Year(), p.today.Month(), 1, 0, 0, 0, 0, p.today.Location())
+	if next {
+		first = first.AddDate(0, 1, 0)
+		p.setDays(first, first.AddDate(0, 1, -1), first.Format("January 2006"))
+		return
+	}
+	p.setDays(p.today, first.AddDate(0, 1, -1), "the rest of "+first.Format("January"))
+}
+
+// boundary handles the beginning, start or end of a week or month, with "this" or
+// "next" optional.
+func (p *parser) boundary() bool {
+	edge := p.peek(0)
+	if edge != "end" && edge != "beginning" && edge != "start" {
+		return false
+	}
+	offset := 1
+	if p.peek(offset) != "of" {
+		return false
+	}
+	offset++
+	which := p.peek(offset)
+	if which == "the" || which == "this" || which == "next" {
+		offset++
+	}
+	next := which == "next"
+	end := edge == "end"
+
+	var first, last time.Time
+	switch p.peek(offset) {
+	case "week":
+		first, last = weekEdge(weekStart(p.today), end)
+		if next {
+			first, last = first.AddDate(0, 0, 7), last.AddDate(0, 0, 7)
+		} else if last.Before(p.today) {
+			first, last = weekEdge(weekStart(p.today).AddDate(0, 0, 7), end)
+		}
+	case "month":
+		month := time.Date(p.today.Year(), p.today.Month(), 1, 0, 0, 0, 0, p.today.Location())
+		if next {
+			month = month.AddDate(0, 1, 0)
+		}
+		first, last = monthEdge(month, end)
+		if last.Before(p.today) {
+			first, last = monthEdge(month.AddDate(0, 1, 0), end)
+		}
+	default:
+		return false
+	}
+
+	text := "the " + edge + " of the " + p.peek(offset)
+	if next {
+		text = "the " + edge + " of next " + p.peek(offset)
+	}
+	p.setDays(maxTime(first, p.today), last, text+" ("+formatDay(maxTime(first, p.today))+" to "+formatDay(last)+")")
+	return p.consume(offset + 1)
+}
+
+// weekEdge returns the first two or the last two working days of the week starting on monday.
+func weekEdge(monday time.Time, end bool) (time.Time, time.Time) {
+	if end {
+		return monday.AddDate(0, 0, 3), monday.AddDate(0, 0, 4)
+	}
+	return monday, monday.AddDate(0, 0, 1)
+}
+
+// monthEdge returns the first or the last five days of the month starting on first.
+func monthEdge(first time.Time, end bool) (time.Time, time.Time) {
+	if end {
+		last := first.AddDate(0, 1, -1)
+		return last.AddDate(0, 0, -4), last
+	}
+	return first, first.AddDate(0, 0, 4)
+}
+
+// weekday handles a bare weekday, optionally after "on".
+func (p *parser) weekday() bool {
+	day, ok := weekdays[p.peek(0)]
+	if !ok {
+		return false
+	}
+	p.weekdayDay(day, false)
+	return p.consume(1)
+}
+
+// weekdayDay sets the next day falling on weekday, from today on, or the one in next
+// week. "next Tuesday" is ambiguous in English, so it lowers the confidence.
+func (p *parser) weekdayDay(weekday time.Weekday, next bool) {
+	var day time.Time
+	if next {
+		monday := weekStart(p.today).AddDate(0, 0, 7)
+		day = monday.AddDate(0, 0, (int(weekday)+6)%7)
+		p.penalty *= 0.9
+	} else {
+		day = p.today.AddDate(0, 0, (int(weekday)-int(p.today.Weekday())+7)%7)
+	}
+	p.setDays(day, day, formatDay(day))
+}
+
+// monthDay handles dates such as "May 14", "14 May" and "14th of May", taking the
+// next such date from today on.
+func (p *parser) monthDay() bool {
+	month, day, size := time.Month(0), 0, 0
+	if m, ok := months[p.peek(0)]; ok {
+		if match := ordinalPattern.FindStringSubmatch(p.peek(1)); match != nil {
+			month, size = m, 2
+			day, _ = strconv.Atoi(match[1])
+		}
+	} else if match := ordinalPattern.FindStringSubmatch(p.peek(0)); match != nil {
+		offset := 1
+		if p.peek(offset) == "of" {
+			offset++
+		}
+		if m, ok := months[p.peek(offset)]; ok {
+			month, size = m, offset+1
+			day, _ = strconv.Atoi(match[1])
+		}
+	}
+	if size == 0 || day < 1 || day > 31 {
+		return false
+	}
+
+	date := time.Date(p.today.Year(), month, day, 0, 0, 0, 0, p.today.Location())
+	if date.Month() != month {
+		return false
+	}
+	if date.Before(p.today) {
+		date = date.AddDate(1, 0, 0)
+	}
+	p.setDays(date, date, formatDay(date))
+	return p.consume(size)
+}
+
+// clock reads a time of day at offset such as 3pm, 15:30, "3 pm" or noon, returning
+// the minutes after midnight, whether am or pm had to be guessed and the tokens it takes.
+func (p *parser) clock(offset int, context string) (int, bool, int, bool) {
+	token := p.peek(offset)
+	switch token {
+	case "noon", "midday":
+		return 12 * 60, false, 1, true
+	case "midnight":
+		if context == "after" || context == "since" || context == "from" {
+			return 0, false, 1, true
+		}
+		return minutesPerDay, false, 1, true
+	case "lunch", "lunchtime":
+		if context == "after" {
+			return 13 * 60, false, 1, true
+		}
+		return 12 * 60, false, 1, true
+	}
+
+	match := clockPattern.FindStringSubmatch(token)
+	if match == nil {
+		return 0, false, 0, false
+	}
+	hour, _ := strconv.Atoi(match[1])
+	minute, _ := strconv.Atoi(match[2])
+	meridiem, size := match[3], 1
+	if meridiem == "" && (p.peek(offset+1) == "am" || p.peek(offset+1) == "pm") {
+		meridiem, size = p.peek(offset+1), 2
+	}
+	if p.peek(offset+size) == "o'clock" {
+		size++
+	}
+	// "15 minutes" is a length, not a time
+	if durationPattern.MatchString("1" + p.peek(offset+size)) {
+		return 0, false, 0, false
+	}
+	if minute > 59 {
+		return 0, false, 0, false
+	}
+
+	switch {
+	case meridiem != "":
+		if hour < 1 || hour > 12 {
+			return 0, false, 0, false
+		}
+		hour %= 12
+		if meridiem == "pm" {
+			hour += 12
+		}
+		return hour*60 + minute, false, size, true
+	case match[2] != "" || hour == 0 || hour > 12:
+		if hour > 24 {
+			return 0, false, 0, false
+		}
+		return hour*60 + minute, false, size, true
+	default:
+		// Without am or pm assume business hours: 8 to 11 in the morning, 1 to 7 in the afternoon
+		if hour < 8 {
+			hour += 12
+		}
+		return hour*60 + minute, true, size, true
+	}
+}
+
+// clockRange handles "after 3pm", "before 11", "between 2 and 4pm", "from 9 to 11",
+// "2-4pm" and "at 3pm".
+func (p *parser) clockRange() bool {
+	word := p.peek(0)
+	offset := 1
+	switch word {
+	case "after", "since", "from", "before", "until", "till", "til", "by", "between", "at", "around":
+	default:
+		word, offset = "", 0
+	}
+
+	start, guessed, size, ok := p.clock(offset, word)
+	if !ok {
+		return false
+	}
+	offset += size
+
+	// A second time after "and", "to" or "-" makes a range
+	if sep := p.peek(offset); sep == "and" || sep == "to" || sep == "-" || sep == "until" || sep == "till" {
+		if end, endGuessed, endSize, ok := p.clock(offset+1, "before"); ok && word != "before" && word != "after" {
+			if guessed && !endGuessed && start >= end && start >= 12*60 {
+				// "11-1pm" starts in the morning
+				start -= 12 * 60
+			}
+			if guessed || endGuessed {
+				p.penalty *= 0.9
+			}
+			if start >= end {
+				return false
+			}
+			p.setClock(start, end)
+			return p.consume(offset + 1 + endSize)
+		}
+	}
+	// Bare numbers are only read as times after a word introducing one
+	if word == "between" || (word == "" && guessed) {
+		return false
+	}
+
+	if guessed {
+		p.penalty *= 0.9
+	}
+	switch word {
+	case "after", "since", "from":
+		p.setClock(start, minutesPerDay)
+	case "before", "until", "till", "til", "by":
+		p.setClock(0, start)
+	default:
+		p.at, p.hasAt = start, true
+	}
+	return p.consume(offset)
+}
+
+// duration handles lengths such as "an hour", "half an hour", "90 minutes", "1.5h" and
+// "an hour and a half".
+func (p *parser) duration() bool {
+	var length time.Duration
+	var size int
+
+	if p.peek(0) == "half" {
+		offset := 1
+		if p.peek(offset) == "an" || p.peek(offset) == "a" {
+			offset++
+		}
+		if p.peek(offset) != "hour" {
+			return false
+		}
+		length, size = 30*time.Minute, offset+1
+	} else if match := durationPattern.FindStringSubmatch(p.peek(0)); match != nil {
+		n, _ := strconv.ParseFloat(match[1], 64)
+		length, size = scale(n, match[2]), 1
+	} else {
+		n, numberSize, ok := p.number(0)
+		if !ok {
+			return false
+		}
+		unit := p.peek(numberSize)
+		if !durationPattern.MatchString("1" + unit) {
+			return false
+		}
+		length, size = scale(n, unit), numberSize+1
+	}
+
+	if p.peek(size) == "and" && p.peek(size+1) == "a" && p.peek(size+2) == "half" {
+		length += length / 2
+		size += 3
+	}
+	if length <= 0 || length > 24*time.Hour {
+		return false
+	}
+
+	p.length = length
+	return p.consume(size)
+}
+
+// scale returns n of unit, which is minutes unless it starts with h.
+func scale(n float64, unit string) time.Duration {
+	if strings.HasPrefix(unit, "h") {
+		return time.Duration(n * float64(time.Hour))
+	}
+	return time.Duration(n * float64(time.Minute))
+}
+
+// dayPart handles morning, afternoon, evening and lunch, halved by a pending "early"
+// or "late".
+func (p *parser) dayPart() bool {
+	part, ok := dayParts[p.peek(0)]
+	if !ok {
+		return false
+	}
+	from, to := part[0], part[1]
+	switch p.modifier {
+	case "early":
+		to = (from + to) / 2
+	case "late":
+		from = (from + to) / 2
+	}
+	p.modifier = ""
+	p.setClock(from, to)
+	return p.consume(1)
+}
+
+// modifierWord records "early" or "late" for the following part of day, week or month.
+func (p *parser) modifierWord() bool {
+	word := p.peek(0)
+	if word != "early" && word != "late" {
+		return false
+	}
+	p.modifier = word
+	return p.consume(1)
+}
+
+// window turns what was understood into concrete ranges.
+func (p *parser) window() (*Window, error) {
+	if p.hasAt {
+		length := p.length
+		if length == 0 {
+			length = defaultAtLength
+		}
+		p.setClock(p.at, min(p.at+int(length/time.Minute), minutesPerDay))
+	}
+
+	if !p.hasDays {
+		switch {
+		case p.hasClock:
+			// A time of day alone means today, or tomorrow once it has passed
+			day := p.today
+			if !p.now.Before(atMinute(day, p.to)) {
+				day = day.AddDate(0, 0, 1)
+			}
+			p.setDays(day, day, formatDay(day))
+		default:
+			p.setDays(p.today, p.today.AddDate(0, 0, defaultDays-1), fmt.Sprintf("the next %d days", defaultDays))
+			p.penalty *= 0.7
+		}
+	}
+
+	notBefore := maxTime(p.now, p.notBefore)
+	var ranges []Range
+	for day := p.firstDay; !day.After(p.lastDay); day = day.AddDate(0, 0, 1) {
+		start, end := atMinute(day, p.from), atMinute(day, p.to)
+		if !end.After(notBefore) {
+			continue
+		}
+		start = maxTime(start, notBefore)
+		if n := len(ranges); n > 0 && ranges[n-1].End.Equal(start) {
+			// Whole days run into each other
+			ranges[n-1].End = end
+			continue
+		}
+		ranges = append(ranges, Range{Start: start, End: end})
+	}
+	if len(ranges) == 0 {
+		return nil, ErrInPast
+	}
+
+	confidence := float64(p.known) / float64(p.known+p.unknown) * p.penalty
+	return &Window{
+		Start:           ranges[0].Start,
+		End:             ranges[len(ranges)-1].End,
+		Ranges:          ranges,
+		DurationMinutes: int(p.length / time.Minute),
+		Confidence:      math.Round(confidence*100) / 100,
+		Interpretation:  p.interpretation(),
+	}, nil
+}
+
+// interpretation describes the window in words.
+func (p *parser) interpretation() string {
+	text := p.daysText
+	switch {
+	case p.hasAt:
+		text += fmt.Sprintf(", at %s", formatMinute(p.from))
+	case !p.hasClock:
+	case p.to == minutesPerDay:
+		text += fmt.Sprintf(", after %s", formatMinute(p.from))
+	case p.from == 0:
+		text += fmt.Sprintf(", before %s", formatMinute(p.to))
+	default:
+		text += fmt.Sprintf(", %s-%s", formatMinute(p.from), formatMinute(p.to))
+	}
+	if !p.notBefore.IsZero() {
+		text += fmt.Sprintf(", from %s", p.notBefore.Format("15:04"))
+	}
+	if p.length > 0 {
+		text += ", for " + formatDuration(p.length)
+	}
+	return fmt.Sprintf("%s (%s)", text, p.now.Location())
+}
+
+// weekStart returns the Monday of the week of day.
+func weekStart(day time.Time) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// atMinute returns the time minute minutes after the midnight starting day.
+func atMinute(day time.Time, minute int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minute/60, minute%60, 0, 0, day.Location())
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func formatDay(day time.Time) string {
+	return day.Format("Monday 2 January")
+}
+
+func formatMinute(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+func formatDuration(d time.Duration) string {
+	hours, minutes := int(d/time.Hour), int(d%time.Hour/time.Minute)
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch {
+	case hours == 0:
+		return plural(minutes, "minute")
+	case minutes == 0:
+		return plural(hours, "hour")
+	default:
+		return plural(hours, "hour") + " " + plural(minutes, "minute")
+	}
+}
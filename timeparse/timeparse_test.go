@@ -0,0 +1,153 @@
+package timeparse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+	// Wednesday
+	now := time.Date(2024, 5, 8, 10, 0, 0, 0, warsaw)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, warsaw)
+	}
+
+	tests := []struct {
+		phrase   string
+		ranges   []Range
+		duration time.Duration
+		text     string
+	}{
+		{
+			phrase: "next Tuesday afternoon",
+			ranges: []Range{{at(14, 12, 0), at(14, 17, 0)}},
+			text:   "Tuesday 14 May, 12:00-17:00 (Europe/Warsaw)",
+		},
+		{
+			phrase: "sometime this week after 3pm",
+			ranges: []Range{
+				{at(8, 15, 0), at(9, 0, 0)},
+				{at(9, 15, 0), at(10, 0, 0)},
+				{at(10, 15, 0), at(11, 0, 0)},
+				{at(11, 15, 0), at(12, 0, 0)},
+				{at(12, 15, 0), at(13, 0, 0)},
+			},
+			text: "this week, after 15:00 (Europe/Warsaw)",
+		},
+		{
+			phrase:   "tomorrow morning for an hour",
+			ranges:   []Range{{at(9, 9, 0), at(9, 12, 0)}},
+			duration: time.Hour,
+			text:     "tomorrow, Thursday 9 May, 09:00-12:00, for 1 hour (Europe/Warsaw)",
+		},
+		{
+			phrase: "today",
+			ranges: []Range{{now, at(9, 0, 0)}},
+		},
+		{
+			phrase: "Friday between 2 and 4pm",
+			ranges: []Range{{at(10, 14, 0), at(10, 16, 0)}},
+		},
+		{
+			phrase:   "at 11:30 on Thursday for half an hour",
+			ranges:   []Range{{at(9, 11, 30), at(9, 12, 0)}},
+			duration: 30 * time.Minute,
+		},
+		{
+			phrase: "end of the month",
+			ranges: []Range{{at(27, 0, 0), at(32, 0, 0)}},
+		},
+		{
+			phrase:   "an hour and a half",
+			ranges:   []Range{{now, at(15, 0, 0)}},
+			duration: 90 * time.Minute,
+		},
+		{
+			phrase: "late afternoon on May 20",
+			ranges: []Range{{at(20, 14, 30), at(20, 17, 0)}},
+		},
+		{
+			phrase: "before lunch",
+			ranges: []Range{{now, at(8, 12, 0)}},
+		},
+		{
+			phrase: "in 2 hours",
+			ranges: []Range{{at(8, 12, 0), at(9, 0, 0)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phrase, func(t *testing.T) {
+			window, err := Parse(tt.phrase, now, warsaw)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(window.Ranges) != len(tt.ranges) {
+				t.Fatalf("expected ranges %v, got %v", tt.ranges, window.Ranges)
+			}
+			for i, r := range window.Ranges {
+				if !r.Start.Equal(tt.ranges[i].Start) || !r.End.Equal(tt.ranges[i].End) {
+					t.Errorf("expected ranges %v, got %v", tt.ranges, window.Ranges)
+					break
+				}
+			}
+			if window.Duration() != tt.duration {
+				t.Errorf("expected duration %s, got %s", tt.duration, window.Duration())
+			}
+			if tt.text != "" && window.Interpretation != tt.text {
+				t.Errorf("expected interpretation %q, got %q", tt.text, window.Interpretation)
+			}
+			if window.Confidence <= 0 || window.Confidence > 1 {
+				t.Errorf("expected confidence between 0 and 1, got %v", window.Confidence)
+			}
+		})
+	}
+}
+
+func TestParseConfidence(t *testing.T) {
+	now := time.Date(2024, 5, 8, 10, 0, 0, 0, time.UTC)
+
+	clear, err := Parse("tomorrow at 3pm", now, time.UTC)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if clear.Confidence != 1 {
+		t.Errorf("expected full confidence, got %v", clear.Confidence)
+	}
+
+	vague, err := Parse("tomorrow at 3 after the standup", now, time.UTC)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vague.Confidence >= clear.Confidence {
+		t.Errorf("expected less confidence for a guessed hour and unknown words, got %v", vague.Confidence)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	now := time.Date(2024, 5, 8, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		phrase   string
+		expected error
+	}{
+		{"whenever works for the team", ErrNoTime},
+		{"", ErrNoTime},
+		{"today in the morning", ErrInPast},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phrase, func(t *testing.T) {
+			_, err := Parse(tt.phrase, now, time.UTC)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}